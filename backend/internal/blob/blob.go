@@ -0,0 +1,24 @@
+// Package blob stores attachment contents outside of SQLite.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore persists opaque blobs under caller-chosen keys. Keys are
+// slash-separated paths made of safe characters.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any blob that
+	// already uses it.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key. It returns ErrNotFound when the
+	// key does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing key is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,250 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is an in-memory stand-in for an S3-compatible store. It serves
+// path-style object requests for one bucket and checks their Signature
+// Version 4 signature the way S3 does, from the request as received.
+type fakeS3 struct {
+	prefix    string // path the store is mounted at, such as /s3
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	f := &fakeS3{bucket: "attachments", region: "eu-north-1", accessKey: "minio", secretKey: "minio123", objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.checkSignature(r); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, f.prefix+"/"+f.bucket+"/")
+	if !ok || key == "" {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		_, _ = w.Write(obj.data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) checkSignature(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	rest, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return errors.New("missing AWS4-HMAC-SHA256 authorization")
+	}
+	parts := map[string]string{}
+	for _, part := range strings.Split(rest, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		parts[name] = value
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return errors.New("bad X-Amz-Date")
+	}
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	if parts["Credential"] != f.accessKey+"/"+scope {
+		return errors.New("bad credential scope " + parts["Credential"])
+	}
+	var headers []string
+	for _, name := range strings.Split(parts["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers = append(headers, name+":"+strings.TrimSpace(value)+"\n")
+	}
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		strings.Join(headers, ""),
+		parts["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+f.secretKey), amzDate[:8])
+	for _, s := range []string{f.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	want := hex.EncodeToString(hmacSHA256(key, "AWS4-HMAC-SHA256\n"+amzDate+"\n"+scope+"\n"+hexSHA256([]byte(canonical))))
+	if parts["Signature"] != want {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (f *fakeS3) object(key string) fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[key]
+}
+
+func newTestS3Store(t *testing.T, f *fakeS3, endpoint string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Config{Endpoint: endpoint, Bucket: f.bucket, Region: f.region, AccessKey: f.accessKey, SecretKey: f.secretKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	f, srv := newFakeS3(t)
+	store := newTestS3Store(t, f, srv.URL)
+	ctx := context.Background()
+	key := "attachments/3/ad9174cff4f16e145fbd80fc1ad97943"
+	data := []byte("hello, attachment")
+
+	if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := f.object(key).contentType; got != "text/plain" {
+		t.Errorf("stored content type = %q, want text/plain", got)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get = %q, want %q", got, data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+}
+
+func TestS3StorePutReplaces(t *testing.T) {
+	f, srv := newFakeS3(t)
+	store := newTestS3Store(t, f, srv.URL)
+	ctx := context.Background()
+	for _, body := range []string{"first", "second version"} {
+		if err := store.Put(ctx, "k", strings.NewReader(body), int64(len(body)), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := string(f.object("k").data); got != "second version" {
+		t.Errorf("object = %q, want the second upload", got)
+	}
+}
+
+func TestS3StoreEndpointWithPath(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.prefix = "/s3"
+	store := newTestS3Store(t, f, srv.URL+"/s3/")
+	if err := store.Put(context.Background(), "a/b", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := string(f.object("a/b").data); got != "x" {
+		t.Errorf("object = %q, want x", got)
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	f, srv := newFakeS3(t)
+	ctx := context.Background()
+
+	wrongSecret, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: f.bucket, Region: f.region, AccessKey: f.accessKey, SecretKey: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wrongSecret.Put(ctx, "k", strings.NewReader("x"), 1, "")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a wrong secret = %v, want a 403 error", err)
+	}
+
+	store := newTestS3Store(t, f, srv.URL)
+	for _, key := range []string{"", "/abs"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+}
+
+func TestNewS3StoreConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  S3Config
+		ok   bool
+	}{
+		{"valid", S3Config{Endpoint: "http://localhost:9000", Bucket: "b"}, true},
+		{"relative endpoint", S3Config{Endpoint: "localhost:9000", Bucket: "b"}, false},
+		{"no bucket", S3Config{Endpoint: "http://localhost:9000"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewS3Store(tt.cfg)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NewS3Store error = %v, want ok=%v", err, tt.ok)
+			}
+			if tt.ok && store.region != "us-east-1" {
+				t.Errorf("default region = %q, want us-east-1", store.region)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Package caldav implements the WebDAV and CalDAV (RFC 4918, RFC 4791,
+// RFC 6578) request and response bodies needed to serve todos as calendar
+// collections of VTODO resources.
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// XML namespaces.
+const (
+	NSDAV            = "DAV:"
+	NSCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NSCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// Properties served by this package's users.
+var (
+	PropResourceType            = xml.Name{Space: NSDAV, Local: "resourcetype"}
+	PropDisplayName             = xml.Name{Space: NSDAV, Local: "displayname"}
+	PropCurrentUserPrincipal    = xml.Name{Space: NSDAV, Local: "current-user-principal"}
+	PropPrincipalURL            = xml.Name{Space: NSDAV, Local: "principal-URL"}
+	PropOwner                   = xml.Name{Space: NSDAV, Local: "owner"}
+	PropGetETag                 = xml.Name{Space: NSDAV, Local: "getetag"}
+	PropGetContentType          = xml.Name{Space: NSDAV, Local: "getcontenttype"}
+	PropGetLastModified         = xml.Name{Space: NSDAV, Local: "getlastmodified"}
+	PropSyncToken               = xml.Name{Space: NSDAV, Local: "sync-token"}
+	PropSupportedReportSet      = xml.Name{Space: NSDAV, Local: "supported-report-set"}
+	PropCurrentUserPrivilegeSet = xml.Name{Space: NSDAV, Local: "current-user-privilege-set"}
+	PropCalendarHomeSet         = xml.Name{Space: NSCalDAV, Local: "calendar-home-set"}
+	PropCalendarData            = xml.Name{Space: NSCalDAV, Local: "calendar-data"}
+	PropSupportedComponentSet   = xml.Name{Space: NSCalDAV, Local: "supported-calendar-component-set"}
+	PropGetCTag                 = xml.Name{Space: NSCalendarServer, Local: "getctag"}
+)
+
+// Report kinds.
+var (
+	ReportCalendarQuery    = xml.Name{Space: NSCalDAV, Local: "calendar-query"}
+	ReportCalendarMultiget = xml.Name{Space: NSCalDAV, Local: "calendar-multiget"}
+	ReportSyncCollection   = xml.Name{Space: NSDAV, Local: "sync-collection"}
+)
+
+var ErrInvalidBody = errors.New("invalid XML request body")
+
+var prefixes = map[string]string{
+	NSDAV:            "d",
+	NSCalDAV:         "c",
+	NSCalendarServer: "cs",
+}
+
+// PropRequest lists the properties a PROPFIND or REPORT asks for. All is set
+// for allprop, and for a PROPFIND without a body.
+type PropRequest struct {
+	All   bool
+	Names []xml.Name
+}
+
+// propNames collects the names of the child elements of a DAV:prop.
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindBody struct {
+	XMLName  xml.Name   `xml:"DAV: propfind"`
+	AllProp  *struct{}  `xml:"DAV: allprop"`
+	PropName *struct{}  `xml:"DAV: propname"`
+	Prop     *propNames `xml:"DAV: prop"`
+}
+
+// ParsePropfind reads the body of a PROPFIND request.
+func ParsePropfind(r io.Reader) (PropRequest, error) {
+	var body propfindBody
+	if err := xml.NewDecoder(r).Decode(&body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return PropRequest{All: true}, nil
+		}
+		return PropRequest{}, fmt.Errorf("%w: %v", ErrInvalidBody, err)
+	}
+	if body.Prop == nil {
+		return PropRequest{All: true}, nil
+	}
+	return PropRequest{Names: *body.Prop}, nil
+}
+
+type proppatchBody struct {
+	XMLName xml.Name `xml:"DAV: propertyupdate"`
+	Set     []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: set"`
+	Remove []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: remove"`
+}
+
+// ParseProppatch returns the names of the properties a PROPPATCH request
+// sets or removes.
+func ParseProppatch(r io.Reader) ([]xml.Name, error) {
+	var body proppatchBody
+	if err := xml.NewDecoder(r).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBody, err)
+	}
+	var names []xml.Name
+	for _, set := range body.Set {
+		names = append(names, set.Prop...)
+	}
+	for _, remove := range body.Remove {
+		names = append(names, remove.Prop...)
+	}
+	return names, nil
+}
+
+// Report is a parsed REPORT request.
+type Report struct {
+	Kind xml.Name
+	Prop PropRequest
+	// Filter is the comp-filter of a calendar-query.
+	Filter *CompFilter
+	// Hrefs are the resources a calendar-multiget asks for.
+	Hrefs []string
+	// SyncToken is the token a sync-collection starts from; empty for an
+	// initial sync.
+	SyncToken string
+}
+
+type reportBody struct {
+	XMLName   xml.Name
+	AllProp   *struct{}   `xml:"DAV: allprop"`
+	Prop      *propNames  `xml:"DAV: prop"`
+	Filter    *CompFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+	Hrefs     []string    `xml:"DAV: href"`
+	SyncToken string      `xml:"DAV: sync-token"`
+}
+
+// ParseReport reads the body of a REPORT request.
+func ParseReport(r io.Reader) (*Report, error) {
+	var body reportBody
+	if err := xml.NewDecoder(r).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBody, err)
+	}
+	report := &Report{
+		Kind:      body.XMLName,
+		Filter:    body.Filter,
+		Hrefs:     body.Hrefs,
+		SyncToken: strings.TrimSpace(body.SyncToken),
+	}
+	if body.Prop != nil {
+		report.Prop.Names = *body.Prop
+	} else {
+		report.Prop.All = true
+	}
+	return report, nil
+}
+
+// Response is one resource in a multistatus. Props maps each property the
+// resource has to its XML content, which must already be escaped; Status is
+// used instead for a resource reported without properties, such as one
+// removed since the last sync.
+type Response struct {
+	Href    string
+	Status  int
+	Props   map[xml.Name]string
+	Missing []xml.Name
+}
+
+// Select keeps the properties of available that req asks for, and lists the
+// ones it asks for that are not available.
+func Select(available map[xml.Name]string, req PropRequest) (map[xml.Name]string, []xml.Name) {
+	if req.All {
+		found := make(map[xml.Name]string, len(available))
+		for name, value := range available {
+			// calendar-data is only returned when asked for by name.
+			if name != PropCalendarData {
+				found[name] = value
+			}
+		}
+		return found, nil
+	}
+	found := make(map[xml.Name]string, len(req.Names))
+	var missing []xml.Name
+	for _, name := range req.Names {
+		if value, ok := available[name]; ok {
+			found[name] = value
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
+// WriteMultistatus writes a 207 Multi-Status response. syncToken is added
+// for sync-collection reports.
+func WriteMultistatus(w http.ResponseWriter, responses []Response, syncToken string) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, resp := range responses {
+		b.WriteString("<d:response><d:href>")
+		b.WriteString(escape(resp.Href))
+		b.WriteString("</d:href>")
+		if resp.Status != 0 {
+			writeStatus(&b, resp.Status)
+		} else {
+			writePropstat(&b, resp.Props, nil, http.StatusOK)
+			writePropstat(&b, nil, resp.Missing, http.StatusNotFound)
+		}
+		b.WriteString("</d:response>")
+	}
+	if syncToken != "" {
+		b.WriteString("<d:sync-token>" + escape(syncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// WriteProppatchDenied answers a PROPPATCH on href by refusing every
+// property it tried to change.
+func WriteProppatchDenied(w http.ResponseWriter, href string, names []xml.Name) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	b.WriteString("<d:response><d:href>" + escape(href) + "</d:href>")
+	writePropstat(&b, nil, names, http.StatusForbidden)
+	b.WriteString("</d:response></d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// WriteError answers with status and a DAV:error body naming the violated
+// precondition.
+func WriteError(w http.ResponseWriter, status int, precondition xml.Name) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header+`<d:error xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+emptyElement(precondition)+`</d:error>`)
+}
+
+func writePropstat(b *strings.Builder, props map[xml.Name]string, empty []xml.Name, status int) {
+	if len(props) == 0 && len(empty) == 0 {
+		return
+	}
+	b.WriteString("<d:propstat><d:prop>")
+	names := make([]xml.Name, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Space != names[j].Space {
+			return names[i].Space < names[j].Space
+		}
+		return names[i].Local < names[j].Local
+	})
+	for _, name := range names {
+		open, close := element(name)
+		b.WriteString(open + props[name] + close)
+	}
+	for _, name := range empty {
+		b.WriteString(emptyElement(name))
+	}
+	b.WriteString("</d:prop>")
+	writeStatus(b, status)
+	b.WriteString("</d:propstat>")
+}
+
+func writeStatus(b *strings.Builder, status int) {
+	fmt.Fprintf(b, "<d:status>HTTP/1.1 %d %s</d:status>", status, http.StatusText(status))
+}
+
+// element returns the tags of name, declaring its namespace when it is not
+// one of the prefixed ones.
+func element(name xml.Name) (string, string) {
+	if prefix, ok := prefixes[name.Space]; ok {
+		return "<" + prefix + ":" + name.Local + ">", "</" + prefix + ":" + name.Local + ">"
+	}
+	return `<x:` + name.Local + ` xmlns:x="` + escape(name.Space) + `">`, "</x:" + name.Local + ">"
+}
+
+func emptyElement(name xml.Name) string {
+	open, _ := element(name)
+	return strings.TrimSuffix(open, ">") + "/>"
+}
+
+// Href renders an href property value.
+func Href(href string) string {
+	return "<d:href>" + escape(href) + "</d:href>"
+}
+
+// Elements renders empty elements, such as the members of a resourcetype.
+func Elements(names ...xml.Name) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(emptyElement(name))
+	}
+	return b.String()
+}
+
+// Text escapes s for use as a property value.
+func Text(s string) string {
+	return escape(s)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,224 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePropfind(t *testing.T) {
+	tests := []struct {
+		body string
+		want PropRequest
+	}{
+		{"", PropRequest{All: true}},
+		{`<d:propfind xmlns:d="DAV:"><d:allprop/></d:propfind>`, PropRequest{All: true}},
+		{`<d:propfind xmlns:d="DAV:"><d:propname/></d:propfind>`, PropRequest{All: true}},
+		{`<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:CS="http://calendarserver.org/ns/">
+  <prop>
+    <displayname/>
+    <C:calendar-data><C:comp name="VCALENDAR"/></C:calendar-data>
+    <CS:getctag/>
+    <x:color xmlns:x="http://apple.com/ns/ical/"/>
+  </prop>
+</propfind>`, PropRequest{Names: []xml.Name{
+			PropDisplayName,
+			PropCalendarData,
+			PropGetCTag,
+			{Space: "http://apple.com/ns/ical/", Local: "color"},
+		}}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePropfind(strings.NewReader(tt.body))
+		if err != nil {
+			t.Errorf("ParsePropfind(%.30q): %v", tt.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePropfind(%.30q) = %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+	for _, body := range []string{"<d:propfind xmlns:d=\"DAV:\">", "<not xml", `<propfind xmlns="other:"/>`} {
+		if _, err := ParsePropfind(strings.NewReader(body)); !errors.Is(err, ErrInvalidBody) {
+			t.Errorf("ParsePropfind(%q) = %v, want ErrInvalidBody", body, err)
+		}
+	}
+}
+
+func TestParseProppatch(t *testing.T) {
+	body := `<d:propertyupdate xmlns:d="DAV:" xmlns:x="http://apple.com/ns/ical/">
+  <d:set><d:prop><d:displayname>New name</d:displayname><x:calendar-color>#ff0000</x:calendar-color></d:prop></d:set>
+  <d:remove><d:prop><x:calendar-order/></d:prop></d:remove>
+</d:propertyupdate>`
+	names, err := ParseProppatch(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []xml.Name{
+		PropDisplayName,
+		{Space: "http://apple.com/ns/ical/", Local: "calendar-color"},
+		{Space: "http://apple.com/ns/ical/", Local: "calendar-order"},
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ParseProppatch = %v, want %v", names, want)
+	}
+	if _, err := ParseProppatch(strings.NewReader("")); !errors.Is(err, ErrInvalidBody) {
+		t.Errorf("empty PROPPATCH = %v, want ErrInvalidBody", err)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	query, err := ParseReport(strings.NewReader(`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VTODO">
+        <c:time-range start="20261001T000000Z" end="20261101T000000Z"/>
+        <c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter>
+        <c:prop-filter name="SUMMARY"><c:text-match negate-condition="yes">milk</c:text-match></c:prop-filter>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Kind != ReportCalendarQuery || query.Prop.All || !reflect.DeepEqual(query.Prop.Names, []xml.Name{PropGetETag}) {
+		t.Errorf("calendar-query = %+v", query)
+	}
+	want := &CompFilter{Name: "VCALENDAR", Comps: []CompFilter{{
+		Name:      "VTODO",
+		TimeRange: &TimeRange{Start: "20261001T000000Z", End: "20261101T000000Z"},
+		Props: []PropFilter{
+			{Name: "COMPLETED", IsNotDefined: &struct{}{}},
+			{Name: "SUMMARY", TextMatch: &TextMatch{Text: "milk", NegateCondition: "yes"}},
+		},
+	}}}
+	if !reflect.DeepEqual(query.Filter, want) {
+		t.Errorf("filter = %+v, want %+v", query.Filter, want)
+	}
+
+	multiget, err := ParseReport(strings.NewReader(`<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>/caldav/lists/1/a.ics</d:href>
+  <d:href>/caldav/lists/1/b%20c.ics</d:href>
+</c:calendar-multiget>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if multiget.Kind != ReportCalendarMultiget || !reflect.DeepEqual(multiget.Hrefs, []string{"/caldav/lists/1/a.ics", "/caldav/lists/1/b%20c.ics"}) || multiget.Filter != nil {
+		t.Errorf("calendar-multiget = %+v", multiget)
+	}
+
+	sync, err := ParseReport(strings.NewReader(`<d:sync-collection xmlns:d="DAV:">
+  <d:sync-token>
+    http://example.com/sync/12
+  </d:sync-token>
+  <d:sync-level>1</d:sync-level>
+  <d:allprop/>
+</d:sync-collection>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sync.Kind != ReportSyncCollection || sync.SyncToken != "http://example.com/sync/12" || !sync.Prop.All {
+		t.Errorf("sync-collection = %+v", sync)
+	}
+
+	if _, err := ParseReport(strings.NewReader("<d:sync-collection")); !errors.Is(err, ErrInvalidBody) {
+		t.Errorf("truncated REPORT = %v, want ErrInvalidBody", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	available := map[xml.Name]string{
+		PropGetETag:      `"1-1"`,
+		PropDisplayName:  "Home",
+		PropCalendarData: "BEGIN:VCALENDAR",
+	}
+	found, missing := Select(available, PropRequest{All: true})
+	if len(found) != 2 || found[PropGetETag] == "" || found[PropDisplayName] == "" || missing != nil {
+		t.Errorf("allprop = %v, %v, want everything but calendar-data", found, missing)
+	}
+	found, missing = Select(available, PropRequest{Names: []xml.Name{PropCalendarData, PropGetCTag}})
+	if len(found) != 1 || found[PropCalendarData] == "" || !reflect.DeepEqual(missing, []xml.Name{PropGetCTag}) {
+		t.Errorf("named props = %v, %v", found, missing)
+	}
+}
+
+func TestWriteMultistatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteMultistatus(w, []Response{{
+		Href: "/caldav/lists/1/a&b.ics",
+		Props: map[xml.Name]string{
+			PropGetETag:     Text(`"1-2"`),
+			PropDisplayName: Text("Tom & Jerry <3"),
+			{Space: "http://apple.com/ns/ical/", Local: "calendar-color"}: "#fff",
+		},
+		Missing: []xml.Name{PropGetCTag},
+	}, {
+		Href:   "/caldav/lists/1/gone.ics",
+		Status: http.StatusNotFound,
+	}}, "http://example.com/sync/3")
+
+	if w.Code != http.StatusMultiStatus || w.Header().Get("Content-Type") != "application/xml; charset=utf-8" {
+		t.Errorf("response = %d %v", w.Code, w.Header())
+	}
+	want := xml.Header + `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">` +
+		`<d:response><d:href>/caldav/lists/1/a&amp;b.ics</d:href>` +
+		`<d:propstat><d:prop><d:displayname>Tom &amp; Jerry &lt;3</d:displayname><d:getetag>&#34;1-2&#34;</d:getetag>` +
+		`<x:calendar-color xmlns:x="http://apple.com/ns/ical/">#fff</x:calendar-color></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>` +
+		`<d:propstat><d:prop><cs:getctag/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>` +
+		`<d:response><d:href>/caldav/lists/1/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>` +
+		`<d:sync-token>http://example.com/sync/3</d:sync-token></d:multistatus>`
+	if w.Body.String() != want {
+		t.Errorf("body =\n%s\nwant\n%s", w.Body.String(), want)
+	}
+
+	// The body must be well-formed, whatever the hrefs and values hold.
+	var parsed struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"DAV: response"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Responses) != 2 || parsed.Responses[0].Href != "/caldav/lists/1/a&b.ics" {
+		t.Errorf("parsed = %+v", parsed)
+	}
+}
+
+func TestWriteProppatchDenied(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteProppatchDenied(w, "/caldav/lists/1/", []xml.Name{PropDisplayName})
+	want := xml.Header + `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">` +
+		`<d:response><d:href>/caldav/lists/1/</d:href><d:propstat><d:prop><d:displayname/></d:prop>` +
+		`<d:status>HTTP/1.1 403 Forbidden</d:status></d:propstat></d:response></d:multistatus>`
+	if w.Code != http.StatusMultiStatus || w.Body.String() != want {
+		t.Errorf("response = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, http.StatusForbidden, xml.Name{Space: NSDAV, Local: "valid-sync-token"})
+	want := xml.Header + `<d:error xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:valid-sync-token/></d:error>`
+	if w.Code != http.StatusForbidden || w.Body.String() != want {
+		t.Errorf("response = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestElements(t *testing.T) {
+	got := Elements(xml.Name{Space: NSDAV, Local: "collection"}, xml.Name{Space: NSCalDAV, Local: "calendar"})
+	if got != "<d:collection/><c:calendar/>" {
+		t.Errorf("Elements = %s", got)
+	}
+	if got := Href("/a b&c"); got != "<d:href>/a b&amp;c</d:href>" {
+		t.Errorf("Href = %s", got)
+	}
+}
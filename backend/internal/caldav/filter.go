@@ -0,0 +1,169 @@
+package caldav
+
+import (
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/ical"
+)
+
+const timeRangeLayout = "20060102T150405Z"
+
+// CompFilter is a CalDAV comp-filter (RFC 4791 section 9.7.1).
+type CompFilter struct {
+	Name         string       `xml:"name,attr"`
+	IsNotDefined *struct{}    `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TimeRange    *TimeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Props        []PropFilter `xml:"urn:ietf:params:xml:ns:caldav prop-filter"`
+	Comps        []CompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// PropFilter is a CalDAV prop-filter (RFC 4791 section 9.7.2).
+type PropFilter struct {
+	Name         string     `xml:"name,attr"`
+	IsNotDefined *struct{}  `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TimeRange    *TimeRange `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	TextMatch    *TextMatch `xml:"urn:ietf:params:xml:ns:caldav text-match"`
+}
+
+// TimeRange bounds a time to [Start, End); either bound may be empty.
+type TimeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// TextMatch is a case-insensitive substring match.
+type TextMatch struct {
+	Text            string `xml:",chardata"`
+	NegateCondition string `xml:"negate-condition,attr"`
+}
+
+// Match reports whether the VTODO t matches the filter f, which must name
+// VCALENDAR at its root. A nil filter matches everything.
+func (f *CompFilter) Match(t ical.Todo) bool {
+	if f == nil {
+		return true
+	}
+	if !strings.EqualFold(f.Name, "VCALENDAR") {
+		return f.IsNotDefined != nil
+	}
+	if f.IsNotDefined != nil {
+		return false
+	}
+	for _, comp := range f.Comps {
+		if !comp.matchTodo(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *CompFilter) matchTodo(t ical.Todo) bool {
+	if !strings.EqualFold(f.Name, "VTODO") {
+		// The calendar holds nothing but VTODOs.
+		return f.IsNotDefined != nil
+	}
+	if f.IsNotDefined != nil {
+		return false
+	}
+	if f.TimeRange != nil {
+		// RFC 4791 section 9.9: a VTODO without DTSTART or DUE overlaps any
+		// range, and one with a DUE overlaps when DUE falls inside it.
+		if t.Due != nil && !f.TimeRange.contains(*t.Due) {
+			return false
+		}
+	}
+	for _, prop := range f.Props {
+		if !prop.match(t) {
+			return false
+		}
+	}
+	// VTODOs have no sub-components worth filtering on; only a filter
+	// asking for one to be absent matches.
+	for _, comp := range f.Comps {
+		if comp.IsNotDefined == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *PropFilter) match(t ical.Todo) bool {
+	value, defined := todoProperty(t, strings.ToUpper(f.Name))
+	if f.IsNotDefined != nil {
+		return !defined
+	}
+	if !defined {
+		return false
+	}
+	if f.TimeRange != nil {
+		at, ok := todoTime(t, strings.ToUpper(f.Name))
+		if !ok || !f.TimeRange.contains(at) {
+			return false
+		}
+	}
+	if f.TextMatch != nil {
+		found := strings.Contains(strings.ToLower(value), strings.ToLower(f.TextMatch.Text))
+		if f.TextMatch.NegateCondition == "yes" {
+			found = !found
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *TimeRange) contains(at time.Time) bool {
+	if start, err := time.Parse(timeRangeLayout, r.Start); err == nil && at.Before(start) {
+		return false
+	}
+	if end, err := time.Parse(timeRangeLayout, r.End); err == nil && !at.Before(end) {
+		return false
+	}
+	return true
+}
+
+// todoProperty returns the text of the named property of t and whether t
+// has it.
+func todoProperty(t ical.Todo, name string) (string, bool) {
+	switch name {
+	case "UID":
+		return t.UID, t.UID != ""
+	case "SUMMARY":
+		return t.Summary, t.Summary != ""
+	case "DESCRIPTION":
+		return t.Description, t.Description != ""
+	case "STATUS":
+		return t.Status, t.Status != ""
+	case "RRULE":
+		return t.RRule, t.RRule != ""
+	case "DTSTAMP":
+		return "", true
+	case "DUE", "CREATED", "LAST-MODIFIED", "COMPLETED":
+		at, ok := todoTime(t, name)
+		if !ok {
+			return "", false
+		}
+		return at.UTC().Format(timeRangeLayout), true
+	}
+	return "", false
+}
+
+func todoTime(t ical.Todo, name string) (time.Time, bool) {
+	switch name {
+	case "DUE":
+		if t.Due != nil {
+			return *t.Due, true
+		}
+	case "COMPLETED":
+		if t.Completed != nil {
+			return *t.Completed, true
+		}
+	case "CREATED":
+		return t.Created, !t.Created.IsZero()
+	case "LAST-MODIFIED":
+		return t.LastModified, !t.LastModified.IsZero()
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,107 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/ical"
+)
+
+func at(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+// parseFilter reads the comp-filter of a calendar-query with the given
+// filter body.
+func parseFilter(t *testing.T, filter string) *CompFilter {
+	t.Helper()
+	report, err := ParseReport(strings.NewReader(`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><c:filter>` + filter + `</c:filter></c:calendar-query>`))
+	if err != nil {
+		t.Fatalf("ParseReport(%s): %v", filter, err)
+	}
+	return report.Filter
+}
+
+func TestCompFilterMatch(t *testing.T) {
+	open := ical.Todo{
+		UID:     "1",
+		Summary: "Buy Milk",
+		Status:  ical.StatusNeedsAction,
+		Created: *at("2026-10-01T08:00:00Z"),
+		Due:     at("2026-10-20T12:00:00Z"),
+	}
+	done := ical.Todo{
+		UID:       "2",
+		Summary:   "pay rent",
+		Status:    ical.StatusCompleted,
+		Completed: at("2026-10-05T09:00:00Z"),
+	}
+	undated := ical.Todo{UID: "3", Summary: "someday"}
+
+	vtodo := func(inner string) string {
+		return `<c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO">` + inner + `</c:comp-filter></c:comp-filter>`
+	}
+	tests := []struct {
+		name   string
+		filter string
+		want   [3]bool // open, done, undated
+	}{
+		{"all todos", vtodo(""), [3]bool{true, true, true}},
+		{"calendar only", `<c:comp-filter name="vcalendar"/>`, [3]bool{true, true, true}},
+		{"events", `<c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter>`, [3]bool{}},
+		{"no events", `<c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"><c:is-not-defined/></c:comp-filter></c:comp-filter>`, [3]bool{true, true, true}},
+		{"no calendar", `<c:comp-filter name="VCALENDAR"><c:is-not-defined/></c:comp-filter>`, [3]bool{}},
+		{"other root", `<c:comp-filter name="VTODO"/>`, [3]bool{}},
+		{"no todos", vtodo(`<c:is-not-defined/>`), [3]bool{}},
+
+		// A VTODO without DUE overlaps every range.
+		{"due in range", vtodo(`<c:time-range start="20261019T000000Z" end="20261021T000000Z"/>`), [3]bool{true, true, true}},
+		{"due before range", vtodo(`<c:time-range start="20261021T000000Z"/>`), [3]bool{false, true, true}},
+		{"end is exclusive", vtodo(`<c:time-range end="20261020T120000Z"/>`), [3]bool{false, true, true}},
+		{"start is inclusive", vtodo(`<c:time-range start="20261020T120000Z"/>`), [3]bool{true, true, true}},
+
+		{"incomplete", vtodo(`<c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter>`), [3]bool{true, false, true}},
+		{"completed", vtodo(`<c:prop-filter name="completed"/>`), [3]bool{false, true, false}},
+		{"status", vtodo(`<c:prop-filter name="STATUS"><c:text-match>completed</c:text-match></c:prop-filter>`), [3]bool{false, true, false}},
+		{"not completed", vtodo(`<c:prop-filter name="STATUS"><c:text-match negate-condition="yes">COMPLETED</c:text-match></c:prop-filter>`), [3]bool{true, false, false}},
+		{"summary", vtodo(`<c:prop-filter name="SUMMARY"><c:text-match collation="i;ascii-casemap">milk</c:text-match></c:prop-filter>`), [3]bool{true, false, false}},
+		{"due range", vtodo(`<c:prop-filter name="DUE"><c:time-range start="20261001T000000Z" end="20261101T000000Z"/></c:prop-filter>`), [3]bool{true, false, false}},
+		{"completed range", vtodo(`<c:prop-filter name="COMPLETED"><c:time-range start="20261006T000000Z"/></c:prop-filter>`), [3]bool{}},
+		{"due text", vtodo(`<c:prop-filter name="DUE"><c:text-match>20261020</c:text-match></c:prop-filter>`), [3]bool{true, false, false}},
+		{"unknown property", vtodo(`<c:prop-filter name="X-UNKNOWN"/>`), [3]bool{}},
+		{"unknown property absent", vtodo(`<c:prop-filter name="X-UNKNOWN"><c:is-not-defined/></c:prop-filter>`), [3]bool{true, true, true}},
+		{"alarms", vtodo(`<c:comp-filter name="VALARM"/>`), [3]bool{}},
+		{"no alarms", vtodo(`<c:comp-filter name="VALARM"><c:is-not-defined/></c:comp-filter>`), [3]bool{true, true, true}},
+		{"every filter", vtodo(`<c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter><c:prop-filter name="DUE"/>`), [3]bool{true, false, false}},
+	}
+	for _, tt := range tests {
+		f := parseFilter(t, tt.filter)
+		for i, todo := range []ical.Todo{open, done, undated} {
+			if got := f.Match(todo); got != tt.want[i] {
+				t.Errorf("%s: Match(%s) = %v, want %v", tt.name, todo.Summary, got, tt.want[i])
+			}
+		}
+	}
+	var none *CompFilter
+	if !none.Match(open) {
+		t.Error("a nil filter must match everything")
+	}
+}
+
+func TestFilterUnmarshal(t *testing.T) {
+	// Elements in other namespaces are not CalDAV filters.
+	var f CompFilter
+	body := `<comp-filter xmlns="urn:ietf:params:xml:ns:caldav" xmlns:x="other:" name="VTODO"><x:is-not-defined/><x:prop-filter name="SUMMARY"/></comp-filter>`
+	if err := xml.Unmarshal([]byte(body), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "VTODO" || f.IsNotDefined != nil || len(f.Props) != 0 {
+		t.Errorf("filter = %+v", f)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Netflix/go-env"
 )
@@ -12,6 +13,30 @@ type AppConfig struct {
 	Port    string         `env:"PORT,default=8080"`
 	DBPath  string         `env:"DB_PATH,default=app.db"`
 	Clients []OAuth2Client // loaded from OAUTH2_CLIENTS
+
+	// TrashRetention is how long deleted todos stay restorable before the
+	// purge job, running every TrashPurgeInterval, removes them for good.
+	TrashRetention     time.Duration `env:"TRASH_RETENTION,default=720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL,default=1h"`
+
+	// IdempotencyTTL is how long the response to a request with an
+	// Idempotency-Key is kept for replay.
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL,default=24h"`
+
+	Blob BlobConfig
+}
+
+type BlobConfig struct {
+	Backend        string        `env:"BLOB_BACKEND,default=local"` // local or s3
+	Dir            string        `env:"BLOB_DIR,default=blobs"`
+	S3Endpoint     string        `env:"S3_ENDPOINT"`
+	S3Bucket       string        `env:"S3_BUCKET"`
+	S3Region       string        `env:"S3_REGION,default=us-east-1"`
+	S3AccessKey    string        `env:"S3_ACCESS_KEY_ID"`
+	S3SecretKey    string        `env:"S3_SECRET_ACCESS_KEY"`
+	MaxUploadBytes int64         `env:"ATTACHMENT_MAX_BYTES,default=26214400"`
+	UserQuotaBytes int64         `env:"ATTACHMENT_QUOTA_BYTES,default=104857600"`
+	SweepInterval  time.Duration `env:"ATTACHMENT_SWEEP_INTERVAL,default=10m"`
 }
 
 type OAuth2Client struct {
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+type AttachmentHandler struct {
+	Attachments *service.AttachmentService
+	// MaxUploadBytes bounds the request body of an upload.
+	MaxUploadBytes int64
+}
+
+func NewAttachmentHandler(attachments *service.AttachmentService, maxUploadBytes int64) *AttachmentHandler {
+	return &AttachmentHandler{Attachments: attachments, MaxUploadBytes: maxUploadBytes}
+}
+
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	attachments, err := h.Attachments.List(r.Context(), userID, todoID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(attachments)
+}
+
+// Upload accepts a multipart/form-data body with the file in the "file" part.
+func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	// Leave headroom for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxUploadBytes+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "expected multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		attachment, err := h.Attachments.Upload(r.Context(), userID, todoID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			writeUploadError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(attachment)
+		return
+	}
+}
+
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := parseIDParam(r, "attachmentId")
+	if err != nil {
+		http.Error(w, "invalid attachment id", http.StatusBadRequest)
+		return
+	}
+	attachment, body, err := h.Attachments.Open(r.Context(), userID, todoID, attachmentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, body)
+}
+
+func (h *AttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := parseIDParam(r, "attachmentId")
+	if err != nil {
+		http.Error(w, "invalid attachment id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Attachments.Delete(r.Context(), userID, todoID, attachmentID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "insufficient role on list", http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge), errors.Is(err, service.ErrAttachmentTooLarge):
+		http.Error(w, "attachment too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "insufficient role on list", http.StatusForbidden)
+	case errors.Is(err, service.ErrQuotaExceeded):
+		http.Error(w, "attachment quota exceeded", http.StatusInsufficientStorage)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
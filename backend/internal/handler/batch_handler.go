@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	maxBatchOps       = 100
+	maxBatchBodyBytes = 8 << 20
+)
+
+// Batch modes.
+const (
+	batchAtomic  = "atomic"
+	batchPartial = "partial"
+)
+
+type batchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchOperation struct {
+	Op     string `json:"op"`
+	ID     int64  `json:"id"`
+	ListID int64  `json:"listId"`
+	// Version makes update and delete conditional, like If-Match.
+	Version int64           `json:"version"`
+	Todo    json.RawMessage `json:"todo"`
+}
+
+type batchItemResult struct {
+	Index    int         `json:"index"`
+	Status   int         `json:"status"`
+	Todo     *model.Todo `json:"todo,omitempty"`
+	Affected []int64     `json:"affected,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Committed bool              `json:"committed"`
+	Results   []batchItemResult `json:"results"`
+}
+
+// Batch applies a list of todo operations in one transaction. In atomic mode
+// (the default) any failure rolls back the whole batch and the response
+// carries the status and result of the failing operation; in partial mode
+// every operation succeeds or fails on its own and the response is 200 with a
+// result per operation.
+func (h *TodoHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = batchAtomic
+	case batchAtomic, batchPartial:
+	default:
+		http.Error(w, `mode must be "atomic" or "partial"`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOps {
+		http.Error(w, fmt.Sprintf("operations must hold between 1 and %d items", maxBatchOps), http.StatusBadRequest)
+		return
+	}
+	ops := make([]service.BatchOp, len(req.Operations))
+	for i, o := range req.Operations {
+		op, err := o.batchOp()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("operation %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		ops[i] = op
+	}
+
+	results, err := h.Todos.Batch(r.Context(), userID, ops, req.Mode == batchAtomic)
+	var batchErr *service.BatchError
+	if errors.As(err, &batchErr) {
+		status, message := todoErrorStatus(batchErr.Err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(batchResponse{Results: []batchItemResult{{
+			Index:  batchErr.Index,
+			Status: status,
+			Error:  message,
+		}}})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := batchResponse{Committed: true, Results: make([]batchItemResult, len(results))}
+	for i, res := range results {
+		item := batchItemResult{Index: i, Status: http.StatusOK, Todo: res.Todo, Affected: res.Affected}
+		if res.Err != nil {
+			item.Status, item.Error = todoErrorStatus(res.Err)
+		} else if ops[i].Op == service.BatchCreate {
+			item.Status = http.StatusCreated
+		}
+		resp.Results[i] = item
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// batchOp decodes the todo fields of an operation into a service.BatchOp.
+func (o batchOperation) batchOp() (service.BatchOp, error) {
+	op := service.BatchOp{Op: o.Op, TodoID: o.ID, ListID: o.ListID, IfVersion: o.Version}
+	switch o.Op {
+	case service.BatchCreate:
+		var req createTodoRequest
+		if err := json.Unmarshal(o.Todo, &req); err != nil {
+			return op, errors.New("invalid todo")
+		}
+		op.Apply = func(todo *model.Todo) error {
+			built, err := req.todo()
+			if err != nil {
+				return err
+			}
+			*todo = *built
+			return nil
+		}
+	case service.BatchUpdate:
+		if o.ID == 0 {
+			return op, errors.New("id is required")
+		}
+		var req updateTodoRequest
+		if err := json.Unmarshal(o.Todo, &req); err != nil {
+			return op, errors.New("invalid todo")
+		}
+		op.Apply = req.apply
+	case service.BatchDelete:
+		if o.ID == 0 {
+			return op, errors.New("id is required")
+		}
+	case service.BatchCompleteAll, service.BatchDeleteCompleted:
+		if o.ListID == 0 {
+			return op, errors.New("listId is required")
+		}
+	default:
+		return op, fmt.Errorf("unknown op %q", o.Op)
+	}
+	return op, nil
+}
@@ -0,0 +1,659 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/caldav"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/ical"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	// calDAVRoot is where the CalDAV handler is mounted.
+	calDAVRoot = "/caldav"
+	// syncTokenPrefix turns a list's sync token into the URI sync-collection
+	// clients expect.
+	syncTokenPrefix = "urn:todos:sync:"
+	// calendarContentType is the media type of a todo served over CalDAV.
+	calendarContentType = "text/calendar; charset=utf-8; component=VTODO"
+)
+
+// CalDAV methods that chi does not know about.
+const (
+	MethodPropfind  = "PROPFIND"
+	MethodProppatch = "PROPPATCH"
+	MethodReport    = "REPORT"
+)
+
+var (
+	errCalDAVDepth  = errors.New("depth must be 0, 1 or infinity")
+	errOtherUser    = errors.New("collection belongs to another user")
+	errUIDMismatch  = errors.New("UID must match the resource name")
+	errNotOneVTODO  = errors.New("resource must contain exactly one VTODO")
+	errSyncToken    = errors.New("invalid sync token")
+	davPrincipal    = xml.Name{Space: caldav.NSDAV, Local: "principal"}
+	davCollection   = xml.Name{Space: caldav.NSDAV, Local: "collection"}
+	calDAVCalendar  = xml.Name{Space: caldav.NSCalDAV, Local: "calendar"}
+	validSyncToken  = xml.Name{Space: caldav.NSDAV, Local: "valid-sync-token"}
+	validObject     = xml.Name{Space: caldav.NSCalDAV, Local: "valid-calendar-object-resource"}
+	validData       = xml.Name{Space: caldav.NSCalDAV, Local: "valid-calendar-data"}
+	supportedReport = xml.Name{Space: caldav.NSDAV, Local: "supported-report"}
+)
+
+// CalDAVHandler serves the lists a user can see as CalDAV calendars holding
+// their todos as VTODO resources:
+//
+//	/caldav/principals/{userId}/                  the user
+//	/caldav/calendars/{userId}/                   their calendar home
+//	/caldav/calendars/{userId}/{listId}/          a list
+//	/caldav/calendars/{userId}/{listId}/{uid}.ics a todo
+//
+// Every change goes through TodoService, so it is versioned and announced
+// like one made through the JSON API.
+type CalDAVHandler struct {
+	Todos *service.TodoService
+	Lists *service.ListService
+	Users *service.UserService
+}
+
+func NewCalDAVHandler(todos *service.TodoService, lists *service.ListService, users *service.UserService) *CalDAVHandler {
+	return &CalDAVHandler{Todos: todos, Lists: lists, Users: users}
+}
+
+// WellKnown redirects /.well-known/caldav to the service root (RFC 6764).
+func (h *CalDAVHandler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, calDAVRoot+"/", http.StatusMovedPermanently)
+}
+
+// Options advertises CalDAV support. It is answered without authentication
+// so that clients can discover the server before signing in.
+func (h *CalDAVHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Root answers PROPFIND on /caldav/ with the signed-in user's principal,
+// which is how clients find their calendars.
+func (h *CalDAVHandler) Root(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case MethodPropfind:
+		req, ok := parsePropfind(w, r)
+		if !ok {
+			return
+		}
+		props := map[xml.Name]string{
+			caldav.PropResourceType:         caldav.Elements(davCollection),
+			caldav.PropCurrentUserPrincipal: caldav.Href(principalHref(userID)),
+		}
+		found, missing := caldav.Select(props, req)
+		caldav.WriteMultistatus(w, []caldav.Response{{Href: calDAVRoot + "/", Props: found, Missing: missing}}, "")
+	case MethodProppatch:
+		h.proppatch(w, r, calDAVRoot+"/")
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// Principal answers PROPFIND on a user's principal.
+func (h *CalDAVHandler) Principal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case MethodPropfind:
+		req, ok := parsePropfind(w, r)
+		if !ok {
+			return
+		}
+		user, err := h.Users.GetByID(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		props := map[xml.Name]string{
+			caldav.PropResourceType:         caldav.Elements(davCollection, davPrincipal),
+			caldav.PropDisplayName:          caldav.Text(user.Username),
+			caldav.PropCurrentUserPrincipal: caldav.Href(principalHref(userID)),
+			caldav.PropPrincipalURL:         caldav.Href(principalHref(userID)),
+			caldav.PropCalendarHomeSet:      caldav.Href(homeHref(userID)),
+		}
+		found, missing := caldav.Select(props, req)
+		caldav.WriteMultistatus(w, []caldav.Response{{Href: principalHref(userID), Props: found, Missing: missing}}, "")
+	case MethodProppatch:
+		h.proppatch(w, r, principalHref(userID))
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// Home answers PROPFIND on a user's calendar home, listing every list they
+// are a member of as a calendar at depth 1.
+func (h *CalDAVHandler) Home(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case MethodPropfind:
+		depth, ok := parseDepth(w, r)
+		if !ok {
+			return
+		}
+		req, ok := parsePropfind(w, r)
+		if !ok {
+			return
+		}
+		props := map[xml.Name]string{
+			caldav.PropResourceType:         caldav.Elements(davCollection),
+			caldav.PropCurrentUserPrincipal: caldav.Href(principalHref(userID)),
+			caldav.PropOwner:                caldav.Href(principalHref(userID)),
+		}
+		found, missing := caldav.Select(props, req)
+		responses := []caldav.Response{{Href: homeHref(userID), Props: found, Missing: missing}}
+		if depth > 0 {
+			lists, err := h.Lists.ListByUser(r.Context(), userID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for i := range lists {
+				resp, err := h.calendarResponse(r.Context(), userID, &lists[i], req)
+				if err != nil {
+					writeTodoError(w, r, err)
+					return
+				}
+				responses = append(responses, resp)
+			}
+		}
+		caldav.WriteMultistatus(w, responses, "")
+	case MethodProppatch:
+		h.proppatch(w, r, homeHref(userID))
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// Calendar serves a list: PROPFIND describes it and, at depth 1, its todos;
+// REPORT runs calendar-query, calendar-multiget and sync-collection.
+func (h *CalDAVHandler) Calendar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	list, err := h.Lists.Get(r.Context(), userID, listID)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	switch r.Method {
+	case MethodPropfind:
+		h.propfindCalendar(w, r, userID, list)
+	case MethodReport:
+		h.report(w, r, userID, list)
+	case MethodProppatch:
+		h.proppatch(w, r, calendarHref(userID, listID))
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (h *CalDAVHandler) propfindCalendar(w http.ResponseWriter, r *http.Request, userID int64, list *model.List) {
+	depth, ok := parseDepth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := parsePropfind(w, r)
+	if !ok {
+		return
+	}
+	resp, err := h.calendarResponse(r.Context(), userID, list, req)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	responses := []caldav.Response{resp}
+	if depth > 0 {
+		todos, err := allTodos(r.Context(), h.Todos, userID, repository.TodoFilter{ListID: list.ID, Limit: maxTodoLimit})
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		for i := range todos {
+			responses = append(responses, objectResponse(userID, &todos[i], req))
+		}
+	}
+	caldav.WriteMultistatus(w, responses, "")
+}
+
+// calendarResponse describes list as a calendar collection.
+func (h *CalDAVHandler) calendarResponse(ctx context.Context, userID int64, list *model.List, req caldav.PropRequest) (caldav.Response, error) {
+	token, err := h.Todos.SyncToken(ctx, userID, list.ID)
+	if err != nil {
+		return caldav.Response{}, err
+	}
+	privileges := "<d:privilege><d:read/></d:privilege>"
+	if list.Role != model.RoleViewer {
+		privileges += "<d:privilege><d:write/></d:privilege><d:privilege><d:write-content/></d:privilege>"
+	}
+	props := map[xml.Name]string{
+		caldav.PropResourceType:            caldav.Elements(davCollection, calDAVCalendar),
+		caldav.PropDisplayName:             caldav.Text(list.Name),
+		caldav.PropCurrentUserPrincipal:    caldav.Href(principalHref(userID)),
+		caldav.PropOwner:                   caldav.Href(principalHref(list.CreatedBy)),
+		caldav.PropSupportedComponentSet:   `<c:comp name="VTODO"/>`,
+		caldav.PropCurrentUserPrivilegeSet: privileges,
+		caldav.PropSupportedReportSet:      supportedReports(),
+		caldav.PropGetCTag:                 strconv.FormatInt(token, 10),
+		caldav.PropSyncToken:               caldav.Text(syncTokenPrefix + strconv.FormatInt(token, 10)),
+	}
+	found, missing := caldav.Select(props, req)
+	return caldav.Response{Href: calendarHref(userID, list.ID), Props: found, Missing: missing}, nil
+}
+
+func supportedReports() string {
+	var b strings.Builder
+	for _, report := range []xml.Name{caldav.ReportCalendarQuery, caldav.ReportCalendarMultiget, caldav.ReportSyncCollection} {
+		b.WriteString("<d:supported-report><d:report>" + caldav.Elements(report) + "</d:report></d:supported-report>")
+	}
+	return b.String()
+}
+
+func (h *CalDAVHandler) report(w http.ResponseWriter, r *http.Request, userID int64, list *model.List) {
+	report, err := caldav.ParseReport(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch report.Kind {
+	case caldav.ReportCalendarQuery:
+		todos, err := allTodos(r.Context(), h.Todos, userID, repository.TodoFilter{ListID: list.ID, Limit: maxTodoLimit})
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		var responses []caldav.Response
+		for i := range todos {
+			if report.Filter.Match(icalTodo(&todos[i])) {
+				responses = append(responses, objectResponse(userID, &todos[i], report.Prop))
+			}
+		}
+		caldav.WriteMultistatus(w, responses, "")
+	case caldav.ReportCalendarMultiget:
+		var responses []caldav.Response
+		for _, href := range report.Hrefs {
+			uid, ok := objectUID(href, calendarHref(userID, list.ID))
+			if !ok {
+				responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			todo, err := h.Todos.GetByUID(r.Context(), userID, list.ID, uid)
+			if errors.Is(err, repository.ErrNotFound) {
+				responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			if err != nil {
+				writeTodoError(w, r, err)
+				return
+			}
+			responses = append(responses, objectResponse(userID, todo, report.Prop))
+		}
+		caldav.WriteMultistatus(w, responses, "")
+	case caldav.ReportSyncCollection:
+		h.syncCollection(w, r, userID, list, report)
+	default:
+		caldav.WriteError(w, http.StatusForbidden, supportedReport)
+	}
+}
+
+// syncCollection answers a sync-collection report (RFC 6578) with the todos
+// that changed since the client's token, or every todo for an initial sync.
+// Todos that left the list are reported as 404.
+func (h *CalDAVHandler) syncCollection(w http.ResponseWriter, r *http.Request, userID int64, list *model.List, report *caldav.Report) {
+	if report.SyncToken == "" {
+		token, err := h.Todos.SyncToken(r.Context(), userID, list.ID)
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		todos, err := allTodos(r.Context(), h.Todos, userID, repository.TodoFilter{ListID: list.ID, Limit: maxTodoLimit})
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		responses := make([]caldav.Response, len(todos))
+		for i := range todos {
+			responses[i] = objectResponse(userID, &todos[i], report.Prop)
+		}
+		caldav.WriteMultistatus(w, responses, syncTokenPrefix+strconv.FormatInt(token, 10))
+		return
+	}
+
+	since, err := parseSyncToken(report.SyncToken)
+	if err != nil {
+		caldav.WriteError(w, http.StatusForbidden, validSyncToken)
+		return
+	}
+	changes, token, err := h.Todos.Changes(r.Context(), userID, list.ID, since)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if since > token {
+		caldav.WriteError(w, http.StatusForbidden, validSyncToken)
+		return
+	}
+	responses := make([]caldav.Response, 0, len(changes))
+	for _, change := range changes {
+		href := objectHref(userID, list.ID, change.UID)
+		if change.Removed {
+			responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+			continue
+		}
+		todo, err := h.Todos.Get(r.Context(), userID, change.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+			continue
+		}
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		responses = append(responses, objectResponse(userID, todo, report.Prop))
+	}
+	caldav.WriteMultistatus(w, responses, syncTokenPrefix+strconv.FormatInt(token, 10))
+}
+
+// Object serves a single todo as an iCalendar resource named after its UID.
+func (h *CalDAVHandler) Object(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	uid, ok := objectUID(chi.URLParam(r, "object"), "")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.getObject(w, r, userID, listID, uid)
+	case http.MethodPut:
+		h.putObject(w, r, userID, listID, uid)
+	case http.MethodDelete:
+		h.deleteObject(w, r, userID, listID, uid)
+	case MethodPropfind:
+		req, ok := parsePropfind(w, r)
+		if !ok {
+			return
+		}
+		todo, err := h.Todos.GetByUID(r.Context(), userID, listID, uid)
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		caldav.WriteMultistatus(w, []caldav.Response{objectResponse(userID, todo, req)}, "")
+	case MethodProppatch:
+		h.proppatch(w, r, objectHref(userID, listID, uid))
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (h *CalDAVHandler) getObject(w http.ResponseWriter, r *http.Request, userID, listID int64, uid string) {
+	todo, err := h.Todos.GetByUID(r.Context(), userID, listID, uid)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if notModified(w, r, todoETag(todo)) {
+		return
+	}
+	data := calendarData(todo)
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Last-Modified", todo.UpdatedAt.UTC().Format(http.TimeFormat))
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = io.WriteString(w, data)
+}
+
+// putObject creates or replaces a todo from a VTODO. If-None-Match: * only
+// creates and If-Match only replaces the named version, as clients use them
+// to avoid overwriting each other's changes.
+func (h *CalDAVHandler) putObject(w http.ResponseWriter, r *http.Request, userID, listID int64, uid string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxTodoBodyBytes)
+	items, err := ical.Decode(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		caldav.WriteError(w, http.StatusForbidden, validData)
+		return
+	}
+	if len(items) != 1 {
+		http.Error(w, errNotOneVTODO.Error(), http.StatusForbidden)
+		return
+	}
+	if items[0].Err == nil && items[0].Todo.UID != uid {
+		http.Error(w, errUIDMismatch.Error(), http.StatusForbidden)
+		return
+	}
+	todo, err := importedTodo(items[0])
+	if err != nil {
+		caldav.WriteError(w, http.StatusForbidden, validObject)
+		return
+	}
+
+	existing, err := h.Todos.GetByUID(r.Context(), userID, listID, uid)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		writeTodoError(w, r, err)
+		return
+	}
+	var ifVersion int64
+	if existing != nil {
+		if r.Header.Get("If-None-Match") == "*" {
+			http.Error(w, "resource already exists", http.StatusPreconditionFailed)
+			return
+		}
+		if preconditionFailed(w, r, todoETag(existing)) {
+			return
+		}
+		if r.Header.Get("If-Match") != "" {
+			ifVersion = existing.Version
+		}
+	} else if r.Header.Get("If-Match") != "" {
+		http.Error(w, "resource does not exist", http.StatusPreconditionFailed)
+		return
+	}
+
+	result, err := h.Todos.SaveByUID(r.Context(), userID, listID, todo, ifVersion)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", todoETag(result.Todo))
+	if result.Outcome == service.ImportCreated {
+		w.Header().Set("Location", objectHref(userID, listID, uid))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CalDAVHandler) deleteObject(w http.ResponseWriter, r *http.Request, userID, listID int64, uid string) {
+	todo, err := h.Todos.GetByUID(r.Context(), userID, listID, uid)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if preconditionFailed(w, r, todoETag(todo)) {
+		return
+	}
+	var ifVersion int64
+	if r.Header.Get("If-Match") != "" {
+		ifVersion = todo.Version
+	}
+	if err := h.Todos.Delete(r.Context(), userID, todo.ID, ifVersion); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// proppatch refuses to change any property; collections are managed through
+// the JSON API.
+func (h *CalDAVHandler) proppatch(w http.ResponseWriter, r *http.Request, href string) {
+	names, err := caldav.ParseProppatch(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	caldav.WriteProppatchDenied(w, href, names)
+}
+
+// owner returns the user ID in the URL, answering 403 when it is not the
+// signed-in user's.
+func (h *CalDAVHandler) owner(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	pathID, err := parseIDParam(r, "userId")
+	if err != nil {
+		http.NotFound(w, r)
+		return 0, false
+	}
+	if pathID != userID {
+		http.Error(w, errOtherUser.Error(), http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
+
+// objectResponse describes todo as a calendar object resource.
+func objectResponse(userID int64, todo *model.Todo, req caldav.PropRequest) caldav.Response {
+	props := map[xml.Name]string{
+		caldav.PropResourceType:    "",
+		caldav.PropGetETag:         caldav.Text(todoETag(todo)),
+		caldav.PropGetContentType:  calendarContentType,
+		caldav.PropGetLastModified: todo.UpdatedAt.UTC().Format(http.TimeFormat),
+	}
+	for _, name := range req.Names {
+		// Rendering the calendar data is only worth it when asked for.
+		if name == caldav.PropCalendarData {
+			props[name] = caldav.Text(calendarData(todo))
+		}
+	}
+	found, missing := caldav.Select(props, req)
+	return caldav.Response{Href: objectHref(userID, todo.ListID, todo.UID), Props: found, Missing: missing}
+}
+
+func calendarData(todo *model.Todo) string {
+	var b bytes.Buffer
+	_ = ical.Encode(&b, []ical.Todo{icalTodo(todo)}, time.Now())
+	return b.String()
+}
+
+func parsePropfind(w http.ResponseWriter, r *http.Request) (caldav.PropRequest, bool) {
+	req, err := caldav.ParsePropfind(http.MaxBytesReader(w, r.Body, maxTodoBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return caldav.PropRequest{}, false
+	}
+	return req, true
+}
+
+// parseDepth reads the Depth header, treating infinity, which collections
+// here need no more than one level for, as 1.
+func parseDepth(w http.ResponseWriter, r *http.Request) (int, bool) {
+	switch r.Header.Get("Depth") {
+	case "0":
+		return 0, true
+	case "1", "infinity", "":
+		return 1, true
+	}
+	http.Error(w, errCalDAVDepth.Error(), http.StatusBadRequest)
+	return 0, false
+}
+
+func parseSyncToken(token string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimPrefix(token, syncTokenPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(token, syncTokenPrefix) || n < 0 {
+		return 0, errSyncToken
+	}
+	return n, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, REPORT")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+func principalHref(userID int64) string {
+	return fmt.Sprintf("%s/principals/%d/", calDAVRoot, userID)
+}
+
+func homeHref(userID int64) string {
+	return fmt.Sprintf("%s/calendars/%d/", calDAVRoot, userID)
+}
+
+func calendarHref(userID, listID int64) string {
+	return fmt.Sprintf("%s/calendars/%d/%d/", calDAVRoot, userID, listID)
+}
+
+func objectHref(userID, listID int64, uid string) string {
+	return calendarHref(userID, listID) + url.PathEscape(uid) + ".ics"
+}
+
+// objectUID returns the UID named by the last segment of href, which must be
+// in the collection at parent unless parent is empty.
+func objectUID(href, parent string) (string, bool) {
+	if u, err := url.Parse(href); err == nil {
+		href = u.EscapedPath()
+	}
+	if parent != "" && path.Dir(href)+"/" != parent {
+		return "", false
+	}
+	name, err := url.PathUnescape(path.Base(href))
+	if err != nil || !strings.HasSuffix(name, ".ics") || name == ".ics" {
+		return "", false
+	}
+	return strings.TrimSuffix(name, ".ics"), true
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	maxCommentBytes     = 16 << 10
+	defaultCommentLimit = 50
+	maxCommentLimit     = 200
+)
+
+type CommentHandler struct {
+	Comments *service.CommentService
+}
+
+func NewCommentHandler(comments *service.CommentService) *CommentHandler {
+	return &CommentHandler{Comments: comments}
+}
+
+type commentRequest struct {
+	Body string `json:"body"`
+}
+
+// List pages through a todo's comments oldest first. ?after= takes the ID of
+// the last comment already seen; a Link header points at the next page.
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	var afterID int64
+	if raw := query.Get("after"); raw != "" {
+		afterID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || afterID < 0 {
+			http.Error(w, "invalid after", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultCommentLimit
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxCommentLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxCommentLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	comments, err := h.Comments.List(r.Context(), userID, todoID, afterID, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(comments) == limit {
+		next := *r.URL
+		q := next.Query()
+		q.Set("after", strconv.FormatInt(comments[len(comments)-1].ID, 10))
+		q.Set("limit", strconv.Itoa(limit))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(comments)
+}
+
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	body, ok := decodeCommentBody(w, r)
+	if !ok {
+		return
+	}
+	comment, err := h.Comments.Create(r.Context(), userID, todoID, body)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	commentID, err := parseIDParam(r, "commentId")
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+	body, ok := decodeCommentBody(w, r)
+	if !ok {
+		return
+	}
+	comment, err := h.Comments.Update(r.Context(), userID, todoID, commentID, body)
+	if err != nil {
+		writeCommentError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	commentID, err := parseIDParam(r, "commentId")
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Comments.Delete(r.Context(), userID, todoID, commentID); err != nil {
+		writeCommentError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeCommentBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCommentBytes+1<<10)
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return "", false
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return "", false
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "body is required", http.StatusBadRequest)
+		return "", false
+	}
+	if len(req.Body) > maxCommentBytes {
+		http.Error(w, "comment too long", http.StatusRequestEntityTooLarge)
+		return "", false
+	}
+	return req.Body, true
+}
+
+func writeCommentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "only the author or todo owner may change this comment", http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
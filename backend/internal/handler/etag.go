@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// todoETag is the strong entity tag of a single stored todo, used by CalDAV
+// and compared by If-Match. It changes whenever the todo's version does, and
+// only then: whether the todo is blocked depends on other todos and records
+// no revision, so it is left out to keep the tag in step with the CalDAV sync
+// token.
+func todoETag(todo *model.Todo) string {
+	return fmt.Sprintf(`"%d-%d"`, todo.ID, todo.Version)
+}
+
+// todoRepresentationETag is the entity tag of a todo as the JSON API shows
+// it. Besides the version it covers what the JSON takes from other rows, the
+// blocked flag and the status's name and category, none of which bump the
+// version, so cached copies revalidate when they change. It starts with
+// todoETag, which lets If-Match keep comparing versions only.
+func todoRepresentationETag(todo *model.Todo) string {
+	h := sha256.New()
+	writeDerivedFields(h, todo)
+	return strings.TrimSuffix(todoETag(todo), `"`) + "-" + hex.EncodeToString(h.Sum(nil)[:4]) + `"`
+}
+
+// todoListETag is a weak entity tag over the IDs, versions and derived fields
+// of a page of todos and the cursor of the page after it. Like
+// todoRepresentationETag it only revalidates cached copies, so it follows
+// blocked flags and statuses too.
+func todoListETag(todos []model.Todo, next string) string {
+	h := sha256.New()
+	for i := range todos {
+		fmt.Fprintf(h, "%d:%d:", todos[i].ID, todos[i].Version)
+		writeDerivedFields(h, &todos[i])
+		h.Write([]byte(","))
+	}
+	h.Write([]byte(next))
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// writeDerivedFields writes the fields of todo that can change without its
+// version changing.
+func writeDerivedFields(w io.Writer, todo *model.Todo) {
+	fmt.Fprintf(w, "%t", todo.Blocked)
+	if todo.Status != nil {
+		fmt.Fprintf(w, ":%d:%q:%q", todo.Status.ID, todo.Status.Name, todo.Status.Category)
+	}
+}
+
+// etagMatches reports whether etag is listed in the If-Match or If-None-Match
+// header value header. If-Match uses the strong comparison, under which weak
+// tags never match; If-None-Match uses the weak one.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// todoPreconditionFailed is preconditionFailed for a todo in the JSON API.
+// If-Match may name todoETag or a todoRepresentationETag of the todo's
+// current version: a blocker being completed or a status being renamed does
+// not make a write conflict.
+func todoPreconditionFailed(w http.ResponseWriter, r *http.Request, todo *model.Todo) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, todoETag(todo), false) {
+		return false
+	}
+	prefix := strings.TrimSuffix(todoETag(todo), `"`) + "-"
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.HasPrefix(strings.TrimSpace(candidate), prefix) {
+			return false
+		}
+	}
+	http.Error(w, "todo has been modified", http.StatusPreconditionFailed)
+	return true
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// already names etag, answers 304 and returns true.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// preconditionFailed answers 412 and returns true when the request carries an
+// If-Match header that does not name etag.
+func preconditionFailed(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, etag, false) {
+		return false
+	}
+	http.Error(w, "todo has been modified", http.StatusPreconditionFailed)
+	return true
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestTodoETag(t *testing.T) {
+	todo := &model.Todo{ID: 7, Version: 3}
+	if got := todoETag(todo); got != `"7-3"` {
+		t.Errorf("todoETag = %s, want \"7-3\"", got)
+	}
+	// Blocking records no revision, so the CalDAV sync token does not move
+	// and neither may the ETag.
+	todo.Blocked = true
+	if got := todoETag(todo); got != `"7-3"` {
+		t.Errorf("todoETag of a blocked todo = %s, want \"7-3\"", got)
+	}
+	todo.Version++
+	if got := todoETag(todo); got != `"7-4"` {
+		t.Errorf("todoETag after an update = %s, want \"7-4\"", got)
+	}
+}
+
+func TestTodoListETag(t *testing.T) {
+	todos := []model.Todo{{ID: 1, Version: 1}, {ID: 2, Version: 5}}
+	etag := todoListETag(todos, "")
+	if etag[:2] != `W/` {
+		t.Errorf("todoListETag = %s, want a weak tag", etag)
+	}
+	if todoListETag(todos, "") != etag {
+		t.Error("todoListETag is not stable")
+	}
+	todos[1].Blocked = true
+	if todoListETag(todos, "") == etag {
+		t.Error("todoListETag ignores blocked flags")
+	}
+	todos[1].Blocked = false
+	if todoListETag(todos, "cursor") == etag {
+		t.Error("todoListETag ignores the next cursor")
+	}
+}
+
+func TestTodoRepresentationETag(t *testing.T) {
+	todo := &model.Todo{ID: 7, Version: 3, Status: &model.StatusSummary{ID: 1, Name: "To do", Category: model.StatusTodo}}
+	etag := todoRepresentationETag(todo)
+	if !strings.HasPrefix(etag, `"7-3-`) || !strings.HasSuffix(etag, `"`) {
+		t.Errorf("todoRepresentationETag = %s, want todoETag with a suffix", etag)
+	}
+	seen := map[string]bool{etag: true}
+	for _, change := range []func(){
+		func() { todo.Blocked = true },
+		func() { todo.Status.Name = "Backlog" },
+		func() { todo.Status.Category = model.StatusInProgress },
+		func() { todo.Status = nil },
+	} {
+		change()
+		etag := todoRepresentationETag(todo)
+		if seen[etag] || !strings.HasPrefix(etag, `"7-3-`) {
+			t.Errorf("todoRepresentationETag = %s after a change, want a new tag of version 3", etag)
+		}
+		seen[etag] = true
+	}
+}
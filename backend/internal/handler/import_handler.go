@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/importer"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+// maxImportFileBytes bounds an uploaded export file.
+const maxImportFileBytes = 20 << 20
+
+type ImportHandler struct {
+	Imports *service.ImportService
+}
+
+func NewImportHandler(imports *service.ImportService) *ImportHandler {
+	return &ImportHandler{Imports: imports}
+}
+
+// Create starts an import of another tool's export file, sent as the request
+// body or as the file field of a multipart form. ?format= names the tool:
+// todoist (a project's CSV), trello (a board's JSON) or mstodo (Microsoft
+// Graph JSON). Tasks go to ?listId= when set, which the user must be able to
+// edit, and otherwise to the editable list named after their project. With ?dryRun=true nothing is changed and the job only
+// previews the outcome of each task. The import runs in the background; the
+// response is the pending job, whose progress is polled at its Location.
+func (h *ImportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	adapter, err := importer.New(query.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job := &model.ImportJob{Format: query.Get("format"), Filename: query.Get("filename")}
+	if raw := query.Get("listId"); raw != "" {
+		listID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid list id", http.StatusBadRequest)
+			return
+		}
+		job.ListID = &listID
+	}
+	if raw := query.Get("dryRun"); raw != "" {
+		if job.DryRun, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, "invalid dryRun", http.StatusBadRequest)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileBytes)
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		if job.Filename == "" {
+			job.Filename = header.Filename
+		}
+	}
+	tasks, err := adapter.Parse(body, job.Filename)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "export file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imports := make([]service.ImportTask, len(tasks))
+	for i, task := range tasks {
+		imports[i] = importTask(task)
+	}
+	job, err = h.Imports.Start(r.Context(), userID, job, imports)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImportTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, repository.ErrNotFound):
+			http.Error(w, "list not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/imports/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// List returns the user's import jobs, newest first, without their items.
+func (h *ImportHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jobs, err := h.Imports.List(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(jobs)
+}
+
+// Get reports the progress of an import job and the outcome of each task
+// processed so far.
+func (h *ImportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := parseIDParam(r, "importId")
+	if err != nil {
+		http.Error(w, "invalid import id", http.StatusBadRequest)
+		return
+	}
+	job, err := h.Imports.Get(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// importTask maps an exported task onto a todo, applying the same checks as
+// creating one through the API.
+func importTask(task importer.Task) service.ImportTask {
+	req := createTodoRequest{
+		Title:    strings.TrimSpace(task.Title),
+		Notes:    task.Notes,
+		DueAt:    task.Due,
+		Priority: task.Priority,
+		Tags:     task.Tags,
+	}
+	todo, err := req.todo()
+	if err != nil {
+		return service.ImportTask{Project: task.Project, Todo: &model.Todo{Title: req.Title}, Err: err, Warnings: task.Warnings}
+	}
+	todo.UID = task.ExternalID
+	todo.Completed = task.Completed
+	return service.ImportTask{Project: task.Project, Todo: todo, Warnings: task.Warnings}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+type fieldRequest struct {
+	Name    *string   `json:"name"`
+	Type    *string   `json:"type"`
+	Options *[]string `json:"options"`
+}
+
+func (req *fieldRequest) changes() service.FieldChanges {
+	return service.FieldChanges{Name: req.Name, Type: req.Type, Options: req.Options}
+}
+
+// Fields lists the custom fields of a list.
+func (h *ListHandler) Fields(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	fields, err := h.Lists.Fields(r.Context(), userID, listID)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(fields)
+}
+
+// CreateField defines a custom field on a list.
+func (h *ListHandler) CreateField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	var req fieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	field, err := h.Lists.CreateField(r.Context(), userID, listID, req.changes())
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(field)
+}
+
+// UpdateField renames a custom field or changes its options.
+func (h *ListHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	fieldID, err := parseIDParam(r, "fieldId")
+	if err != nil {
+		http.Error(w, "invalid field id", http.StatusBadRequest)
+		return
+	}
+	var req fieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	field, err := h.Lists.UpdateField(r.Context(), userID, listID, fieldID, req.changes())
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(field)
+}
+
+// DeleteField removes a custom field and every value todos have for it.
+func (h *ListHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	fieldID, err := parseIDParam(r, "fieldId")
+	if err != nil {
+		http.Error(w, "invalid field id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Lists.DeleteField(r.Context(), userID, listID, fieldID); err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+type ListHandler struct {
+	Lists *service.ListService
+	Users *service.UserService
+}
+
+func NewListHandler(lists *service.ListService, users *service.UserService) *ListHandler {
+	return &ListHandler{Lists: lists, Users: users}
+}
+
+type listRequest struct {
+	Name string `json:"name"`
+}
+
+type updateListRequest struct {
+	Name                *string `json:"name"`
+	EnforceDependencies *bool   `json:"enforceDependencies"`
+}
+
+type memberRequest struct {
+	UserID   int64  `json:"userId"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (h *ListHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	lists, err := h.Lists.ListByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lists)
+}
+
+func (h *ListHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Lists.Get(r.Context(), userID, listID)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req listRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Lists.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	var req updateListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Lists.Update(r.Context(), userID, listID, service.ListChanges{Name: req.Name, EnforceDependencies: req.EnforceDependencies})
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (h *ListHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Lists.Delete(r.Context(), userID, listID); err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ListHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	members, err := h.Lists.Members(r.Context(), userID, listID)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(members)
+}
+
+// AddMember shares a list with a user, identified by userId or username.
+func (h *ListHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	memberID := req.UserID
+	if memberID == 0 {
+		if req.Username == "" {
+			http.Error(w, "userId or username is required", http.StatusBadRequest)
+			return
+		}
+		user, err := h.Users.GetByUsername(r.Context(), req.Username)
+		if err != nil {
+			writeListError(w, r, err)
+			return
+		}
+		memberID = user.ID
+	}
+	member, err := h.Lists.SetMember(r.Context(), userID, listID, memberID, req.Role)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(member)
+}
+
+func (h *ListHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	memberID, err := parseIDParam(r, "userId")
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	member, err := h.Lists.SetMember(r.Context(), userID, listID, memberID, req.Role)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(member)
+}
+
+func (h *ListHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	memberID, err := parseIDParam(r, "userId")
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Lists.RemoveMember(r.Context(), userID, listID, memberID); err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeListError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "insufficient role on list", http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrInvalidListName),
+		errors.Is(err, service.ErrInvalidStatusName), errors.Is(err, service.ErrInvalidStatusCategory), errors.Is(err, service.ErrInvalidWIPLimit),
+		errors.Is(err, service.ErrInvalidFieldName), errors.Is(err, service.ErrInvalidFieldType), errors.Is(err, service.ErrInvalidFieldOptions),
+		errors.Is(err, service.ErrFieldTypeChange):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrLastOwner), errors.Is(err, service.ErrDefaultList), errors.Is(err, service.ErrDefaultListOwner),
+		errors.Is(err, service.ErrLastStatus), errors.Is(err, service.ErrStatusInUse),
+		errors.Is(err, service.ErrDuplicateField), errors.Is(err, service.ErrFieldOptionInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
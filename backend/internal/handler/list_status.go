@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	defaultBoardLimit = 100
+	maxBoardLimit     = 500
+)
+
+type statusRequest struct {
+	Name     *string `json:"name"`
+	Category *string `json:"category"`
+	Position *int    `json:"position"`
+	WIPLimit *int    `json:"wipLimit"`
+}
+
+func (req *statusRequest) changes() service.StatusChanges {
+	return service.StatusChanges{Name: req.Name, Category: req.Category, Position: req.Position, WIPLimit: req.WIPLimit}
+}
+
+// Statuses lists the workflow statuses of a list in board order.
+func (h *ListHandler) Statuses(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	statuses, err := h.Lists.Statuses(r.Context(), userID, listID)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(statuses)
+}
+
+// CreateStatus adds a status to a list; it goes last unless a position is
+// given.
+func (h *ListHandler) CreateStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	var req statusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	status, err := h.Lists.CreateStatus(r.Context(), userID, listID, req.changes())
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// UpdateStatus renames, recategorises, moves or limits a status.
+func (h *ListHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	statusID, err := parseIDParam(r, "statusId")
+	if err != nil {
+		http.Error(w, "invalid status id", http.StatusBadRequest)
+		return
+	}
+	var req statusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	status, err := h.Lists.UpdateStatus(r.Context(), userID, listID, statusID, req.changes())
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+func (h *ListHandler) DeleteStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	statusID, err := parseIDParam(r, "statusId")
+	if err != nil {
+		http.Error(w, "invalid status id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Lists.DeleteStatus(r.Context(), userID, listID, statusID); err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Board returns the todos of a list grouped by status. ?limit= caps the
+// todos per status; each column counts all of them.
+func (h *TodoHandler) Board(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, err := parseIDParam(r, "listId")
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return
+	}
+	limit := defaultBoardLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxBoardLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxBoardLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	board, err := h.Todos.Board(r.Context(), userID, listID, limit)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if wantsNotesHTML(r) {
+		for i := range board.Columns {
+			for j := range board.Columns[i].Todos {
+				if err := renderNotes(&board.Columns[i].Todos[j]); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(board)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+type NotificationHandler struct {
+	Notifications *service.NotificationService
+}
+
+func NewNotificationHandler(notifications *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{Notifications: notifications}
+}
+
+// List pages through the user's notifications newest first. ?unread=true
+// limits it to unread ones and ?before= takes the ID of the last notification
+// already seen; a Link header points at the next page.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	unreadOnly := query.Get("unread") == "true"
+	var (
+		beforeID int64
+		err      error
+	)
+	if raw := query.Get("before"); raw != "" {
+		beforeID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || beforeID < 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultNotificationLimit
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxNotificationLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxNotificationLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	notifications, err := h.Notifications.List(r.Context(), userID, unreadOnly, beforeID, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(notifications) == limit {
+		next := *r.URL
+		q := next.Query()
+		q.Set("before", strconv.FormatInt(notifications[len(notifications)-1].ID, 10))
+		q.Set("limit", strconv.Itoa(limit))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(notifications)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	notificationID, err := parseIDParam(r, "notificationId")
+	if err != nil {
+		http.Error(w, "invalid notification id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Notifications.MarkRead(r.Context(), userID, notificationID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.Notifications.MarkAllRead(r.Context(), userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+// defaultRangeDays is the length of a date range without ?from=.
+const defaultRangeDays = 30
+
+// Stats reports how many todos were created and completed per day or week
+// (?interval=day or week) from ?from= to ?to= (YYYY-MM-DD, inclusive), with
+// days counted in ?tz= (an IANA timezone, UTC by default). The range
+// defaults to the last 30 days; ?listId= limits it to one list.
+func (h *TodoHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	q := service.StatsQuery{Interval: query.Get("interval")}
+	if q.Interval == "" {
+		q.Interval = service.StatsDaily
+	}
+	if q.From, q.To, ok = parseDateRange(w, r); !ok {
+		return
+	}
+	if raw := query.Get("listId"); raw != "" {
+		var err error
+		if q.ListID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			http.Error(w, "invalid list id", http.StatusBadRequest)
+			return
+		}
+	}
+	stats, err := h.Todos.Stats(r.Context(), userID, q)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+// parseDateRange reads the days from ?from= to ?to= (YYYY-MM-DD, inclusive)
+// in ?tz= (an IANA timezone, UTC by default) as midnights in that timezone.
+// The range defaults to the last 30 days up to today. It writes an error
+// response and returns false when a parameter is invalid.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	query := r.URL.Query()
+	loc := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "invalid timezone", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	now := time.Now().In(loc)
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if raw := query.Get("to"); raw != "" {
+		var err error
+		if to, err = time.ParseInLocation(time.DateOnly, raw, loc); err != nil {
+			http.Error(w, "invalid to date", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	from = to.AddDate(0, 0, 1-defaultRangeDays)
+	if raw := query.Get("from"); raw != "" {
+		var err error
+		if from, err = time.ParseInLocation(time.DateOnly, raw, loc); err != nil {
+			http.Error(w, "invalid from date", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
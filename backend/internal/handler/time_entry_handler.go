@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+// maxTimeEntryNoteBytes bounds the note of a time entry.
+const maxTimeEntryNoteBytes = 1 << 10
+
+type TimeEntryHandler struct {
+	TimeEntries *service.TimeEntryService
+}
+
+func NewTimeEntryHandler(timeEntries *service.TimeEntryService) *TimeEntryHandler {
+	return &TimeEntryHandler{TimeEntries: timeEntries}
+}
+
+type timeEntryRequest struct {
+	StartedAt *time.Time `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+	Note      *string    `json:"note"`
+}
+
+// Running returns the user's running timer, or 204 when none is running.
+func (h *TimeEntryHandler) Running(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	entry, err := h.TimeEntries.Running(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// Start starts a timer on a todo, with an optional {"note"}. It fails with
+// 409 while another timer of the user is running.
+func (h *TimeEntryHandler) Start(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	var req timeEntryRequest
+	if r.ContentLength != 0 {
+		if !decodeTimeEntryBody(w, r, &req) {
+			return
+		}
+	}
+	var note string
+	if req.Note != nil {
+		note = *req.Note
+	}
+	entry, err := h.TimeEntries.Start(r.Context(), userID, todoID, note)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// Stop stops the user's timer on a todo.
+func (h *TimeEntryHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	entry, err := h.TimeEntries.Stop(r.Context(), userID, todoID)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// ListForTodo returns every time entry on a todo, oldest first.
+func (h *TimeEntryHandler) ListForTodo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	entries, err := h.TimeEntries.List(r.Context(), userID, repository.TimeEntryFilter{TodoID: todoID})
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Seconds
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Entries      []model.TimeEntry `json:"entries"`
+		TotalSeconds int64             `json:"totalSeconds"`
+	}{entries, total})
+}
+
+// Create records time spent on a todo: {"startedAt","endedAt","note"}.
+func (h *TimeEntryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	var req timeEntryRequest
+	if !decodeTimeEntryBody(w, r, &req) {
+		return
+	}
+	if req.StartedAt == nil || req.EndedAt == nil {
+		http.Error(w, "startedAt and endedAt are required", http.StatusBadRequest)
+		return
+	}
+	var note string
+	if req.Note != nil {
+		note = *req.Note
+	}
+	entry, err := h.TimeEntries.Create(r.Context(), userID, todoID, *req.StartedAt, *req.EndedAt, note)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// Update changes the startedAt, endedAt or note of a time entry. Setting
+// endedAt on a running timer stops it.
+func (h *TimeEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	entryID, err := parseIDParam(r, "entryId")
+	if err != nil {
+		http.Error(w, "invalid time entry id", http.StatusBadRequest)
+		return
+	}
+	var req timeEntryRequest
+	if !decodeTimeEntryBody(w, r, &req) {
+		return
+	}
+	entry, err := h.TimeEntries.Update(r.Context(), userID, todoID, entryID, service.TimeEntryChanges{
+		StartedAt: req.StartedAt,
+		EndedAt:   req.EndedAt,
+		Note:      req.Note,
+	})
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+func (h *TimeEntryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	entryID, err := parseIDParam(r, "entryId")
+	if err != nil {
+		http.Error(w, "invalid time entry id", http.StatusBadRequest)
+		return
+	}
+	if err := h.TimeEntries.Delete(r.Context(), userID, todoID, entryID); err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List returns the time entries in the user's lists that overlap the days
+// from ?from= to ?to= in ?tz=, oldest first. ?listId= and ?userId= narrow
+// them down.
+func (h *TimeEntryHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := parseTimeEntryFilter(w, r)
+	if !ok {
+		return
+	}
+	entries, err := h.TimeEntries.List(r.Context(), userID, filter)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+// ExportCSV downloads the entries List returns as CSV, with times in ?tz=.
+func (h *TimeEntryHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := parseTimeEntryFilter(w, r)
+	if !ok {
+		return
+	}
+	entries, err := h.TimeEntries.List(r.Context(), userID, filter)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	loc := filter.From.Location()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="time-entries.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "date", "started_at", "ended_at", "seconds", "hours", "user", "list_id", "todo_id", "todo", "note"})
+	for _, e := range entries {
+		var ended string
+		if e.EndedAt != nil {
+			ended = e.EndedAt.In(loc).Format(time.RFC3339)
+		}
+		_ = cw.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.StartedAt.In(loc).Format(time.DateOnly),
+			e.StartedAt.In(loc).Format(time.RFC3339),
+			ended,
+			strconv.FormatInt(e.Seconds, 10),
+			strconv.FormatFloat(float64(e.Seconds)/3600, 'f', 2, 64),
+			e.User.Username,
+			strconv.FormatInt(e.ListID, 10),
+			strconv.FormatInt(e.TodoID, 10),
+			e.TodoTitle,
+			e.Note,
+		})
+	}
+	cw.Flush()
+}
+
+// Totals sums the time spent per todo, or per list with ?groupBy=list, over
+// the days from ?from= to ?to= in ?tz=. Only the part of each entry within
+// the range counts.
+func (h *TimeEntryHandler) Totals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := parseTimeEntryFilter(w, r)
+	if !ok {
+		return
+	}
+	var byList bool
+	switch r.URL.Query().Get("groupBy") {
+	case "", "todo":
+	case "list":
+		byList = true
+	default:
+		http.Error(w, "groupBy must be todo or list", http.StatusBadRequest)
+		return
+	}
+	totals, err := h.TimeEntries.Totals(r.Context(), userID, filter, byList)
+	if err != nil {
+		writeTimeEntryError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(totals)
+}
+
+// parseTimeEntryFilter reads the date range and the ?listId= and ?userId=
+// filters of a query over time entries. It writes an error response and
+// returns false when a parameter is invalid.
+func parseTimeEntryFilter(w http.ResponseWriter, r *http.Request) (repository.TimeEntryFilter, bool) {
+	var filter repository.TimeEntryFilter
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return filter, false
+	}
+	end := to.AddDate(0, 0, 1)
+	filter.From, filter.To = &from, &end
+	query := r.URL.Query()
+	for name, dst := range map[string]*int64{"listId": &filter.ListID, "userId": &filter.UserID} {
+		if raw := query.Get(name); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return filter, false
+			}
+			*dst = id
+		}
+	}
+	return filter, true
+}
+
+func decodeTimeEntryBody(w http.ResponseWriter, r *http.Request, req *timeEntryRequest) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxTimeEntryNoteBytes+1<<10)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return false
+	}
+	if req.Note != nil && len(*req.Note) > maxTimeEntryNoteBytes {
+		http.Error(w, "note too long", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	return true
+}
+
+func writeTimeEntryError(w http.ResponseWriter, r *http.Request, err error) {
+	var running *service.TimerRunningError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "only its user or a list owner may change this time entry", http.StatusForbidden)
+	case errors.As(err, &running), errors.Is(err, service.ErrNoRunningTimer):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidTimeEntry):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
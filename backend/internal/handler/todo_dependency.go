@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+)
+
+type dependencyRequest struct {
+	BlockerID int64 `json:"blockerId"`
+}
+
+// Dependencies lists the todos blocking a todo and those it blocks.
+func (h *TodoHandler) Dependencies(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	deps, err := h.Todos.Dependencies(r.Context(), userID, todoID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(deps)
+}
+
+// AddDependency makes another todo block a todo, answering with the todo's
+// dependencies. Dependencies that would form a cycle are refused with 409.
+func (h *TodoHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	var req dependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.BlockerID == 0 {
+		http.Error(w, "blockerId is required", http.StatusBadRequest)
+		return
+	}
+	deps, err := h.Todos.AddDependency(r.Context(), userID, todoID, req.BlockerID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(deps)
+}
+
+func (h *TodoHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	blockerID, err := parseIDParam(r, "blockerId")
+	if err != nil {
+		http.Error(w, "invalid blocker id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Todos.RemoveDependency(r.Context(), userID, todoID, blockerID); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
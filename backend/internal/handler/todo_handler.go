@@ -2,55 +2,348 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/markdown"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
 )
 
+const (
+	// maxNotesBytes bounds the Markdown notes stored with a single todo.
+	maxNotesBytes = 64 << 10
+	// maxTodoBodyBytes bounds a create/update request body, leaving room for
+	// the notes plus the remaining fields.
+	maxTodoBodyBytes = maxNotesBytes + 16<<10
+
+	defaultTodoLimit = 100
+	maxTodoLimit     = 500
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
 type TodoHandler struct {
-	Todos      *service.TodoService
-	CreatedHub *EventHub
-	UpdatedHub *EventHub
-	DeletedHub *EventHub
+	Todos *service.TodoService
+	Lists *service.ListService
+	Users *service.UserService
+}
+
+func NewTodoHandler(todos *service.TodoService, lists *service.ListService, users *service.UserService) *TodoHandler {
+	return &TodoHandler{Todos: todos, Lists: lists, Users: users}
 }
 
-func NewTodoHandler(todos *service.TodoService) *TodoHandler {
-	return &TodoHandler{Todos: todos}
+// List pages through the todos the user can see. It accepts ?listId=,
+// ?statusId=, ?completed=, ?createdAfter= and ?createdBefore= (RFC 3339),
+// ?titleContains=, ?field.ID= (also .gte and .lte) on custom fields,
+// ?filter= (a filter query), ?sort= (comma-separated keys, - for
+// descending) and ?limit=. ?cursor= takes the X-Next-Cursor of the previous
+// page; a Link header points at the next page.
+func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, err := parseTodoFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	todos, next, err := h.Todos.List(r.Context(), userID, filter)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	writeTodoPage(w, r, todos, next, filter.Limit)
 }
 
-func (h *TodoHandler) SetWebSocketHubs(created, updated, deleted *EventHub) {
-	h.CreatedHub = created
-	h.UpdatedHub = updated
-	h.DeletedHub = deleted
+// writeTodoPage writes a page of todos from List or a view, with the
+// headers pointing at the next page when there is one.
+func writeTodoPage(w http.ResponseWriter, r *http.Request, todos []model.Todo, next string, limit int) {
+	if wantsNotesHTML(r) {
+		for i := range todos {
+			if err := renderNotes(&todos[i]); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if next != "" {
+		nextURL := *r.URL
+		q := nextURL.Query()
+		q.Set("cursor", next)
+		q.Set("limit", strconv.Itoa(limit))
+		nextURL.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.RequestURI()))
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	if notModified(w, r, todoListETag(todos, next)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(todos)
 }
 
-func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
+func parseTodoFilter(query url.Values) (repository.TodoFilter, error) {
+	filter := repository.TodoFilter{
+		TitleContains: query.Get("titleContains"),
+		Cursor:        query.Get("cursor"),
+		Limit:         defaultTodoLimit,
+	}
+	var err error
+	if raw := query.Get("listId"); raw != "" {
+		if filter.ListID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return filter, errors.New("invalid list id")
+		}
+	}
+	if raw := query.Get("statusId"); raw != "" {
+		if filter.StatusID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return filter, errors.New("invalid status id")
+		}
+	}
+	if raw := query.Get("completed"); raw != "" {
+		completed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, errors.New("invalid completed")
+		}
+		filter.Completed = &completed
+	}
+	for name, dst := range map[string]**time.Time{
+		"createdAfter":  &filter.CreatedAfter,
+		"createdBefore": &filter.CreatedBefore,
+	} {
+		if raw := query.Get(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: want RFC 3339", name)
+			}
+			*dst = &t
+		}
+	}
+	for name, values := range query {
+		raw, ok := strings.CutPrefix(name, "field.")
+		if !ok {
+			continue
+		}
+		op := "="
+		if id, suffix, ok := strings.Cut(raw, "."); ok {
+			ops := map[string]string{"gte": ">=", "lte": "<="}
+			if op, ok = ops[suffix]; !ok {
+				return filter, fmt.Errorf("invalid filter %s: want field.ID, field.ID.gte or field.ID.lte", name)
+			}
+			raw = id
+		}
+		fieldID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid filter %s: want field.ID, field.ID.gte or field.ID.lte", name)
+		}
+		for _, value := range values {
+			filter.Fields = append(filter.Fields, repository.FieldFilter{FieldID: fieldID, Op: op, Value: value})
+		}
+	}
+	if raw := query.Get("filter"); raw != "" {
+		expr, err := service.ParseQuery(raw)
+		if err != nil {
+			return filter, err
+		}
+		filter.Query = &repository.TodoQuery{Expr: expr}
+	}
+	if filter.Sort, err = repository.ParseTodoSort(query.Get("sort")); err != nil {
+		return filter, err
+	}
+	if raw := query.Get("limit"); raw != "" {
+		filter.Limit, err = strconv.Atoi(raw)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxTodoLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxTodoLimit)
+		}
+	}
+	return filter, nil
+}
+
+// Assigned lists the todos assigned to the current user across all lists.
+func (h *TodoHandler) Assigned(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	todos, err := h.Todos.ListByUser(r.Context(), userID)
+	todos, err := h.Todos.ListAssigned(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if wantsNotesHTML(r) {
+		for i := range todos {
+			if err := renderNotes(&todos[i]); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(todos)
 }
 
+// Search runs a full-text search over the titles and notes of the user's
+// todos. ?q= takes words, "quoted phrases" and prefix* terms, all of which
+// must match.
+func (h *TodoHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	limit := defaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	results, err := h.Todos.Search(r.Context(), userID, query.Get("q"), limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptyQuery) {
+			http.Error(w, "q is required", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
 type createTodoRequest struct {
-	Title string `json:"title"`
+	ListID     int64      `json:"listId"`
+	AssigneeID *int64     `json:"assigneeId"`
+	Title      string     `json:"title"`
+	Notes      string     `json:"notes"`
+	DueAt      *time.Time `json:"dueAt"`
+	RRule      string     `json:"rrule"`
+	Timezone   string     `json:"timezone"`
+	RepeatMode string     `json:"repeatMode"`
+	Priority   string     `json:"priority"`
+	Tags       []string   `json:"tags"`
+	StatusID   int64      `json:"statusId"`
+	// Fields holds custom field values by field ID.
+	Fields map[int64]any `json:"fields"`
 }
 
 type updateTodoRequest struct {
-	Title     *string `json:"title"`
-	Completed *bool   `json:"completed"`
+	ListID     *int64     `json:"listId"`
+	AssigneeID optionalID `json:"assigneeId"`
+	Title      *string    `json:"title"`
+	Notes      *string    `json:"notes"`
+	Completed  *bool      `json:"completed"`
+	DueAt      *time.Time `json:"dueAt"`
+	RRule      *string    `json:"rrule"`
+	Timezone   *string    `json:"timezone"`
+	RepeatMode *string    `json:"repeatMode"`
+	Priority   *string    `json:"priority"`
+	Tags       *[]string  `json:"tags"`
+	StatusID   *int64     `json:"statusId"`
+	// Fields sets the given custom field values, or removes those that are
+	// null, leaving the others alone.
+	Fields map[int64]any `json:"fields"`
+}
+
+var (
+	errTitleRequired = errors.New("title is required")
+	errNotesTooLong  = errors.New("notes too long")
+)
+
+// todo validates the request and builds the todo it describes.
+func (req *createTodoRequest) todo() (*model.Todo, error) {
+	if req.Title == "" {
+		return nil, errTitleRequired
+	}
+	if len(req.Notes) > maxNotesBytes {
+		return nil, errNotesTooLong
+	}
+	return &model.Todo{
+		ListID:     req.ListID,
+		AssigneeID: req.AssigneeID,
+		Title:      req.Title,
+		Notes:      req.Notes,
+		DueAt:      req.DueAt,
+		RRule:      req.RRule,
+		Timezone:   req.Timezone,
+		RepeatMode: req.RepeatMode,
+		Priority:   req.Priority,
+		Tags:       req.Tags,
+		StatusID:   req.StatusID,
+		Fields:     req.Fields,
+	}, nil
+}
+
+// apply validates the request and copies the fields it sets onto todo.
+func (req *updateTodoRequest) apply(todo *model.Todo) error {
+	if req.Notes != nil && len(*req.Notes) > maxNotesBytes {
+		return errNotesTooLong
+	}
+	if req.ListID != nil {
+		todo.ListID = *req.ListID
+	}
+	if req.AssigneeID.Set {
+		todo.AssigneeID = req.AssigneeID.Value
+	}
+	if req.Title != nil {
+		todo.Title = *req.Title
+	}
+	if req.Notes != nil {
+		todo.Notes = *req.Notes
+	}
+	if req.Completed != nil {
+		todo.Completed = *req.Completed
+	}
+	if req.DueAt != nil {
+		todo.DueAt = req.DueAt
+	}
+	if req.RRule != nil {
+		todo.RRule = *req.RRule
+	}
+	if req.Timezone != nil {
+		todo.Timezone = *req.Timezone
+	}
+	if req.RepeatMode != nil {
+		todo.RepeatMode = *req.RepeatMode
+	}
+	if req.Priority != nil {
+		todo.Priority = *req.Priority
+	}
+	if req.Tags != nil {
+		todo.Tags = *req.Tags
+	}
+	if req.StatusID != nil {
+		todo.StatusID = *req.StatusID
+	}
+	if len(req.Fields) > 0 {
+		fields := maps.Clone(todo.Fields)
+		if fields == nil {
+			fields = map[int64]any{}
+		}
+		for fieldID, value := range req.Fields {
+			fields[fieldID] = value
+		}
+		todo.Fields = fields
+	}
+	return nil
 }
 
 func (h *TodoHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -66,32 +359,75 @@ func (h *TodoHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req createTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+	if !decodeTodoBody(w, r, &req) {
 		return
 	}
-	if req.Title == "" {
-		http.Error(w, "title is required", http.StatusBadRequest)
+	if r.URL.Query().Get("parse") == "true" {
+		parsed, err := h.quickAdd(r.Context(), userID, req.Title, req.Timezone)
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		req.parse(parsed)
+	}
+	todo, err := req.todo()
+	if err != nil {
+		writeTodoError(w, r, err)
 		return
 	}
-	todo := &model.Todo{UserID: userID, Title: req.Title}
+	todo.UserID = userID
 	if err := h.Todos.Create(r.Context(), todo); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeTodoError(w, r, err)
 		return
 	}
 
-	// Broadcast the created todo to all WebSocket clients
-	if h.CreatedHub != nil {
-		if data, err := json.Marshal(todo); err == nil {
-			h.CreatedHub.Broadcast(data)
+	if wantsNotesHTML(r) {
+		if err := renderNotes(todo); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
-
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", todoRepresentationETag(todo))
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(todo)
 }
 
+// Get returns a single todo. It answers 304 when If-None-Match names the
+// todo's current ETag.
+func (h *TodoHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	todo, err := h.Todos.Get(r.Context(), userID, todoID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if notModified(w, r, todoRepresentationETag(todo)) {
+		return
+	}
+
+	if wantsNotesHTML(r) {
+		if err := renderNotes(todo); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(todo)
+}
+
+// Update applies a partial update. With If-Match it only succeeds while the
+// todo is still at the named version; without it, it still fails with 412
+// when the todo changes between being read and written.
 func (h *TodoHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Simulate a 2 second delay
 	// select {
@@ -112,41 +448,160 @@ func (h *TodoHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	todo, err := h.Todos.Get(r.Context(), userID, todoID)
 	if err != nil {
-		if err == repository.ErrNotFound {
-			http.NotFound(w, r)
+		writeTodoError(w, r, err)
+		return
+	}
+	if todoPreconditionFailed(w, r, todo) {
+		return
+	}
+	var req updateTodoRequest
+	if !decodeTodoBody(w, r, &req) {
+		return
+	}
+	if err := req.apply(todo); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if _, err := h.Todos.Update(r.Context(), userID, todo); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+
+	if wantsNotesHTML(r) {
+		if err := renderNotes(todo); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", todoRepresentationETag(todo))
+	_ = json.NewEncoder(w).Encode(todo)
+}
+
+// Delete moves a todo to the trash, honouring If-Match like Update.
+func (h *TodoHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	var req updateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
 		return
 	}
-	if req.Title != nil {
-		todo.Title = *req.Title
+	var ifVersion int64
+	if r.Header.Get("If-Match") != "" {
+		todo, err := h.Todos.Get(r.Context(), userID, todoID)
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		if todoPreconditionFailed(w, r, todo) {
+			return
+		}
+		ifVersion = todo.Version
 	}
-	if req.Completed != nil {
-		todo.Completed = *req.Completed
+	if err := h.Todos.Delete(r.Context(), userID, todoID, ifVersion); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Trash lists the deleted todos the user can restore.
+func (h *TodoHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
 	}
-	if err := h.Todos.Update(r.Context(), todo); err != nil {
+	todos, err := h.Todos.Trash(r.Context(), userID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(todos)
+}
 
-	// Broadcast the updated todo to all WebSocket clients
-	if h.UpdatedHub != nil {
-		if data, err := json.Marshal(todo); err == nil {
-			h.UpdatedHub.Broadcast(data)
-		}
+func (h *TodoHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	todo, err := h.Todos.Restore(r.Context(), userID, todoID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", todoRepresentationETag(todo))
 	_ = json.NewEncoder(w).Encode(todo)
 }
 
-func (h *TodoHandler) Delete(w http.ResponseWriter, r *http.Request) {
+// History lists the revisions of a todo, newest first.
+func (h *TodoHandler) History(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	revisions, err := h.Todos.History(r.Context(), userID, todoID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(revisions)
+}
+
+type revertTodoRequest struct {
+	RevisionID int64 `json:"revisionId"`
+}
+
+// Revert restores a todo to the state it had right after a revision.
+func (h *TodoHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	var req revertTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RevisionID == 0 {
+		http.Error(w, "revisionId is required", http.StatusBadRequest)
+		return
+	}
+	todo, err := h.Todos.Revert(r.Context(), userID, todoID, req.RevisionID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", todoRepresentationETag(todo))
+	_ = json.NewEncoder(w).Encode(todo)
+}
+
+func (h *TodoHandler) Occurrences(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -157,26 +612,121 @@ func (h *TodoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid todo id", http.StatusBadRequest)
 		return
 	}
-	if err := h.Todos.Delete(r.Context(), userID, todoID); err != nil {
-		if err == repository.ErrNotFound {
+	count := 5
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		count, err = strconv.Atoi(raw)
+		if err != nil || count < 1 {
+			http.Error(w, "invalid count", http.StatusBadRequest)
+			return
+		}
+	}
+	occurrences, err := h.Todos.Occurrences(r.Context(), userID, todoID, count)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			http.NotFound(w, r)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(occurrences)
+}
+
+func writeTodoError(w http.ResponseWriter, r *http.Request, err error) {
+	status, message := todoErrorStatus(err)
+	if status == http.StatusNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// todoErrorStatus maps an error from a todo operation to its HTTP status and
+// message.
+func todoErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound, "not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "insufficient role on list"
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed, err.Error()
+	case errors.Is(err, service.ErrDependencyCycle), errors.Is(err, service.ErrTodoBlocked), errors.Is(err, service.ErrWIPLimit),
+		errors.Is(err, service.ErrDuplicateView):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, errNotesTooLong):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, errTitleRequired),
+		errors.Is(err, service.ErrInvalidRecurrence),
+		errors.Is(err, service.ErrInvalidAssignee),
+		errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidStatus),
+		errors.Is(err, service.ErrInvalidField),
+		errors.Is(err, service.ErrInvalidFieldValue),
+		errors.Is(err, repository.ErrInvalidCursor),
+		errors.Is(err, repository.ErrInvalidQuery),
+		errors.Is(err, service.ErrInvalidViewName),
+		errors.Is(err, service.ErrInvalidViewSort),
+		errors.Is(err, service.ErrInvalidBatchOp),
+		errors.Is(err, service.ErrInvalidStatsRange),
+		errors.Is(err, service.ErrInvalidStatsInterval),
+		errors.Is(err, recurrence.ErrInvalidTimezone):
+		return http.StatusBadRequest, err.Error()
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+// optionalID is a nullable ID in a partial update, telling an absent field
+// (leave unchanged) apart from an explicit null (clear).
+type optionalID struct {
+	Set   bool
+	Value *int64
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
 
-	// Broadcast the deleted todo ID to all WebSocket clients
-	if h.DeletedHub != nil {
-		type deletedEvent struct {
-			ID int64 `json:"id"`
+// decodeTodoBody decodes a size-limited JSON todo payload into dst, writing an
+// error response and returning false when the body is too large or invalid.
+func decodeTodoBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxTodoBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return false
 		}
-		if data, err := json.Marshal(deletedEvent{ID: todoID}); err == nil {
-			h.DeletedHub.Broadcast(data)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// wantsNotesHTML reports whether the client asked for rendered notes with
+// ?include=notesHtml.
+func wantsNotesHTML(r *http.Request) bool {
+	for _, include := range r.URL.Query()["include"] {
+		for _, part := range strings.Split(include, ",") {
+			if part == "notesHtml" {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	w.WriteHeader(http.StatusNoContent)
+func renderNotes(todo *model.Todo) error {
+	html, err := markdown.Render(todo.Notes)
+	if err != nil {
+		return err
+	}
+	todo.NotesHTML = html
+	return nil
 }
 
 func parseIDParam(r *http.Request, name string) (int64, error) {
@@ -0,0 +1,300 @@
+//go:build sqlite_fts5
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/migrations"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+// testUsers signs requests in by username, standing in for app passwords.
+type testUsers map[string]int64
+
+func (u testUsers) AuthenticateAppPassword(ctx context.Context, username, password string) (int64, error) {
+	if id, ok := u[username]; ok {
+		return id, nil
+	}
+	return 0, errors.New("unknown user")
+}
+
+// todoServer serves the todo routes over a migrated database in a temporary
+// directory, for a user named alice who owns one list.
+type todoServer struct {
+	t      *testing.T
+	db     *repository.DB
+	router chi.Router
+	userID int64
+	listID int64
+}
+
+func newTodoServer(t *testing.T) *todoServer {
+	t.Helper()
+	db, err := repository.NewDB("file:" + t.TempDir() + "/test.db?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Up(db.DB); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	user := &model.User{Username: "alice", PasswordHash: "x"}
+	if err := db.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	list := &model.List{Name: "Home", CreatedBy: user.ID}
+	if err := db.CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewTodoHandler(service.NewTodoService(db), service.NewListService(db), service.NewUserService(db))
+	r := chi.NewRouter()
+	r.Use(middleware.BasicAuthGuard(nil, testUsers{"alice": user.ID}, "test"))
+	r.Get("/todos", h.List)
+	r.Post("/todos", h.Create)
+	r.Get("/todos/{id}", h.Get)
+	r.Put("/todos/{id}", h.Update)
+	r.Patch("/todos/{id}", h.Patch)
+	r.Delete("/todos/{id}", h.Delete)
+	r.Post("/todos/{id}/dependencies", h.AddDependency)
+	return &todoServer{t: t, db: db, router: r, userID: user.ID, listID: list.ID}
+}
+
+// do sends a request as alice with the given headers, as name/value pairs.
+func (s *todoServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.SetBasicAuth("alice", "app-password")
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// create adds a todo to alice's list from a JSON body without the listId.
+func (s *todoServer) create(fields string) *model.Todo {
+	s.t.Helper()
+	body := fmt.Sprintf(`{"listId":%d,%s}`, s.listID, fields)
+	w := s.do(http.MethodPost, "/todos", body)
+	if w.Code != http.StatusCreated {
+		s.t.Fatalf("create %s = %d %s", body, w.Code, w.Body.String())
+	}
+	var todo model.Todo
+	if err := json.Unmarshal(w.Body.Bytes(), &todo); err != nil {
+		s.t.Fatal(err)
+	}
+	return &todo
+}
+
+func TestTodoGetETag(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk"`)
+	path := fmt.Sprintf("/todos/%d", todo.ID)
+
+	w := s.do(http.MethodGet, path, "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != todoRepresentationETag(todo) {
+		t.Fatalf("GET = %d with ETag %s, want 200 with %s", w.Code, etag, todoRepresentationETag(todo))
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+		{fmt.Sprintf(`"%d-%d"`, todo.ID, todo.Version+1), http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := s.do(http.MethodGet, path, "", "If-None-Match", tt.ifNoneMatch)
+		if w.Code != tt.want {
+			t.Errorf("GET with If-None-Match %s = %d, want %d", tt.ifNoneMatch, w.Code, tt.want)
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("GET with If-None-Match %s has ETag %s, want %s", tt.ifNoneMatch, w.Header().Get("ETag"), etag)
+		}
+		if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("304 has a body: %s", w.Body.String())
+		}
+	}
+
+	// Updating moves the ETag on, and the old one no longer revalidates.
+	if w := s.do(http.MethodPut, path, `{"title":"buy oat milk"}`); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("PUT = %d with ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := s.do(http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("GET with a stale If-None-Match = %d, want 200", w.Code)
+	}
+}
+
+func TestTodoIfMatch(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk"`)
+	path := fmt.Sprintf("/todos/%d", todo.ID)
+	etag := todoETag(todo)
+	// GET answers with the representation tag, which If-Match accepts too.
+	if w := s.do(http.MethodPut, path, `{"notes":"x"}`, "If-Match", todoRepresentationETag(todo)); w.Code != http.StatusOK {
+		t.Fatalf("PUT with the representation tag = %d %s", w.Code, w.Body.String())
+	}
+	todo.Version++
+	etag = todoETag(todo)
+
+	// Weak tags never match If-Match, and neither do stale ones.
+	for _, ifMatch := range []string{`"other"`, "W/" + etag, fmt.Sprintf(`"%d-%d"`, todo.ID, todo.Version-1)} {
+		if w := s.do(http.MethodPut, path, `{"title":"x"}`, "If-Match", ifMatch); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("PUT with If-Match %s = %d, want 412", ifMatch, w.Code)
+		}
+		if w := s.do(http.MethodDelete, path, "", "If-Match", ifMatch); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("DELETE with If-Match %s = %d, want 412", ifMatch, w.Code)
+		}
+	}
+	if w := s.do(http.MethodGet, path, ""); !strings.Contains(w.Body.String(), `"buy milk"`) {
+		t.Fatalf("a failed precondition changed the todo: %s", w.Body.String())
+	}
+
+	w := s.do(http.MethodPut, path, `{"title":"buy oat milk"}`, "If-Match", `"other", `+etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT with a matching If-Match = %d %s", w.Code, w.Body.String())
+	}
+	next := w.Header().Get("ETag")
+	if !strings.HasPrefix(next, fmt.Sprintf(`"%d-%d-`, todo.ID, todo.Version+1)) {
+		t.Errorf("ETag after PUT = %s", next)
+	}
+	// A second writer holding the old ETag loses.
+	if w := s.do(http.MethodPut, path, `{"title":"buy soy milk"}`, "If-Match", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with the old ETag = %d, want 412", w.Code)
+	}
+	if w := s.do(http.MethodPut, path, `{"completed":true}`, "If-Match", "*"); w.Code != http.StatusOK {
+		t.Errorf("PUT with If-Match * = %d, want 200", w.Code)
+	}
+
+	if w := s.do(http.MethodDelete, path, "", "If-Match", next); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with a stale ETag = %d, want 412", w.Code)
+	}
+	current := s.do(http.MethodGet, path, "").Header().Get("ETag")
+	if w := s.do(http.MethodDelete, path, "", "If-Match", current); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE with the current ETag = %d, want 204", w.Code)
+	}
+	if w := s.do(http.MethodDelete, path, "", "If-Match", current); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE of a deleted todo = %d, want 404", w.Code)
+	}
+}
+
+func TestTodoListNotModified(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk"`)
+	s.create(`"title":"pay rent"`)
+
+	w := s.do(http.MethodGet, "/todos", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("GET /todos = %d with ETag %s, want a weak tag", w.Code, etag)
+	}
+	if w := s.do(http.MethodGet, "/todos", "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("GET /todos with its ETag = %d, want 304", w.Code)
+	}
+	s.do(http.MethodPut, fmt.Sprintf("/todos/%d", todo.ID), `{"completed":true}`)
+	if w := s.do(http.MethodGet, "/todos", "", "If-None-Match", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("GET /todos after an update = %d with ETag %s, want a new page", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// TestTodoGetDerivedFields checks that GET does not answer 304 with a stale
+// blocked flag or status, which change without the todo's version.
+func TestTodoGetDerivedFields(t *testing.T) {
+	s := newTodoServer(t)
+	blocker := s.create(`"title":"buy paint"`)
+	todo := s.create(`"title":"paint the fence"`)
+	path := fmt.Sprintf("/todos/%d", todo.ID)
+	if w := s.do(http.MethodPost, path+"/dependencies", fmt.Sprintf(`{"blockerId":%d}`, blocker.ID)); w.Code != http.StatusCreated {
+		t.Fatalf("add dependency = %d %s", w.Code, w.Body.String())
+	}
+
+	get := func(ifNoneMatch string) (*httptest.ResponseRecorder, model.Todo) {
+		t.Helper()
+		w := s.do(http.MethodGet, path, "", "If-None-Match", ifNoneMatch)
+		var got model.Todo
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w, got
+	}
+	w, got := get("")
+	etag := w.Header().Get("ETag")
+	if !got.Blocked {
+		t.Fatalf("todo = %+v, want it blocked", got)
+	}
+
+	if w := s.do(http.MethodPut, fmt.Sprintf("/todos/%d", blocker.ID), `{"completed":true}`); w.Code != http.StatusOK {
+		t.Fatalf("completing the blocker = %d %s", w.Code, w.Body.String())
+	}
+	w, got = get(etag)
+	if w.Code != http.StatusOK || got.Blocked {
+		t.Fatalf("GET after the blocker was completed = %d blocked=%v, want 200 and unblocked", w.Code, got.Blocked)
+	}
+	if got.Version != todo.Version || w.Header().Get("ETag") == etag {
+		t.Errorf("unblocking gave version %d and ETag %s, want version %d and a new ETag", got.Version, w.Header().Get("ETag"), todo.Version)
+	}
+	// If-Match still compares versions only.
+	if w := s.do(http.MethodPut, path, `{"notes":"two coats"}`, "If-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("PUT with the tag from before unblocking = %d, want 200", w.Code)
+	}
+
+	w, got = get("")
+	etag = w.Header().Get("ETag")
+	name := "Backlog"
+	if _, err := service.NewListService(s.db).UpdateStatus(context.Background(), s.userID, s.listID, got.StatusID, service.StatusChanges{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	w, got = get(etag)
+	if w.Code != http.StatusOK || got.Status == nil || got.Status.Name != name {
+		t.Errorf("GET after renaming the status = %d with %+v, want 200 with %s", w.Code, got.Status, name)
+	}
+	if w, _ := get(w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("GET with the new ETag = %d, want 304", w.Code)
+	}
+}
+
+func TestTodoCompleteBlocked(t *testing.T) {
+	s := newTodoServer(t)
+	blocker := s.create(`"title":"buy paint"`)
+	todo := s.create(`"title":"paint the fence"`)
+	path := fmt.Sprintf("/todos/%d", todo.ID)
+	if w := s.do(http.MethodPost, path+"/dependencies", fmt.Sprintf(`{"blockerId":%d}`, blocker.ID)); w.Code != http.StatusCreated {
+		t.Fatalf("add dependency = %d %s", w.Code, w.Body.String())
+	}
+	if w := s.do(http.MethodPost, fmt.Sprintf("/todos/%d/dependencies", blocker.ID), fmt.Sprintf(`{"blockerId":%d}`, todo.ID)); w.Code != http.StatusConflict {
+		t.Errorf("adding a cycle = %d, want 409", w.Code)
+	}
+	enforce := true
+	if _, err := service.NewListService(s.db).Update(context.Background(), s.userID, s.listID, service.ListChanges{EnforceDependencies: &enforce}); err != nil {
+		t.Fatal(err)
+	}
+	if w := s.do(http.MethodPut, path, `{"completed":true}`); w.Code != http.StatusConflict {
+		t.Errorf("completing a blocked todo = %d %s, want 409", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/ical"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+const (
+	// maxImportBytes bounds an uploaded calendar.
+	maxImportBytes = 5 << 20
+	// maxImportTodos bounds the todos a single import may contain.
+	maxImportTodos = 1000
+)
+
+// Export downloads the user's todos, or those of ?listId=, as an iCalendar
+// file of VTODO components.
+func (h *TodoHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter := repository.TodoFilter{Limit: maxTodoLimit}
+	if raw := r.URL.Query().Get("listId"); raw != "" {
+		var err error
+		if filter.ListID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			http.Error(w, "invalid list id", http.StatusBadRequest)
+			return
+		}
+	}
+	all, err := allTodos(r.Context(), h.Todos, userID, filter)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	todos := make([]ical.Todo, len(all))
+	for i := range all {
+		todos[i] = icalTodo(&all[i])
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.ics"`)
+	_ = ical.Encode(w, todos, time.Now())
+}
+
+// allTodos returns every todo matching filter, reading it page by page.
+func allTodos(ctx context.Context, todos *service.TodoService, userID int64, filter repository.TodoFilter) ([]model.Todo, error) {
+	var all []model.Todo
+	for {
+		page, next, err := todos.List(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if next == "" {
+			return all, nil
+		}
+		filter.Cursor = next
+	}
+}
+
+func icalTodo(todo *model.Todo) ical.Todo {
+	status := ical.StatusNeedsAction
+	if todo.Completed {
+		status = ical.StatusCompleted
+	}
+	return ical.Todo{
+		UID:          todo.UID,
+		Summary:      todo.Title,
+		Description:  todo.Notes,
+		Status:       status,
+		Created:      todo.CreatedAt,
+		LastModified: todo.UpdatedAt,
+		Due:          todo.DueAt,
+		Completed:    todo.CompletedAt,
+		Sequence:     int(todo.Version - 1),
+		RRule:        todo.RRule,
+	}
+}
+
+type importItemResult struct {
+	Index  int         `json:"index"`
+	UID    string      `json:"uid,omitempty"`
+	Result string      `json:"result"`
+	Todo   *model.Todo `json:"todo,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Import reads an iCalendar file, sent as the request body or as the file
+// field of a multipart form, and imports its VTODOs. Todos already imported
+// or exported before, recognised by their UID, are updated; new ones go to
+// ?listId= or the user's Inbox. The response reports every VTODO in order.
+func (h *TodoHandler) Import(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var listID int64
+	if raw := r.URL.Query().Get("listId"); raw != "" {
+		var err error
+		if listID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			http.Error(w, "invalid list id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	items, err := ical.Decode(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "calendar too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(items) > maxImportTodos {
+		http.Error(w, fmt.Sprintf("at most %d todos can be imported at once", maxImportTodos), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]importItemResult, len(items))
+	var (
+		todos   []*model.Todo
+		indexes []int
+	)
+	for i, item := range items {
+		results[i] = importItemResult{Index: i, UID: item.Todo.UID}
+		todo, err := importedTodo(item)
+		if err != nil {
+			results[i].Result = service.ImportFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		todos = append(todos, todo)
+		indexes = append(indexes, i)
+	}
+	imported, err := h.Todos.Import(r.Context(), userID, listID, todos)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for j, res := range imported {
+		result := &results[indexes[j]]
+		result.Result = res.Outcome
+		result.Todo = res.Todo
+		if res.Err != nil {
+			_, result.Error = todoErrorStatus(res.Err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Results []importItemResult `json:"results"`
+	}{results})
+}
+
+// importedTodo maps a decoded VTODO onto a todo, applying the same checks as
+// creating one through the API.
+func importedTodo(item ical.Item) (*model.Todo, error) {
+	if item.Err != nil {
+		return nil, item.Err
+	}
+	t := item.Todo
+	req := createTodoRequest{
+		Title: strings.TrimSpace(t.Summary),
+		Notes: t.Description,
+		DueAt: t.Due,
+		RRule: t.RRule,
+	}
+	if t.RRule != "" {
+		req.Timezone = t.DueTZID
+	}
+	todo, err := req.todo()
+	if err != nil {
+		return nil, err
+	}
+	todo.UID = t.UID
+	todo.Completed = t.Status == ical.StatusCompleted || t.Completed != nil
+	todo.CompletedAt = t.Completed
+	return todo, nil
+}
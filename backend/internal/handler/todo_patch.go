@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// patchableTodo lists the fields of a todo a patch may change. Every field is
+// always present in the document being patched, null when unset, so that
+// JSON Patch can replace or remove it.
+type patchableTodo struct {
+	ListID     int64      `json:"listId"`
+	AssigneeID *int64     `json:"assigneeId"`
+	Title      string     `json:"title"`
+	Notes      *string    `json:"notes"`
+	Completed  bool       `json:"completed"`
+	DueAt      *time.Time `json:"dueAt"`
+	RRule      *string    `json:"rrule"`
+	Timezone   *string    `json:"timezone"`
+	RepeatMode *string    `json:"repeatMode"`
+	Priority   *string    `json:"priority"`
+	Tags       []string   `json:"tags"`
+	StatusID   int64      `json:"statusId"`
+	// Fields holds custom field values by field ID.
+	Fields map[int64]any `json:"fields"`
+}
+
+// nullableTodoFields are the patchable fields that may be removed or set to
+// null, which clears them.
+var nullableTodoFields = map[string]bool{
+	"assigneeId": true,
+	"notes":      true,
+	"dueAt":      true,
+	"rrule":      true,
+	"timezone":   true,
+	"repeatMode": true,
+	"priority":   true,
+	"tags":       true,
+	"fields":     true,
+}
+
+// todoPatchError is a patch that applied cleanly but produced a todo that
+// cannot be saved, such as one changing a read-only field.
+type todoPatchError struct {
+	field string
+	msg   string
+}
+
+func (e *todoPatchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.field, e.msg)
+}
+
+// Patch updates a todo with a JSON Merge Patch (RFC 7396) or a JSON Patch
+// (RFC 6902), chosen by the Content-Type. The patch applies to the todo as
+// returned by the API; only the fields of patchableTodo may change, and
+// If-Match is honoured as for Update.
+func (h *TodoHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	todoID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid todo id", http.StatusBadRequest)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		http.Error(w, "unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxTodoBodyBytes)
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	todo, err := h.Todos.Get(r.Context(), userID, todoID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if todoPreconditionFailed(w, r, todo) {
+		return
+	}
+	doc, err := todoPatchDocument(todo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var patched []byte
+	if mediaType == mergePatchType {
+		patched, err = jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			http.Error(w, "invalid merge patch", http.StatusBadRequest)
+			return
+		}
+	} else {
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			http.Error(w, "invalid JSON patch", http.StatusBadRequest)
+			return
+		}
+		patched, err = ops.Apply(doc)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) || errors.Is(err, jsonpatch.ErrMissing) || errors.Is(err, jsonpatch.ErrInvalidIndex) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := applyTodoPatch(todo, doc, patched); err != nil {
+		var patchErr *todoPatchError
+		if errors.As(err, &patchErr) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeTodoError(w, r, err)
+		return
+	}
+	if _, err := h.Todos.Update(r.Context(), userID, todo); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+
+	if wantsNotesHTML(r) {
+		if err := renderNotes(todo); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", todoRepresentationETag(todo))
+	_ = json.NewEncoder(w).Encode(todo)
+}
+
+// todoPatchDocument renders todo as the JSON object patches apply to.
+func todoPatchDocument(todo *model.Todo) ([]byte, error) {
+	doc := map[string]any{}
+	for _, v := range []any{todo, todoPatchFields(todo)} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func todoPatchFields(todo *model.Todo) patchableTodo {
+	orNil := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	return patchableTodo{
+		ListID:     todo.ListID,
+		AssigneeID: todo.AssigneeID,
+		Title:      todo.Title,
+		Notes:      orNil(todo.Notes),
+		Completed:  todo.Completed,
+		DueAt:      todo.DueAt,
+		RRule:      orNil(todo.RRule),
+		Timezone:   orNil(todo.Timezone),
+		RepeatMode: orNil(todo.RepeatMode),
+		Priority:   orNil(todo.Priority),
+		Tags:       todo.Tags,
+		StatusID:   todo.StatusID,
+		Fields:     todo.Fields,
+	}
+}
+
+// applyTodoPatch checks the patched document against the original one and
+// copies the patchable fields onto todo. Changing any other field, adding an
+// unknown one or nulling a required one is a todoPatchError.
+func applyTodoPatch(todo *model.Todo, original, patched []byte) error {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return &todoPatchError{field: "todo", msg: "must be an object"}
+	}
+
+	patchable := map[string]bool{}
+	fields := reflect.TypeOf(patchableTodo{})
+	for i := 0; i < fields.NumField(); i++ {
+		patchable[fields.Field(i).Tag.Get("json")] = true
+	}
+	for name, value := range after {
+		if patchable[name] {
+			if string(value) == "null" && !nullableTodoFields[name] {
+				return &todoPatchError{field: name, msg: "cannot be null"}
+			}
+			continue
+		}
+		old, ok := before[name]
+		if !ok {
+			return &todoPatchError{field: name, msg: "unknown field"}
+		}
+		if !sameJSON(old, value) {
+			return &todoPatchError{field: name, msg: "read-only field"}
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; ok {
+			continue
+		}
+		if !patchable[name] {
+			return &todoPatchError{field: name, msg: "read-only field"}
+		}
+		if !nullableTodoFields[name] {
+			return &todoPatchError{field: name, msg: "cannot be null"}
+		}
+	}
+
+	changes := map[string]json.RawMessage{}
+	for name := range patchable {
+		if value, ok := after[name]; ok {
+			changes[name] = value
+		}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	var next patchableTodo
+	if err := json.Unmarshal(data, &next); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &todoPatchError{field: typeErr.Field, msg: "invalid value"}
+		}
+		var timeErr *time.ParseError
+		if errors.As(err, &timeErr) {
+			return &todoPatchError{field: "dueAt", msg: "invalid time"}
+		}
+		return &todoPatchError{field: "todo", msg: err.Error()}
+	}
+
+	if next.Title == "" {
+		return errTitleRequired
+	}
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	if len(deref(next.Notes)) > maxNotesBytes {
+		return errNotesTooLong
+	}
+	todo.ListID = next.ListID
+	todo.AssigneeID = next.AssigneeID
+	todo.Title = next.Title
+	todo.Notes = deref(next.Notes)
+	todo.Completed = next.Completed
+	todo.DueAt = next.DueAt
+	todo.RRule = deref(next.RRule)
+	todo.Timezone = deref(next.Timezone)
+	todo.RepeatMode = deref(next.RepeatMode)
+	todo.Priority = deref(next.Priority)
+	todo.Tags = next.Tags
+	todo.StatusID = next.StatusID
+	todo.Fields = next.Fields
+	return nil
+}
+
+// sameJSON reports whether a and b encode the same value.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,165 @@
+//go:build sqlite_fts5
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// patch sends a PATCH of the given media type and decodes the todo it
+// returns on success.
+func (s *todoServer) patch(todo *model.Todo, mediaType, body string, headers ...string) (int, *model.Todo, string) {
+	s.t.Helper()
+	headers = append([]string{"Content-Type", mediaType}, headers...)
+	w := s.do(http.MethodPatch, fmt.Sprintf("/todos/%d", todo.ID), body, headers...)
+	if w.Code != http.StatusOK {
+		return w.Code, nil, w.Body.String()
+	}
+	var patched model.Todo
+	if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil {
+		s.t.Fatal(err)
+	}
+	if etag := w.Header().Get("ETag"); etag != todoRepresentationETag(&patched) {
+		s.t.Errorf("PATCH ETag = %s, want %s", etag, todoRepresentationETag(&patched))
+	}
+	return w.Code, &patched, ""
+}
+
+func TestTodoMergePatch(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk","notes":"semi-skimmed","priority":"B","tags":["shop"],"dueAt":"2026-10-20T12:00:00Z"`)
+
+	code, patched, msg := s.patch(todo, mergePatchType, `{"title":"buy oat milk","notes":null,"dueAt":null,"tags":["shop","vegan"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("merge patch = %d %s", code, msg)
+	}
+	if patched.Title != "buy oat milk" || patched.Notes != "" || patched.DueAt != nil || !slices.Equal(patched.Tags, []string{"shop", "vegan"}) {
+		t.Errorf("patched todo = %+v", patched)
+	}
+	// Fields the patch leaves out keep their values.
+	if patched.Priority != "B" || patched.Version != todo.Version+1 {
+		t.Errorf("patched todo = %+v, want priority B at version %d", patched, todo.Version+1)
+	}
+
+	// Read-only fields may appear as long as they do not change.
+	body := fmt.Sprintf(`{"id":%d,"uid":%q,"version":%d,"completed":true}`, patched.ID, patched.UID, patched.Version)
+	if code, patched, msg = s.patch(patched, mergePatchType, body); code != http.StatusOK || !patched.Completed {
+		t.Errorf("merge patch with unchanged read-only fields = %d %s", code, msg)
+	}
+}
+
+func TestTodoJSONPatch(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk","notes":"semi-skimmed","tags":["shop"]`)
+
+	code, patched, msg := s.patch(todo, jsonPatchType, `[
+		{"op":"test","path":"/title","value":"buy milk"},
+		{"op":"replace","path":"/title","value":"buy oat milk"},
+		{"op":"remove","path":"/notes"},
+		{"op":"add","path":"/tags/-","value":"vegan"},
+		{"op":"add","path":"/priority","value":"A"}
+	]`)
+	if code != http.StatusOK {
+		t.Fatalf("JSON patch = %d %s", code, msg)
+	}
+	if patched.Title != "buy oat milk" || patched.Notes != "" || patched.Priority != "A" || !slices.Equal(patched.Tags, []string{"shop", "vegan"}) {
+		t.Errorf("patched todo = %+v", patched)
+	}
+
+	// A failed test or a missing path leaves the todo as it was.
+	for _, body := range []string{
+		`[{"op":"test","path":"/title","value":"buy milk"},{"op":"replace","path":"/title","value":"x"}]`,
+		`[{"op":"replace","path":"/tags/5","value":"x"}]`,
+		`[{"op":"remove","path":"/dueAt/nested"}]`,
+	} {
+		if code, _, msg := s.patch(patched, jsonPatchType, body); code != http.StatusConflict {
+			t.Errorf("JSON patch %s = %d %s, want 409", body, code, msg)
+		}
+	}
+	w := s.do(http.MethodGet, fmt.Sprintf("/todos/%d", todo.ID), "")
+	if !strings.Contains(w.Body.String(), `"buy oat milk"`) || w.Header().Get("ETag") != todoRepresentationETag(patched) {
+		t.Errorf("a failed patch changed the todo: %s", w.Body.String())
+	}
+}
+
+// TestTodoPatchAllowList checks that a patch can only change the fields of
+// patchableTodo, and can only clear the nullable ones.
+func TestTodoPatchAllowList(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk"`)
+
+	tests := []struct {
+		mediaType string
+		body      string
+		want      int
+		msg       string
+	}{
+		{mergePatchType, `{"id":999}`, http.StatusUnprocessableEntity, "id: read-only field"},
+		{mergePatchType, `{"version":99}`, http.StatusUnprocessableEntity, "version: read-only field"},
+		{mergePatchType, `{"userId":999}`, http.StatusUnprocessableEntity, "userId: read-only field"},
+		{mergePatchType, `{"createdAt":"2020-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity, "createdAt: read-only field"},
+		{mergePatchType, `{"blocked":true}`, http.StatusUnprocessableEntity, "blocked: read-only field"},
+		{mergePatchType, `{"creator":{"username":"mallory"}}`, http.StatusUnprocessableEntity, "creator: read-only field"},
+		{mergePatchType, `{"uid":null}`, http.StatusUnprocessableEntity, "uid: read-only field"},
+		{mergePatchType, `{"deletedAt":"2026-10-19T00:00:00Z"}`, http.StatusUnprocessableEntity, "deletedAt: unknown field"},
+		{mergePatchType, `{"notesHtml":"<b>x</b>"}`, http.StatusUnprocessableEntity, "notesHtml: unknown field"},
+		{mergePatchType, `{"bogus":1}`, http.StatusUnprocessableEntity, "bogus: unknown field"},
+		{mergePatchType, `{"title":null}`, http.StatusUnprocessableEntity, "title: cannot be null"},
+		{mergePatchType, `{"listId":null}`, http.StatusUnprocessableEntity, "listId: cannot be null"},
+		{mergePatchType, `{"completed":null}`, http.StatusUnprocessableEntity, "completed: cannot be null"},
+		{mergePatchType, `{"title":5}`, http.StatusUnprocessableEntity, "title: invalid value"},
+		{mergePatchType, `{"dueAt":"tomorrow"}`, http.StatusUnprocessableEntity, "dueAt: invalid time"},
+		{mergePatchType, `{"title":""}`, http.StatusBadRequest, errTitleRequired.Error()},
+		{mergePatchType, `[1]`, http.StatusUnprocessableEntity, "todo: must be an object"},
+		{mergePatchType, `{`, http.StatusBadRequest, "invalid merge patch"},
+		{jsonPatchType, `[{"op":"replace","path":"/id","value":999}]`, http.StatusUnprocessableEntity, "id: read-only field"},
+		{jsonPatchType, `[{"op":"remove","path":"/version"}]`, http.StatusUnprocessableEntity, "version: read-only field"},
+		{jsonPatchType, `[{"op":"remove","path":"/title"}]`, http.StatusUnprocessableEntity, "title: cannot be null"},
+		{jsonPatchType, `[{"op":"remove","path":"/completed"}]`, http.StatusUnprocessableEntity, "completed: cannot be null"},
+		{jsonPatchType, `[{"op":"add","path":"/bogus","value":1}]`, http.StatusUnprocessableEntity, "bogus: unknown field"},
+		{jsonPatchType, `[{"op":"move","from":"/title","path":"/uid"}]`, http.StatusUnprocessableEntity, ""},
+		{jsonPatchType, `[{"op":"replace","path":"","value":[]}]`, http.StatusUnprocessableEntity, "todo: must be an object"},
+		{jsonPatchType, `{"op":"replace"}`, http.StatusBadRequest, "invalid JSON patch"},
+		{jsonPatchType, `[{"op":"frobnicate","path":"/title"}]`, http.StatusBadRequest, ""},
+		{"application/json", `{"title":"x"}`, http.StatusUnsupportedMediaType, "unsupported patch format"},
+	}
+	for _, tt := range tests {
+		code, _, msg := s.patch(todo, tt.mediaType, tt.body)
+		if code != tt.want || !strings.HasPrefix(msg, tt.msg) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.mediaType, tt.body, code, strings.TrimSpace(msg), tt.want, tt.msg)
+		}
+	}
+
+	w := s.do(http.MethodGet, fmt.Sprintf("/todos/%d", todo.ID), "")
+	if w.Header().Get("ETag") != todoRepresentationETag(todo) {
+		t.Errorf("rejected patches changed the todo: %s", w.Body.String())
+	}
+	w = s.do(http.MethodPatch, fmt.Sprintf("/todos/%d", todo.ID), `{}`, "Content-Type", "text/plain")
+	if got := w.Header().Get("Accept-Patch"); got != mergePatchType+", "+jsonPatchType {
+		t.Errorf("Accept-Patch = %q", got)
+	}
+}
+
+func TestTodoPatchIfMatch(t *testing.T) {
+	s := newTodoServer(t)
+	todo := s.create(`"title":"buy milk"`)
+	stale := fmt.Sprintf(`"%d-%d"`, todo.ID, todo.Version-1)
+
+	if code, _, _ := s.patch(todo, mergePatchType, `{"title":"x"}`, "If-Match", stale); code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with a stale If-Match = %d, want 412", code)
+	}
+	code, patched, msg := s.patch(todo, mergePatchType, `{"title":"buy oat milk"}`, "If-Match", todoETag(todo))
+	if code != http.StatusOK {
+		t.Fatalf("PATCH with the current If-Match = %d %s", code, msg)
+	}
+	if code, _, _ := s.patch(patched, mergePatchType, `{"title":"buy soy milk"}`, "If-Match", todoETag(todo)); code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with the replaced ETag = %d, want 412", code)
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/quickadd"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
+)
+
+type parseTodoRequest struct {
+	Text     string `json:"text"`
+	Timezone string `json:"timezone"`
+}
+
+// parsedTodo is the todo a quick-add text describes, with the parts of the
+// text that were recognised.
+type parsedTodo struct {
+	Title    string       `json:"title"`
+	ListID   *int64       `json:"listId"`
+	DueAt    *time.Time   `json:"dueAt"`
+	RRule    string       `json:"rrule,omitempty"`
+	Timezone string       `json:"timezone,omitempty"`
+	Priority string       `json:"priority,omitempty"`
+	Tags     []string     `json:"tags"`
+	Matches  []parseMatch `json:"matches"`
+}
+
+type parseMatch struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Parse previews what POST /api/todos?parse=true would make of a text,
+// without creating anything.
+func (h *TodoHandler) Parse(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req parseTodoRequest
+	if !decodeTodoBody(w, r, &req) {
+		return
+	}
+	parsed, err := h.quickAdd(r.Context(), userID, req.Text, req.Timezone)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(parsed)
+}
+
+// quickAdd reads text in timezone, or in the user's timezone when it is
+// empty. A +list may name any list the user belongs to.
+func (h *TodoHandler) quickAdd(ctx context.Context, userID int64, text, timezone string) (*parsedTodo, error) {
+	loc, err := recurrence.LoadLocation(timezone)
+	if timezone == "" {
+		loc, err = h.Users.Location(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lists, err := h.Lists.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(lists))
+	for i, list := range lists {
+		names[i] = list.Name
+	}
+
+	res := quickadd.Parse(text, quickadd.Options{Now: time.Now().In(loc), Lists: names})
+	parsed := &parsedTodo{
+		Title:    res.Title,
+		RRule:    res.RRule,
+		Priority: res.Priority,
+		Tags:     res.Tags,
+		Matches:  make([]parseMatch, len(res.Matches)),
+	}
+	for i, m := range res.Matches {
+		parsed.Matches[i] = parseMatch{Kind: m.Kind, Text: m.Text, Start: m.Start, End: m.End}
+	}
+	if res.List != "" {
+		for _, list := range lists {
+			if list.Name == res.List {
+				parsed.ListID = &list.ID
+				break
+			}
+		}
+	}
+	if res.Due != nil {
+		// The timezone keeps recurrences and all-day dates on the days the
+		// user meant.
+		due := res.Due.UTC()
+		parsed.DueAt = &due
+		parsed.Timezone = loc.String()
+	}
+	return parsed, nil
+}
+
+// parse replaces the title of req with the todo it describes. Fields set in
+// the request win over those found in the title; tags are added up.
+func (req *createTodoRequest) parse(parsed *parsedTodo) {
+	req.Title = parsed.Title
+	if req.ListID == 0 && parsed.ListID != nil {
+		req.ListID = *parsed.ListID
+	}
+	if req.DueAt == nil {
+		req.DueAt = parsed.DueAt
+	}
+	if req.RRule == "" {
+		req.RRule = parsed.RRule
+	}
+	if req.Timezone == "" {
+		req.Timezone = parsed.Timezone
+	}
+	if req.Priority == "" {
+		req.Priority = parsed.Priority
+	}
+	req.Tags = append(req.Tags, parsed.Tags...)
+}
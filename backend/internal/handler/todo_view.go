@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+)
+
+type viewRequest struct {
+	Name  *string `json:"name"`
+	Query *string `json:"query"`
+	Sort  *string `json:"sort"`
+}
+
+func (req *viewRequest) changes() service.ViewChanges {
+	return service.ViewChanges{Name: req.Name, Query: req.Query, Sort: req.Sort}
+}
+
+// Views lists the current user's saved views.
+func (h *TodoHandler) Views(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	views, err := h.Todos.Views(r.Context(), userID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(views)
+}
+
+// CreateView saves a filter query and sort under a name.
+func (h *TodoHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req viewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	view, err := h.Todos.CreateView(r.Context(), userID, req.changes())
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(view)
+}
+
+func (h *TodoHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	viewID, err := parseIDParam(r, "viewId")
+	if err != nil {
+		http.Error(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+	view, err := h.Todos.GetView(r.Context(), userID, viewID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(view)
+}
+
+// UpdateView renames a view or changes its query or sort.
+func (h *TodoHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	viewID, err := parseIDParam(r, "viewId")
+	if err != nil {
+		http.Error(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+	var req viewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	view, err := h.Todos.UpdateView(r.Context(), userID, viewID, req.changes())
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(view)
+}
+
+func (h *TodoHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	viewID, err := parseIDParam(r, "viewId")
+	if err != nil {
+		http.Error(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Todos.DeleteView(r.Context(), userID, viewID); err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ViewTodos pages through the todos matching a view. It takes the
+// parameters of List: filters narrow the view further, and ?sort= replaces
+// the view's sort.
+func (h *TodoHandler) ViewTodos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	viewID, err := parseIDParam(r, "viewId")
+	if err != nil {
+		http.Error(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseTodoFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	todos, next, err := h.Todos.ViewTodos(r.Context(), userID, viewID, filter)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	writeTodoPage(w, r, todos, next, filter.Limit)
+}
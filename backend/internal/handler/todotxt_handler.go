@@ -0,0 +1,535 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/todotxt"
+)
+
+// maxTodoTxtLines bounds the tasks of an uploaded todo.txt file.
+const maxTodoTxtLines = 5000
+
+// todo.txt extensions that map onto todo fields. Other extensions stay in
+// the title.
+const (
+	txtID    = "id"
+	txtDue   = "due"
+	txtRec   = "rec"
+	txtRRule = "rrule"
+	txtPri   = "pri"
+)
+
+// simpleRRule matches the rules a rec: extension can express.
+var simpleRRule = regexp.MustCompile(`^FREQ=(DAILY|WEEKLY|MONTHLY|YEARLY)(;INTERVAL=([1-9][0-9]*))?$`)
+
+var recUnits = map[byte]string{'d': "DAILY", 'w': "WEEKLY", 'm': "MONTHLY", 'y': "YEARLY"}
+
+// TodoTxtHandler serves a user's todos as a todo.txt file that can be edited
+// and uploaded again.
+type TodoTxtHandler struct {
+	Todos *service.TodoService
+	Lists *service.ListService
+}
+
+func NewTodoTxtHandler(todos *service.TodoService, lists *service.ListService) *TodoTxtHandler {
+	return &TodoTxtHandler{Todos: todos, Lists: lists}
+}
+
+// txtLineError is a line of an uploaded file that cannot be applied.
+type txtLineError struct {
+	line int
+	err  error
+}
+
+func (e *txtLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func (e *txtLineError) Unwrap() error {
+	return e.err
+}
+
+// txtTodo is a parsed todo.txt line. Due and rec are kept as written so that
+// a line left alone leaves the todo alone, even when the todo has a due
+// time or a rule the line can only approximate.
+type txtTodo struct {
+	line      int
+	id        int64
+	listID    int64 // zero when the line names no list
+	title     string
+	completed bool
+	priority  string
+	tags      []string
+	due       string
+	rec       string
+}
+
+// Get downloads the user's todos, or those of ?listId=, as todo.txt. Every
+// line carries an id: extension that PUT uses to match it to its todo.
+func (h *TodoTxtHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, ok := parseListIDQuery(w, r)
+	if !ok {
+		return
+	}
+	text, err := h.export(r.Context(), userID, listID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	if notModified(w, r, todoTxtETag(text)) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(text)
+}
+
+// Put replaces the user's todos, or those of ?listId=, with the uploaded
+// todo.txt file. Lines with an id: update that todo when they differ from
+// it, lines without one create a todo, and todos with no line are moved to
+// the trash, all in one transaction. With If-Match the file must have been
+// downloaded from the current state. The response is the resulting file.
+func (h *TodoTxtHandler) Put(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listID, ok := parseListIDQuery(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	lines, err := todotxt.Read(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(lines) > maxTodoTxtLines {
+		http.Error(w, fmt.Sprintf("at most %d todos can be uploaded at once", maxTodoTxtLines), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.export(r.Context(), userID, listID)
+		if err != nil {
+			writeTodoError(w, r, err)
+			return
+		}
+		if preconditionFailed(w, r, todoTxtETag(current)) {
+			return
+		}
+	}
+	lists, err := h.Lists.ListByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	todos, err := allTodos(r.Context(), h.Todos, userID, repository.TodoFilter{ListID: listID, Limit: maxTodoLimit})
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+
+	ops, opLines, err := todoTxtOps(lines, lists, todos, listID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(ops) > 0 {
+		if _, err := h.Todos.Batch(r.Context(), userID, ops, true); err != nil {
+			var batchErr *service.BatchError
+			if !errors.As(err, &batchErr) {
+				writeTodoError(w, r, err)
+				return
+			}
+			status, _ := todoErrorStatus(batchErr.Err)
+			if line := opLines[batchErr.Index]; line > 0 {
+				err = &txtLineError{line: line, err: batchErr.Err}
+			} else {
+				err = fmt.Errorf("removing todo id:%d: %w", ops[batchErr.Index].TodoID, batchErr.Err)
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	text, err := h.export(r.Context(), userID, listID)
+	if err != nil {
+		writeTodoError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", todoTxtETag(text))
+	_, _ = w.Write(text)
+}
+
+// export renders the todos of listID, or all of the user's, as todo.txt.
+func (h *TodoTxtHandler) export(ctx context.Context, userID, listID int64) ([]byte, error) {
+	lists, err := h.Lists.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(lists))
+	for _, list := range lists {
+		names[list.ID] = list.Name
+	}
+	todos, err := allTodos(ctx, h.Todos, userID, repository.TodoFilter{ListID: listID, Limit: maxTodoLimit})
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]todotxt.Task, len(todos))
+	for i := range todos {
+		tasks[i] = txtTask(&todos[i], names[todos[i].ListID])
+	}
+	var b bytes.Buffer
+	if err := todotxt.Write(&b, tasks); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// todoTxtETag is the strong entity tag of an exported file.
+func todoTxtETag(text []byte) string {
+	sum := sha256.Sum256(text)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// txtTask renders todo as a task. Its list becomes a +project; the dates
+// are those of its creation and, for done todos, its completion.
+func txtTask(todo *model.Todo, listName string) todotxt.Task {
+	words := []string{todo.Title}
+	if listName != "" {
+		words = append(words, "+"+projectName(listName))
+	}
+	for _, tag := range todo.Tags {
+		words = append(words, "@"+tag)
+	}
+	if due := txtDueDate(todo); due != "" {
+		words = append(words, txtDue+":"+due)
+	}
+	if rec := txtRecurrenceOf(todo); rec != "" {
+		words = append(words, rec)
+	}
+	words = append(words, txtID+":"+strconv.FormatInt(todo.ID, 10))
+	task := todotxt.Task{
+		Done:        todo.Completed,
+		Priority:    todo.Priority,
+		Created:     dateOf(todo.CreatedAt),
+		Description: strings.Join(words, " "),
+	}
+	if todo.CompletedAt != nil {
+		task.Completed = dateOf(*todo.CompletedAt)
+	}
+	return task
+}
+
+// txtDueDate is the day a todo is due in its timezone, or empty.
+func txtDueDate(todo *model.Todo) string {
+	if todo.DueAt == nil {
+		return ""
+	}
+	loc, err := recurrence.LoadLocation(todo.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return todo.DueAt.In(loc).Format(todotxt.DateLayout)
+}
+
+// txtRecurrence renders a todo's rule as a rec: value such as "2w", with a
+// "+" for fixed schedules, or returns empty when the rule is too complex.
+func txtRecurrence(todo *model.Todo) string {
+	m := simpleRRule.FindStringSubmatch(strings.TrimPrefix(todo.RRule, "RRULE:"))
+	if m == nil {
+		return ""
+	}
+	interval := "1"
+	if m[3] != "" {
+		interval = m[3]
+	}
+	var unit byte
+	for u, freq := range recUnits {
+		if freq == m[1] {
+			unit = u
+		}
+	}
+	rec := interval + string(unit)
+	if todo.RepeatMode != model.RepeatAfterCompletion {
+		rec = "+" + rec
+	}
+	return rec
+}
+
+// txtRecurrenceOf is the rec: or rrule: value a todo is exported with.
+func txtRecurrenceOf(todo *model.Todo) string {
+	if todo.RRule == "" {
+		return ""
+	}
+	if rec := txtRecurrence(todo); rec != "" {
+		return txtRec + ":" + rec
+	}
+	return txtRRule + ":" + strings.TrimPrefix(todo.RRule, "RRULE:")
+}
+
+// dateOf is the UTC day of t.
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// projectName turns a list name into a +project, which cannot hold spaces.
+func projectName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// parseTxtLine maps a task onto todo fields. A +project naming one of lists
+// selects that list; other projects, like unknown extensions, stay in the
+// title. @contexts become tags.
+func parseTxtLine(line todotxt.Line, lists []model.List) (*txtTodo, error) {
+	t := &txtTodo{
+		line:      line.Number,
+		completed: line.Task.Done,
+		priority:  line.Task.Priority,
+		tags:      []string{},
+	}
+	var title []string
+	for _, word := range strings.Fields(line.Task.Description) {
+		if name, ok := todotxt.ParseProject(word); ok && t.listID == 0 {
+			if list := listByProject(lists, name); list != nil {
+				t.listID = list.ID
+				continue
+			}
+		}
+		if name, ok := todotxt.ParseContext(word); ok {
+			t.tags = append(t.tags, strings.ToLower(name))
+			continue
+		}
+		ext, ok := todotxt.ParseExtension(word)
+		if !ok {
+			title = append(title, word)
+			continue
+		}
+		switch ext.Key {
+		case txtID:
+			id, err := strconv.ParseInt(ext.Value, 10, 64)
+			if err != nil || id <= 0 || t.id != 0 {
+				return nil, &txtLineError{line: line.Number, err: fmt.Errorf("invalid %s", word)}
+			}
+			t.id = id
+		case txtDue:
+			if _, err := time.Parse(todotxt.DateLayout, ext.Value); err != nil {
+				return nil, &txtLineError{line: line.Number, err: fmt.Errorf("invalid %s", word)}
+			}
+			t.due = ext.Value
+		case txtRec:
+			if _, _, err := parseRec(ext.Value); err != nil {
+				return nil, &txtLineError{line: line.Number, err: err}
+			}
+			t.rec = word
+		case txtRRule:
+			t.rec = word
+		case txtPri:
+			// Parse has already read the priority of a done task.
+		default:
+			title = append(title, word)
+		}
+	}
+	t.title = strings.Join(title, " ")
+	if t.title == "" {
+		return nil, &txtLineError{line: line.Number, err: errTitleRequired}
+	}
+	return t, nil
+}
+
+func listByProject(lists []model.List, project string) *model.List {
+	for i := range lists {
+		if strings.EqualFold(projectName(lists[i].Name), project) {
+			return &lists[i]
+		}
+	}
+	return nil
+}
+
+// parseRec reads a rec: value such as "1w" or "+3m" into a rule and repeat
+// mode.
+func parseRec(value string) (string, string, error) {
+	mode := model.RepeatAfterCompletion
+	rest := value
+	if strings.HasPrefix(rest, "+") {
+		mode = model.RepeatFixed
+		rest = rest[1:]
+	}
+	if len(rest) < 2 {
+		return "", "", fmt.Errorf("invalid rec:%s", value)
+	}
+	freq, ok := recUnits[rest[len(rest)-1]]
+	n, err := strconv.Atoi(rest[:len(rest)-1])
+	if !ok || err != nil || n < 1 {
+		return "", "", fmt.Errorf("invalid rec:%s", value)
+	}
+	rule := "FREQ=" + freq
+	if n > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(n)
+	}
+	return rule, mode, nil
+}
+
+// apply copies the fields of the line onto todo, leaving the due time and
+// rule alone when the line still shows them as they are. A line naming no
+// list leaves the todo in its list.
+func (t *txtTodo) apply(todo *model.Todo) error {
+	if t.listID != 0 {
+		todo.ListID = t.listID
+	}
+	todo.Title = t.title
+	todo.Completed = t.completed
+	todo.Priority = t.priority
+	todo.Tags = t.tags
+	if t.due != txtDueDate(todo) {
+		if t.due == "" {
+			todo.DueAt = nil
+		} else {
+			loc, err := recurrence.LoadLocation(todo.Timezone)
+			if err != nil {
+				loc = time.UTC
+			}
+			due, err := time.ParseInLocation(todotxt.DateLayout, t.due, loc)
+			if err != nil {
+				return err
+			}
+			due = due.UTC()
+			todo.DueAt = &due
+		}
+	}
+	if t.rec != txtRecurrenceOf(todo) {
+		key, value, _ := strings.Cut(t.rec, ":")
+		switch key {
+		case "":
+			todo.RRule, todo.RepeatMode = "", ""
+		case txtRec:
+			rule, mode, err := parseRec(value)
+			if err != nil {
+				return err
+			}
+			todo.RRule, todo.RepeatMode = rule, mode
+		case txtRRule:
+			todo.RRule, todo.RepeatMode = value, ""
+		}
+	}
+	return nil
+}
+
+// changes reports whether applying the line to todo would change it.
+func (t *txtTodo) changes(todo *model.Todo) (bool, error) {
+	next := *todo
+	if err := t.apply(&next); err != nil {
+		return false, err
+	}
+	return next.ListID != todo.ListID ||
+		next.Title != todo.Title ||
+		next.Completed != todo.Completed ||
+		next.Priority != todo.Priority ||
+		!slices.Equal(next.Tags, todo.Tags) ||
+		!sameTime(next.DueAt, todo.DueAt) ||
+		next.RRule != todo.RRule ||
+		next.RepeatMode != todo.RepeatMode, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// todoTxtOps diffs the uploaded lines against the todos they replace and
+// returns the batch that turns one into the other, along with the line each
+// operation came from (zero for deletions).
+func todoTxtOps(lines []todotxt.Line, lists []model.List, todos []model.Todo, listID int64) ([]service.BatchOp, []int, error) {
+	current := make(map[int64]*model.Todo, len(todos))
+	for i := range todos {
+		current[todos[i].ID] = &todos[i]
+	}
+	var (
+		ops     []service.BatchOp
+		opLines []int
+		seen    = make(map[int64]bool)
+	)
+	for _, line := range lines {
+		t, err := parseTxtLine(line, lists)
+		if err != nil {
+			return nil, nil, err
+		}
+		if t.id == 0 {
+			if t.listID == 0 {
+				t.listID = listID
+			}
+			ops = append(ops, service.BatchOp{Op: service.BatchCreate, Apply: t.apply})
+			opLines = append(opLines, t.line)
+			continue
+		}
+		todo, ok := current[t.id]
+		if !ok {
+			return nil, nil, &txtLineError{line: t.line, err: fmt.Errorf("unknown todo id:%d", t.id)}
+		}
+		if seen[t.id] {
+			return nil, nil, &txtLineError{line: t.line, err: fmt.Errorf("duplicate todo id:%d", t.id)}
+		}
+		seen[t.id] = true
+		changed, err := t.changes(todo)
+		if err != nil {
+			return nil, nil, &txtLineError{line: t.line, err: err}
+		}
+		if changed {
+			ops = append(ops, service.BatchOp{Op: service.BatchUpdate, TodoID: t.id, IfVersion: todo.Version, Apply: t.apply})
+			opLines = append(opLines, t.line)
+		}
+	}
+	for i := range todos {
+		if !seen[todos[i].ID] {
+			ops = append(ops, service.BatchOp{Op: service.BatchDelete, TodoID: todos[i].ID, IfVersion: todos[i].Version})
+			opLines = append(opLines, 0)
+		}
+	}
+	return ops, opLines, nil
+}
+
+// parseListIDQuery reads the optional ?listId=, answering 400 when it is
+// malformed.
+func parseListIDQuery(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	raw := r.URL.Query().Get("listId")
+	if raw == "" {
+		return 0, true
+	}
+	listID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid list id", http.StatusBadRequest)
+		return 0, false
+	}
+	return listID, true
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/todotxt"
+)
+
+var txtLists = []model.List{{ID: 1, Name: "Home"}, {ID: 2, Name: "Work Stuff"}}
+
+// txtTodos covers what a todo.txt line can carry, and what it can only
+// approximate: due times, rules beyond rec: and completion without a date.
+func txtTodos() []model.Todo {
+	at := func(s string) *time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return &t
+	}
+	created := *at("2026-10-01T08:30:00Z")
+	return []model.Todo{
+		{ID: 1, Version: 1, ListID: 1, Title: "buy milk", CreatedAt: created, Tags: []string{}},
+		{ID: 2, Version: 3, ListID: 2, Title: "ship report", Priority: "A", Tags: []string{"office", "q4"}, CreatedAt: created,
+			DueAt: at("2026-11-02T14:00:00Z"), Timezone: "Europe/Berlin"},
+		{ID: 3, Version: 2, ListID: 1, Title: "pay rent", Priority: "B", Completed: true, CompletedAt: at("2026-10-03T23:30:00Z"), CreatedAt: created, Tags: []string{}},
+		{ID: 4, Version: 1, ListID: 1, Title: "water plants", RRule: "FREQ=WEEKLY;INTERVAL=2", RepeatMode: model.RepeatFixed, CreatedAt: created, Tags: []string{}},
+		{ID: 5, Version: 1, ListID: 2, Title: "standup", RRule: "FREQ=WEEKLY;BYDAY=MO,WE", CreatedAt: created, Tags: []string{},
+			DueAt: at("2026-10-19T07:15:00Z"), Timezone: "Europe/Berlin"},
+		{ID: 6, Version: 1, ListID: 2, Title: "gym", RRule: "FREQ=DAILY", RepeatMode: model.RepeatAfterCompletion, CreatedAt: created, Tags: []string{}},
+		{ID: 7, Version: 1, ListID: 1, Title: "read +unknown foo:bar https://example.com/x", CreatedAt: created, Tags: []string{}},
+		{ID: 8, Version: 1, ListID: 1, Title: "done without date", Completed: true, CreatedAt: created, Tags: []string{}},
+	}
+}
+
+// exportTxt renders todos the way the export endpoint does.
+func exportTxt(t *testing.T, todos []model.Todo) string {
+	t.Helper()
+	tasks := make([]todotxt.Task, len(todos))
+	for i := range todos {
+		var name string
+		for _, list := range txtLists {
+			if list.ID == todos[i].ListID {
+				name = list.Name
+			}
+		}
+		tasks[i] = txtTask(&todos[i], name)
+	}
+	var b bytes.Buffer
+	if err := todotxt.Write(&b, tasks); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func txtOps(t *testing.T, text string, todos []model.Todo) []service.BatchOp {
+	t.Helper()
+	lines, err := todotxt.Read(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, opLines, err := todoTxtOps(lines, txtLists, todos, 0)
+	if err != nil {
+		t.Fatalf("todoTxtOps: %v", err)
+	}
+	if len(opLines) != len(ops) {
+		t.Fatalf("%d operations but %d line numbers", len(ops), len(opLines))
+	}
+	return ops
+}
+
+func TestTodoTxtExport(t *testing.T) {
+	want := strings.Join([]string{
+		"2026-10-01 buy milk +Home id:1",
+		"(A) 2026-10-01 ship report +Work_Stuff @office @q4 due:2026-11-02 id:2",
+		"x 2026-10-03 2026-10-01 pay rent +Home id:3 pri:B",
+		"2026-10-01 water plants +Home rec:+2w id:4",
+		"2026-10-01 standup +Work_Stuff due:2026-10-19 rrule:FREQ=WEEKLY;BYDAY=MO,WE id:5",
+		"2026-10-01 gym +Work_Stuff rec:1d id:6",
+		"2026-10-01 read +unknown foo:bar https://example.com/x +Home id:7",
+		"x 2026-10-01 done without date +Home id:8",
+		"",
+	}, "\n")
+	if got := exportTxt(t, txtTodos()); got != want {
+		t.Errorf("export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestTodoTxtUnchangedUpload uploads a downloaded file as it is, which must
+// change nothing, even for the todos the file only approximates.
+func TestTodoTxtUnchangedUpload(t *testing.T) {
+	todos := txtTodos()
+	if ops := txtOps(t, exportTxt(t, todos), todos); len(ops) != 0 {
+		t.Errorf("unchanged upload gave %d operations, want none: %+v", len(ops), ops)
+	}
+	// Nor does reformatting that todo.txt treats as the same.
+	text := strings.ReplaceAll(exportTxt(t, todos), " id:", "  id:") + "\n\n"
+	if ops := txtOps(t, text, todos); len(ops) != 0 {
+		t.Errorf("reformatted upload gave %d operations, want none", len(ops))
+	}
+}
+
+func TestTodoTxtEdits(t *testing.T) {
+	todos := txtTodos()
+	lines := strings.Split(strings.TrimSuffix(exportTxt(t, todos), "\n"), "\n")
+	lines[0] = "(C) 2026-10-01 buy oat milk +Work_Stuff @shop due:2026-10-21 id:1"
+	lines[1] = "x " + strings.TrimPrefix(lines[1], "(A) ")
+	lines = slices.Delete(lines, 2, 3) // pay rent
+	lines = append(lines, "call bob +Home rec:+1m foo:bar")
+	ops := txtOps(t, strings.Join(lines, "\n"), todos)
+
+	if len(ops) != 4 {
+		t.Fatalf("got %d operations, want 4: %+v", len(ops), ops)
+	}
+	byOp := map[string][]service.BatchOp{}
+	for _, op := range ops {
+		byOp[op.Op] = append(byOp[op.Op], op)
+	}
+	if d := byOp[service.BatchDelete]; len(d) != 1 || d[0].TodoID != 3 || d[0].IfVersion != 2 {
+		t.Errorf("deletes = %+v, want todo 3 at version 2", d)
+	}
+
+	updates := byOp[service.BatchUpdate]
+	if len(updates) != 2 || updates[0].TodoID != 1 || updates[1].TodoID != 2 {
+		t.Fatalf("updates = %+v, want todos 1 and 2", updates)
+	}
+	milk := todos[0]
+	if err := updates[0].Apply(&milk); err != nil {
+		t.Fatal(err)
+	}
+	if milk.Title != "buy oat milk" || milk.ListID != 2 || milk.Priority != "C" || !slices.Equal(milk.Tags, []string{"shop"}) ||
+		milk.DueAt == nil || milk.DueAt.Format(time.DateOnly) != "2026-10-21" {
+		t.Errorf("edited todo = %+v", milk)
+	}
+	report := todos[1]
+	if err := updates[1].Apply(&report); err != nil {
+		t.Fatal(err)
+	}
+	// Completing keeps the due time the line could not show.
+	if !report.Completed || !report.DueAt.Equal(*todos[1].DueAt) {
+		t.Errorf("completed todo = %+v", report)
+	}
+
+	creates := byOp[service.BatchCreate]
+	if len(creates) != 1 {
+		t.Fatalf("creates = %+v, want one", creates)
+	}
+	var created model.Todo
+	if err := creates[0].Apply(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Title != "call bob foo:bar" || created.ListID != 1 || created.RRule != "FREQ=MONTHLY" || created.RepeatMode != model.RepeatFixed {
+		t.Errorf("created todo = %+v", created)
+	}
+}
+
+func TestTodoTxtInvalidLines(t *testing.T) {
+	todos := txtTodos()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"buy milk id:99", "line 1: unknown todo id:99"},
+		{"buy milk id:1\nagain id:1", "line 2: duplicate todo id:1"},
+		{"buy milk id:x", "line 1: invalid id:x"},
+		{"buy milk due:tomorrow", "line 1: invalid due:tomorrow"},
+		{"buy milk rec:2x", "line 1: invalid rec:2x"},
+		{"\n+Home @tag id:1", "line 2: "},
+	}
+	for _, tt := range tests {
+		lines, err := todotxt.Read(strings.NewReader(tt.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = todoTxtOps(lines, txtLists, todos, 0)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("todoTxtOps(%q) = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
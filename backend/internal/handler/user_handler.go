@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/service"
 )
 
@@ -75,3 +79,112 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(user)
 }
+
+type updateUserRequest struct {
+	Timezone *string `json:"timezone"`
+}
+
+// UpdateCurrentUser changes the current user's settings; only the timezone
+// can be changed so far.
+func (h *UserHandler) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req updateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	var (
+		user *model.User
+		err  error
+	)
+	if req.Timezone != nil {
+		user, err = h.Users.SetTimezone(r.Context(), userID, *req.Timezone)
+	} else {
+		user, err = h.Users.GetByID(r.Context(), userID)
+	}
+	if err != nil {
+		if errors.Is(err, recurrence.ErrInvalidTimezone) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(user)
+}
+
+type createAppPasswordRequest struct {
+	Name string `json:"name"`
+}
+
+// AppPasswords lists the current user's app passwords, without the
+// passwords themselves.
+func (h *UserHandler) AppPasswords(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	passwords, err := h.Users.AppPasswords(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(passwords)
+}
+
+// CreateAppPassword generates an app password; the response is the only
+// place the password is shown.
+func (h *UserHandler) CreateAppPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req createAppPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	p, err := h.Users.CreateAppPassword(r.Context(), userID, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrAppPasswordName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// DeleteAppPassword revokes an app password.
+func (h *UserHandler) DeleteAppPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := parseIDParam(r, "appPasswordId")
+	if err != nil {
+		http.Error(w, "invalid app password id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Users.DeleteAppPassword(r.Context(), userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
 )
 
 var upgrader = websocket.Upgrader{
@@ -21,19 +24,31 @@ var upgrader = websocket.Upgrader{
 // EventHub manages WebSocket connections for a specific event type
 type EventHub struct {
 	name       string
-	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
-	register   chan *websocket.Conn
+	clients    map[*websocket.Conn]int64 // connection -> authenticated user ID
+	broadcast  chan event
+	register   chan subscription
 	unregister chan *websocket.Conn
 	mu         sync.RWMutex
 }
 
+type subscription struct {
+	conn   *websocket.Conn
+	userID int64
+}
+
+// event is a message queued for delivery. A nil recipients set means every
+// connected client receives it.
+type event struct {
+	message    []byte
+	recipients map[int64]bool
+}
+
 func NewEventHub(name string) *EventHub {
 	return &EventHub{
 		name:       name,
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *websocket.Conn),
+		clients:    make(map[*websocket.Conn]int64),
+		broadcast:  make(chan event, 256),
+		register:   make(chan subscription),
 		unregister: make(chan *websocket.Conn),
 	}
 }
@@ -41,9 +56,9 @@ func NewEventHub(name string) *EventHub {
 func (h *EventHub) Run() {
 	for {
 		select {
-		case client := <-h.register:
+		case sub := <-h.register:
 			h.mu.Lock()
-			h.clients[client] = true
+			h.clients[sub.conn] = sub.userID
 			h.mu.Unlock()
 			log.Printf("[%s] WebSocket client connected, total: %d", h.name, len(h.clients))
 
@@ -56,10 +71,13 @@ func (h *EventHub) Run() {
 			h.mu.Unlock()
 			log.Printf("[%s] WebSocket client disconnected, total: %d", h.name, len(h.clients))
 
-		case message := <-h.broadcast:
+		case ev := <-h.broadcast:
 			h.mu.RLock()
-			for client := range h.clients {
-				err := client.WriteMessage(websocket.TextMessage, message)
+			for client, userID := range h.clients {
+				if ev.recipients != nil && !ev.recipients[userID] {
+					continue
+				}
+				err := client.WriteMessage(websocket.TextMessage, ev.message)
 				if err != nil {
 					log.Printf("[%s] Error broadcasting to client: %v", h.name, err)
 					client.Close()
@@ -77,18 +95,58 @@ func (h *EventHub) Run() {
 
 // Broadcast sends a message to all connected clients for this event
 func (h *EventHub) Broadcast(message []byte) {
-	h.broadcast <- message
+	h.broadcast <- event{message: message}
+}
+
+// BroadcastTo sends a message only to the connections of the given users
+func (h *EventHub) BroadcastTo(message []byte, userIDs ...int64) {
+	recipients := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		recipients[id] = true
+	}
+	h.broadcast <- event{message: message, recipients: recipients}
+}
+
+// Hubs routes events published by the services to the EventHub registered
+// under each event name.
+type Hubs map[string]*EventHub
+
+// Publish implements service.Publisher.
+func (hs Hubs) Publish(name string, payload any, userIDs []int64) {
+	hub, ok := hs[name]
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[%s] Error encoding event: %v", name, err)
+		return
+	}
+	hub.BroadcastTo(data, userIDs...)
 }
 
-// HandleWebSocket upgrades the HTTP connection and manages the WebSocket for this specific event
+// Run starts every hub.
+func (hs Hubs) Run() {
+	for _, hub := range hs {
+		go hub.Run()
+	}
+}
+
+// HandleWebSocket upgrades the HTTP connection and manages the WebSocket for this specific event.
+// It must run behind middleware.OAuth2Guard; browsers pass the token as ?access_token=.
 func (h *EventHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[%s] WebSocket upgrade error: %v", h.name, err)
 		return
 	}
 
-	h.register <- conn
+	h.register <- subscription{conn: conn, userID: userID}
 
 	// Keep connection alive and handle incoming messages
 	go func() {
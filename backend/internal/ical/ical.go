@@ -0,0 +1,353 @@
+// Package ical reads and writes the VTODO components of iCalendar (RFC 5545)
+// files.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// VTODO status values.
+const (
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusCompleted   = "COMPLETED"
+	StatusInProcess   = "IN-PROCESS"
+	StatusCancelled   = "CANCELLED"
+)
+
+// ProdID identifies this application as the producer of exported calendars.
+const ProdID = "-//hello-world-ripple-app//todos//EN"
+
+var ErrInvalidCalendar = errors.New("invalid iCalendar data")
+
+const (
+	dateTimeLayout    = "20060102T150405"
+	utcDateTimeLayout = "20060102T150405Z"
+	dateLayout        = "20060102"
+	// maxLineOctets is the longest content line RFC 5545 allows before it
+	// must be folded, excluding the line break.
+	maxLineOctets = 75
+)
+
+// Todo is a VTODO component. Times are in UTC; DueTZID keeps the TZID the
+// due time was given in, if any.
+type Todo struct {
+	UID          string
+	Summary      string
+	Description  string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+	Due          *time.Time
+	DueTZID      string
+	Completed    *time.Time
+	Sequence     int
+	RRule        string
+}
+
+// Item is one VTODO read by Decode. Err is set when the component could not
+// be read, in which case Todo holds whatever was read before the error.
+type Item struct {
+	Todo Todo
+	Err  error
+}
+
+// Encode writes todos as a VCALENDAR with one VTODO each, stamped with now.
+func Encode(w io.Writer, todos []Todo, now time.Time) error {
+	enc := &encoder{w: bufio.NewWriter(w)}
+	enc.line("BEGIN", "VCALENDAR")
+	enc.line("VERSION", "2.0")
+	enc.line("PRODID", ProdID)
+	stamp := now.UTC().Format(utcDateTimeLayout)
+	for _, t := range todos {
+		enc.line("BEGIN", "VTODO")
+		enc.line("UID", escapeText(t.UID))
+		enc.line("DTSTAMP", stamp)
+		if !t.Created.IsZero() {
+			enc.line("CREATED", t.Created.UTC().Format(utcDateTimeLayout))
+		}
+		if !t.LastModified.IsZero() {
+			enc.line("LAST-MODIFIED", t.LastModified.UTC().Format(utcDateTimeLayout))
+		}
+		enc.line("SEQUENCE", strconv.Itoa(t.Sequence))
+		enc.line("SUMMARY", escapeText(t.Summary))
+		if t.Description != "" {
+			enc.line("DESCRIPTION", escapeText(t.Description))
+		}
+		if t.Status != "" {
+			enc.line("STATUS", t.Status)
+		}
+		if t.Due != nil {
+			enc.line("DUE", t.Due.UTC().Format(utcDateTimeLayout))
+		}
+		if t.Completed != nil {
+			enc.line("COMPLETED", t.Completed.UTC().Format(utcDateTimeLayout))
+		}
+		if t.RRule != "" {
+			enc.line("RRULE", strings.TrimPrefix(t.RRule, "RRULE:"))
+		}
+		enc.line("END", "VTODO")
+	}
+	enc.line("END", "VCALENDAR")
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it so that no physical line exceeds
+// maxLineOctets and no UTF-8 sequence is split.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, e.err = e.w.WriteString(s[:cut] + "\r\n "); e.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = maxLineOctets - 1
+	}
+	_, e.err = e.w.WriteString(s + "\r\n")
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// property is a parsed content line.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads every VTODO of the VCALENDAR in r. Components other than
+// VTODO, and components nested inside a VTODO such as VALARM, are skipped.
+// A malformed property fails only the VTODO it belongs to; a malformed
+// calendar returns ErrInvalidCalendar.
+func Decode(r io.Reader) ([]Item, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		items    []Item
+		stack    []string
+		current  *Item
+		calendar bool
+	)
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			if current != nil {
+				if current.Err == nil {
+					current.Err = fmt.Errorf("line %d: %w", n+1, err)
+				}
+				continue
+			}
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCalendar, n+1, err)
+		}
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, fmt.Errorf("%w: expected BEGIN:VCALENDAR", ErrInvalidCalendar)
+			}
+			calendar = true
+			stack = append(stack, component)
+			if len(stack) == 2 && component == "VTODO" {
+				items = append(items, Item{})
+				current = &items[len(items)-1]
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrInvalidCalendar, n+1, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 1 && component == "VTODO" {
+				if current.Err == nil && current.Todo.UID == "" {
+					current.Err = errors.New("missing UID")
+				}
+				current = nil
+			}
+			continue
+		}
+		if len(stack) != 2 || current == nil || current.Err != nil {
+			continue
+		}
+		if err := current.Todo.set(prop); err != nil {
+			current.Err = fmt.Errorf("line %d: %s: %w", n+1, prop.name, err)
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: missing END:%s", ErrInvalidCalendar, stack[len(stack)-1])
+	}
+	if !calendar {
+		return nil, fmt.Errorf("%w: missing VCALENDAR", ErrInvalidCalendar)
+	}
+	return items, nil
+}
+
+func (t *Todo) set(p property) error {
+	var err error
+	switch p.name {
+	case "UID":
+		t.UID = unescapeText(p.value)
+	case "SUMMARY":
+		t.Summary = unescapeText(p.value)
+	case "DESCRIPTION":
+		t.Description = unescapeText(p.value)
+	case "STATUS":
+		t.Status = strings.ToUpper(p.value)
+	case "CREATED":
+		t.Created, err = parseTime(p)
+	case "LAST-MODIFIED":
+		t.LastModified, err = parseTime(p)
+	case "DUE":
+		var due time.Time
+		if due, err = parseTime(p); err == nil {
+			t.Due = &due
+			t.DueTZID = p.params["TZID"]
+		}
+	case "COMPLETED":
+		var completed time.Time
+		if completed, err = parseTime(p); err == nil {
+			t.Completed = &completed
+		}
+	case "SEQUENCE":
+		t.Sequence, err = strconv.Atoi(p.value)
+	case "RRULE":
+		t.RRule = p.value
+	}
+	return err
+}
+
+// parseTime reads a DATE or DATE-TIME value. Local times are resolved in
+// their TZID, which must be an IANA name; floating times are taken as UTC.
+func parseTime(p property) (time.Time, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(dateLayout) {
+		return time.Parse(dateLayout, p.value)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(utcDateTimeLayout, p.value)
+	}
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, p.value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// unfold splits r into logical content lines, joining folded ones.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	return lines, nil
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted to contain ';', ':' or ','.
+func parseLine(line string) (property, error) {
+	p := property{params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, errors.New("malformed content line")
+	}
+	p.name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return p, errors.New("malformed parameter")
+		}
+		name := strings.ToUpper(rest[:eq])
+		j := eq + 1
+		var value string
+		if j < len(rest) && rest[j] == '"' {
+			end := strings.IndexByte(rest[j+1:], '"')
+			if end < 0 {
+				return p, errors.New("unterminated quoted parameter")
+			}
+			value = rest[j+1 : j+1+end]
+			j += end + 2
+		} else {
+			end := strings.IndexAny(rest[j:], ";:")
+			if end < 0 {
+				return p, errors.New("malformed parameter")
+			}
+			value = rest[j : j+end]
+			j += end
+		}
+		p.params[name] = value
+		i += 1 + j
+		if i >= len(line) {
+			return p, errors.New("missing value")
+		}
+	}
+	if line[i] != ':' {
+		return p, errors.New("malformed content line")
+	}
+	p.value = line[i+1:]
+	return p, nil
+}
@@ -0,0 +1,293 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func at(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+// calendar joins lines into a VCALENDAR with CRLF line breaks.
+func calendar(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//test//EN"}, lines...)
+	all = append(all, "END:VCALENDAR", "")
+	return strings.Join(all, "\r\n")
+}
+
+func TestEncode(t *testing.T) {
+	todos := []Todo{{
+		UID:          "42@example.com",
+		Summary:      "buy milk, eggs; bread",
+		Description:  "line one\nline two \\ end",
+		Status:       StatusCompleted,
+		Created:      *at("2026-10-01T08:30:00Z"),
+		LastModified: *at("2026-10-02T10:00:00+02:00"),
+		Due:          at("2026-10-03T12:00:00Z"),
+		Completed:    at("2026-10-02T08:00:00Z"),
+		Sequence:     3,
+		RRule:        "RRULE:FREQ=WEEKLY;BYDAY=MO",
+	}, {
+		UID:     "43@example.com",
+		Summary: "no extras",
+	}}
+	var b bytes.Buffer
+	if err := Encode(&b, todos, *at("2026-10-19T09:00:00Z")); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + ProdID,
+		"BEGIN:VTODO",
+		"UID:42@example.com",
+		"DTSTAMP:20261019T090000Z",
+		"CREATED:20261001T083000Z",
+		"LAST-MODIFIED:20261002T080000Z",
+		"SEQUENCE:3",
+		`SUMMARY:buy milk\, eggs\; bread`,
+		`DESCRIPTION:line one\nline two \\ end`,
+		"STATUS:COMPLETED",
+		"DUE:20261003T120000Z",
+		"COMPLETED:20261002T080000Z",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:43@example.com",
+		"DTSTAMP:20261019T090000Z",
+		"SEQUENCE:0",
+		"SUMMARY:no extras",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if b.String() != want {
+		t.Errorf("Encode =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+// TestEncodeFolds checks that long lines are folded within 75 octets without
+// splitting a UTF-8 sequence, and that unfolding restores them.
+func TestEncodeFolds(t *testing.T) {
+	summaries := []string{
+		strings.Repeat("a", 200),
+		strings.Repeat("ä", 100),
+		strings.Repeat("a", 66) + "€€€€€€€€€€",
+		strings.Repeat("🙂", 40),
+	}
+	for _, summary := range summaries {
+		var b bytes.Buffer
+		if err := Encode(&b, []Todo{{UID: "1", Summary: summary}}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+			if len(line) > maxLineOctets {
+				t.Errorf("line of %d octets: %q", len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("line splits a UTF-8 sequence: %q", line)
+			}
+		}
+		items, err := Decode(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].Todo.Summary != summary {
+			t.Errorf("unfolded summary = %+v, want %q", items, summary)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	todos := []Todo{{
+		UID:          "a,b;c\\d",
+		Summary:      "multi\nline, with; specials\\",
+		Description:  "notes",
+		Status:       StatusInProcess,
+		Created:      *at("2026-10-01T08:30:00Z"),
+		LastModified: *at("2026-10-02T08:00:00Z"),
+		Due:          at("2026-10-03T12:00:00Z"),
+		Sequence:     7,
+		RRule:        "FREQ=DAILY;COUNT=3",
+	}, {
+		UID:       "2",
+		Summary:   "done",
+		Status:    StatusCompleted,
+		Completed: at("2026-10-04T00:00:00Z"),
+	}}
+	var b bytes.Buffer
+	if err := Encode(&b, todos, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	items, err := Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(todos) {
+		t.Fatalf("Decode = %d items, want %d", len(items), len(todos))
+	}
+	for i, item := range items {
+		if item.Err != nil {
+			t.Errorf("item %d: %v", i, item.Err)
+		}
+		if !reflect.DeepEqual(item.Todo, todos[i]) {
+			t.Errorf("item %d = %+v, want %+v", i, item.Todo, todos[i])
+		}
+	}
+}
+
+func TestDecodeTimes(t *testing.T) {
+	tests := []struct {
+		line  string
+		due   string
+		tzid  string
+		isErr bool
+	}{
+		{line: "DUE:20261003T120000Z", due: "2026-10-03T12:00:00Z"},
+		{line: "DUE;VALUE=DATE:20261003", due: "2026-10-03T00:00:00Z"},
+		{line: "DUE:20261003", due: "2026-10-03T00:00:00Z"},
+		// Floating times are taken as UTC.
+		{line: "DUE:20261003T120000", due: "2026-10-03T12:00:00Z"},
+		{line: "DUE;TZID=Europe/Berlin:20261003T120000", due: "2026-10-03T10:00:00Z", tzid: "Europe/Berlin"},
+		{line: "DUE;TZID=Europe/Berlin:20261203T120000", due: "2026-12-03T11:00:00Z", tzid: "Europe/Berlin"},
+		{line: `DUE;TZID="America/New_York":20261003T120000`, due: "2026-10-03T16:00:00Z", tzid: "America/New_York"},
+		{line: "DUE;TZID=/Europe/Berlin:20261003T120000", due: "2026-10-03T10:00:00Z", tzid: "/Europe/Berlin"},
+		{line: "DUE;TZID=Central European Standard Time:20261003T120000", isErr: true},
+		{line: "DUE:2026-10-03", isErr: true},
+		{line: "DUE:20261003T1200", isErr: true},
+	}
+	for _, tt := range tests {
+		items, err := Decode(strings.NewReader(calendar("BEGIN:VTODO", "UID:1", tt.line, "END:VTODO")))
+		if err != nil {
+			t.Fatalf("Decode(%q): %v", tt.line, err)
+		}
+		item := items[0]
+		if tt.isErr {
+			if item.Err == nil {
+				t.Errorf("Decode(%q) = %v, want an error", tt.line, item.Todo.Due)
+			}
+			continue
+		}
+		if item.Err != nil {
+			t.Errorf("Decode(%q): %v", tt.line, item.Err)
+			continue
+		}
+		if got := item.Todo.Due.Format(time.RFC3339); got != tt.due || item.Todo.DueTZID != tt.tzid {
+			t.Errorf("Decode(%q) due = %s in %q, want %s in %q", tt.line, got, item.Todo.DueTZID, tt.due, tt.tzid)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	text := calendar(
+		"BEGIN:VTIMEZONE", "TZID:Europe/Berlin", "END:VTIMEZONE",
+		"BEGIN:VEVENT", "UID:event", "SUMMARY:not a todo", "END:VEVENT",
+		"BEGIN:VTODO",
+		"uid:1",
+		"Summary:lower-case names",
+		"status:needs-action",
+		"X-CUSTOM;X-PARAM=\"a;b:c\":ignored",
+		"BEGIN:VALARM", "ACTION:DISPLAY", "SUMMARY:alarm summary", "END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO", "SUMMARY:no uid", "END:VTODO",
+		"BEGIN:VTODO", "UID:3", "SEQUENCE:two", "SUMMARY:after the error", "END:VTODO",
+		"BEGIN:VTODO", "UID:4", "malformed", "END:VTODO",
+		"BEGIN:VTODO", "UID:5", "SUMMARY:fine", "END:VTODO",
+	)
+	items, err := Decode(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("Decode = %+v, want 5 items", items)
+	}
+	if items[0].Err != nil || items[0].Todo.UID != "1" || items[0].Todo.Summary != "lower-case names" || items[0].Todo.Status != StatusNeedsAction {
+		t.Errorf("item 0 = %+v", items[0])
+	}
+	if items[1].Err == nil || items[1].Err.Error() != "missing UID" {
+		t.Errorf("item 1 error = %v, want missing UID", items[1].Err)
+	}
+	// A bad property fails its VTODO and stops it being read further.
+	if items[2].Err == nil || !strings.HasPrefix(items[2].Err.Error(), "line 26: SEQUENCE:") || items[2].Todo.Summary != "" {
+		t.Errorf("item 2 = %+v, want a SEQUENCE error on line 26", items[2])
+	}
+	if items[3].Err == nil || items[3].Err.Error() != "line 31: malformed content line" {
+		t.Errorf("item 3 error = %v, want a malformed line", items[3].Err)
+	}
+	if items[4].Err != nil || items[4].Todo.Summary != "fine" {
+		t.Errorf("item 4 = %+v", items[4])
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"\r\n\r\n",
+		"BEGIN:VTODO\r\nUID:1\r\nEND:VTODO\r\n",
+		"not a calendar",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:1\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nX;=1:y\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nX;A=\"unterminated:y\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nX" + strings.Repeat("x", 2<<20) + "\r\nEND:VCALENDAR\r\n",
+	}
+	for _, text := range tests {
+		if _, err := Decode(strings.NewReader(text)); !errors.Is(err, ErrInvalidCalendar) {
+			t.Errorf("Decode(%.40q) = %v, want ErrInvalidCalendar", text, err)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		name   string
+		params map[string]string
+		value  string
+	}{
+		{"SUMMARY:a:b;c", "SUMMARY", map[string]string{}, "a:b;c"},
+		{"summary:", "SUMMARY", map[string]string{}, ""},
+		{"DUE;VALUE=DATE:20261003", "DUE", map[string]string{"VALUE": "DATE"}, "20261003"},
+		{`X;a="q;u:o,te";B=2:v`, "X", map[string]string{"A": "q;u:o,te", "B": "2"}, "v"},
+	}
+	for _, tt := range tests {
+		p, err := parseLine(tt.line)
+		if err != nil {
+			t.Errorf("parseLine(%q): %v", tt.line, err)
+			continue
+		}
+		if p.name != tt.name || p.value != tt.value || !reflect.DeepEqual(p.params, tt.params) {
+			t.Errorf("parseLine(%q) = %+v", tt.line, p)
+		}
+	}
+	for _, line := range []string{":value", "NOVALUE", "X;A:v", `X;A="open:v`, "X;A=1", `X;A="q"`} {
+		if _, err := parseLine(line); err == nil {
+			t.Errorf("parseLine(%q) succeeded", line)
+		}
+	}
+}
+
+func TestUnescapeText(t *testing.T) {
+	tests := map[string]string{
+		`a\,b\;c\\d`:      `a,b;c\d`,
+		`one\ntwo\Nthree`: "one\ntwo\nthree",
+		`trailing\`:       `trailing\`,
+		`\x`:              "x",
+	}
+	for in, want := range tests {
+		if got := unescapeText(in); got != want {
+			t.Errorf("unescapeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
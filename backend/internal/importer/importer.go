@@ -0,0 +1,94 @@
+// Package importer reads the export files of other task managers into tasks
+// that can be created as todos.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Supported formats.
+const (
+	FormatTodoist = "todoist"
+	FormatTrello  = "trello"
+	FormatMSTodo  = "mstodo"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrInvalidExport = errors.New("invalid export file")
+)
+
+// maxTagLength matches the longest tag a todo may carry.
+const maxTagLength = 50
+
+// Task is a task read from an export file.
+type Task struct {
+	// ExternalID identifies the task in the source tool, prefixed with the
+	// format, or is empty when the export has no IDs. Importing the same
+	// task again updates it instead of creating a copy.
+	ExternalID string
+	// Project is the name of the project, board or list the task was in,
+	// which decides the list it is imported into.
+	Project   string
+	Title     string
+	Notes     string
+	Completed bool
+	Due       *time.Time
+	Priority  string
+	Tags      []string
+	// Warnings describe what could not be carried over, such as a
+	// recurrence or a due date in a form that is not understood.
+	Warnings []string
+}
+
+// Adapter reads the export files of one tool.
+type Adapter interface {
+	// Parse reads an export file. name is the file's name, which some
+	// formats take the project name from.
+	Parse(r io.Reader, name string) ([]Task, error)
+}
+
+// New returns the adapter for format.
+func New(format string) (Adapter, error) {
+	switch format {
+	case FormatTodoist:
+		return todoist{}, nil
+	case FormatTrello:
+		return trello{}, nil
+	case FormatMSTodo:
+		return msTodo{}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// addTag appends label to tags as a todo tag: lowercase, with runs of spaces
+// and commas replaced by a dash. Labels with nothing else are skipped.
+func addTag(tags []string, label string) []string {
+	t := strings.ToLower(strings.Join(strings.FieldsFunc(label, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}), "-"))
+	for len(t) > maxTagLength {
+		_, size := utf8.DecodeLastRuneInString(t)
+		t = t[:len(t)-size]
+	}
+	if t == "" {
+		return tags
+	}
+	return append(tags, t)
+}
+
+// projectFromFilename names a project after an export file, without its
+// directory and extension.
+func projectFromFilename(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// msTodoTask is a todoTask of the Microsoft Graph API.
+type msTodoTask struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	Importance string   `json:"importance"`
+	Categories []string `json:"categories"`
+	Body       *struct {
+		Content     string `json:"content"`
+		ContentType string `json:"contentType"`
+	} `json:"body"`
+	DueDateTime *struct {
+		DateTime string `json:"dateTime"`
+		TimeZone string `json:"timeZone"`
+	} `json:"dueDateTime"`
+	Recurrence     json.RawMessage `json:"recurrence"`
+	ChecklistItems []struct {
+		DisplayName string `json:"displayName"`
+		IsChecked   bool   `json:"isChecked"`
+	} `json:"checklistItems"`
+}
+
+type msTodoList struct {
+	DisplayName string       `json:"displayName"`
+	Tasks       []msTodoTask `json:"tasks"`
+}
+
+// msTodoExport is either a page of tasks as the Graph API returns them, or
+// lists with their tasks.
+type msTodoExport struct {
+	Value []msTodoTask `json:"value"`
+	Lists []msTodoList `json:"lists"`
+}
+
+// msTodo reads Microsoft To Do tasks in the JSON of the Microsoft Graph API:
+// either {"lists": [{"displayName", "tasks": [...]}]}, or a single list's
+// {"value": [...]}, which is named after the file. Importance high and low
+// become priorities A and C, categories become tags and checklist items are
+// added to the notes.
+type msTodo struct{}
+
+func (msTodo) Parse(r io.Reader, name string) ([]Task, error) {
+	var export msTodoExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if export.Value == nil && export.Lists == nil {
+		return nil, fmt.Errorf("%w: expected \"lists\" or \"value\"", ErrInvalidExport)
+	}
+	lists := export.Lists
+	if export.Value != nil {
+		lists = append(lists, msTodoList{DisplayName: projectFromFilename(name), Tasks: export.Value})
+	}
+	var tasks []Task
+	for _, list := range lists {
+		for _, mt := range list.Tasks {
+			tasks = append(tasks, msTodoToTask(mt, list.DisplayName))
+		}
+	}
+	return tasks, nil
+}
+
+func msTodoToTask(mt msTodoTask, project string) Task {
+	t := Task{
+		Project:   project,
+		Title:     strings.TrimSpace(mt.Title),
+		Completed: mt.Status == "completed",
+	}
+	if mt.ID != "" {
+		t.ExternalID = "mstodo-" + mt.ID
+	}
+	switch mt.Importance {
+	case "high":
+		t.Priority = "A"
+	case "low":
+		t.Priority = "C"
+	}
+	for _, category := range mt.Categories {
+		t.Tags = addTag(t.Tags, category)
+	}
+	var notes []string
+	if mt.Body != nil && strings.TrimSpace(mt.Body.Content) != "" {
+		notes = append(notes, strings.TrimSpace(mt.Body.Content))
+		if strings.EqualFold(mt.Body.ContentType, "html") {
+			t.Warnings = append(t.Warnings, "notes are HTML and were imported as is")
+		}
+	}
+	for _, item := range mt.ChecklistItems {
+		mark := " "
+		if item.IsChecked {
+			mark = "x"
+		}
+		notes = append(notes, fmt.Sprintf("- [%s] %s", mark, item.DisplayName))
+	}
+	t.Notes = strings.Join(notes, "\n")
+	if mt.DueDateTime != nil && mt.DueDateTime.DateTime != "" {
+		loc, err := time.LoadLocation(mt.DueDateTime.TimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		// Graph writes seven fractional digits and no offset.
+		due, err := time.ParseInLocation("2006-01-02T15:04:05.9999999", mt.DueDateTime.DateTime, loc)
+		if err != nil {
+			t.Warnings = append(t.Warnings, fmt.Sprintf("due date %q not understood", mt.DueDateTime.DateTime))
+		} else {
+			due = due.UTC()
+			t.Due = &due
+		}
+	}
+	if len(mt.Recurrence) > 0 && string(mt.Recurrence) != "null" {
+		t.Warnings = append(t.Warnings, "recurrence was not imported")
+	}
+	return t
+}
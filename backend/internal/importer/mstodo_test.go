@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMSTodo(t *testing.T) {
+	export := `{"lists": [{"displayName": "Groceries", "tasks": [
+		{"id": "t1", "title": "Buy milk", "status": "notStarted", "importance": "high",
+		 "categories": ["Red category", "Shop"],
+		 "body": {"content": "semi-skimmed", "contentType": "text"},
+		 "dueDateTime": {"dateTime": "2026-10-20T00:00:00.0000000", "timeZone": "Europe/Berlin"},
+		 "checklistItems": [{"displayName": "check fridge", "isChecked": true}]},
+		{"id": "t2", "title": "Bake bread", "status": "completed", "importance": "low",
+		 "body": {"content": "<p>rye</p>", "contentType": "html"},
+		 "dueDateTime": {"dateTime": "tomorrow", "timeZone": "UTC"},
+		 "recurrence": {"pattern": {"type": "weekly"}}},
+		{"title": "No ID", "importance": "normal", "recurrence": null}
+	]}]}`
+	tasks, err := msTodo{}.Parse(strings.NewReader(export), "todo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Task{{
+		ExternalID: "mstodo-t1",
+		Project:    "Groceries",
+		Title:      "Buy milk",
+		Notes:      "semi-skimmed\n- [x] check fridge",
+		Priority:   "A",
+		Tags:       []string{"red-category", "shop"},
+		Due:        at("2026-10-19T22:00:00Z"),
+	}, {
+		ExternalID: "mstodo-t2",
+		Project:    "Groceries",
+		Title:      "Bake bread",
+		Notes:      "<p>rye</p>",
+		Completed:  true,
+		Priority:   "C",
+		Warnings: []string{
+			"notes are HTML and were imported as is",
+			`due date "tomorrow" not understood`,
+			"recurrence was not imported",
+		},
+	}, {
+		Project: "Groceries",
+		Title:   "No ID",
+	}}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("tasks =\n%+v\nwant\n%+v", tasks, want)
+	}
+
+	// A single list's page of tasks is named after the file.
+	tasks, err = msTodo{}.Parse(strings.NewReader(`{"value": [{"id": "t3", "title": "Read"}]}`), "Reading List.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Project != "Reading List" || tasks[0].ExternalID != "mstodo-t3" {
+		t.Errorf("tasks = %+v", tasks)
+	}
+}
+
+func TestMSTodoInvalid(t *testing.T) {
+	for _, export := range []string{`{}`, `{"value": 1}`, `not json`} {
+		if _, err := (msTodo{}).Parse(strings.NewReader(export), "todo.json"); !errors.Is(err, ErrInvalidExport) {
+			t.Errorf("Parse(%s) error = %v, want ErrInvalidExport", export, err)
+		}
+	}
+}
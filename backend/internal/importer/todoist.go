@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// todoistDateLayouts are the absolute due dates a Todoist CSV may hold.
+var todoistDateLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+}
+
+// todoist reads the CSV file Todoist exports for a project. The file has no
+// project name, so the project is named after the file. Only active tasks
+// are exported; @labels in the content become tags and PRIORITY 1 (the
+// highest) to 3 become priorities A to C.
+type todoist struct{}
+
+func (todoist) Parse(r io.Reader, name string) ([]Task, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, required := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidExport, required)
+		}
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	project := projectFromFilename(name)
+	var tasks []Task
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		if !strings.EqualFold(field(record, "TYPE"), "task") {
+			continue
+		}
+		t := Task{Project: project, Notes: field(record, "DESCRIPTION")}
+		var title []string
+		for _, word := range strings.Fields(field(record, "CONTENT")) {
+			if len(word) > 1 && word[0] == '@' {
+				t.Tags = addTag(t.Tags, word[1:])
+				continue
+			}
+			title = append(title, word)
+		}
+		t.Title = strings.Join(title, " ")
+		switch field(record, "PRIORITY") {
+		case "1":
+			t.Priority = "A"
+		case "2":
+			t.Priority = "B"
+		case "3":
+			t.Priority = "C"
+		}
+		if date := field(record, "DATE"); date != "" {
+			t.Due, t.Warnings = todoistDue(date, field(record, "TIMEZONE"), line)
+		}
+		tasks = append(tasks, t)
+	}
+}
+
+// todoistDue reads an absolute due date in the task's timezone. Recurring
+// and relative dates such as "every monday" are reported instead.
+func todoistDue(date, timezone string, line int) (*time.Time, []string) {
+	loc := time.UTC
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		}
+	}
+	for _, layout := range todoistDateLayouts {
+		if due, err := time.ParseInLocation(layout, date, loc); err == nil {
+			due = due.UTC()
+			return &due, nil
+		}
+	}
+	return nil, []string{fmt.Sprintf("line %d: due date %q not understood", line, date)}
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestTodoist(t *testing.T) {
+	// Todoist writes a byte order mark before the header.
+	export := "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Errands,,,,,,,,\n" +
+		"task,Buy @shop milk @Weekly,semi-skimmed,1,1,alice,,2026-10-20,en,Europe/Berlin\n" +
+		"task,Call mum,,4,1,alice,,Oct 21 2026 18:30,en,\n" +
+		"task,Water plants @home,,3,1,alice,,every monday,en,UTC\n" +
+		"note,A note,,,,,,,,\n" +
+		"task,@ alone,,2,1,alice,,,en,\n"
+	tasks, err := todoist{}.Parse(strings.NewReader(export), "exports/Home Chores.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Task{{
+		Project:  "Home Chores",
+		Title:    "Buy milk",
+		Notes:    "semi-skimmed",
+		Priority: "A",
+		Tags:     []string{"shop", "weekly"},
+		Due:      at("2026-10-19T22:00:00Z"),
+	}, {
+		Project: "Home Chores",
+		Title:   "Call mum",
+		Due:     at("2026-10-21T18:30:00Z"),
+	}, {
+		Project:  "Home Chores",
+		Title:    "Water plants",
+		Priority: "C",
+		Tags:     []string{"home"},
+		Warnings: []string{`line 5: due date "every monday" not understood`},
+	}, {
+		Project:  "Home Chores",
+		Title:    "@ alone",
+		Priority: "B",
+	}}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("tasks =\n%+v\nwant\n%+v", tasks, want)
+	}
+}
+
+func TestTodoistInvalid(t *testing.T) {
+	for _, export := range []string{
+		"",
+		"CONTENT,PRIORITY\nBuy milk,1\n",
+		"TYPE,CONTENT\ntask,\"unterminated\n",
+	} {
+		if _, err := (todoist{}).Parse(strings.NewReader(export), "x.csv"); !errors.Is(err, ErrInvalidExport) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidExport", export, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string     `json:"id"`
+		Name        string     `json:"name"`
+		Desc        string     `json:"desc"`
+		IDList      string     `json:"idList"`
+		Closed      bool       `json:"closed"`
+		Due         *time.Time `json:"due"`
+		DueComplete bool       `json:"dueComplete"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string `json:"idCard"`
+		Name       string `json:"name"`
+		CheckItems []struct {
+			Name  string `json:"name"`
+			State string `json:"state"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// trello reads the JSON export of a Trello board. The board is the project
+// and each card a task, tagged with its labels and the name of its list.
+// Cards in a list named "Done" or with their due date marked complete are
+// completed; checklists are added to the notes. Archived cards and lists
+// are skipped.
+type trello struct{}
+
+func (trello) Parse(r io.Reader, name string) ([]Task, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if board.Name == "" && board.Cards == nil {
+		return nil, fmt.Errorf("%w: not a Trello board", ErrInvalidExport)
+	}
+	project := board.Name
+	if project == "" {
+		project = projectFromFilename(name)
+	}
+	type list struct {
+		name   string
+		closed bool
+	}
+	lists := make(map[string]list, len(board.Lists))
+	for _, l := range board.Lists {
+		lists[l.ID] = list{name: l.Name, closed: l.Closed}
+	}
+	checklists := make(map[string][]string)
+	for _, c := range board.Checklists {
+		lines := []string{"", "### " + c.Name}
+		for _, item := range c.CheckItems {
+			mark := " "
+			if item.State == "complete" {
+				mark = "x"
+			}
+			lines = append(lines, fmt.Sprintf("- [%s] %s", mark, item.Name))
+		}
+		checklists[c.IDCard] = append(checklists[c.IDCard], lines...)
+	}
+
+	var tasks []Task
+	for _, card := range board.Cards {
+		l := lists[card.IDList]
+		if card.Closed || l.closed {
+			continue
+		}
+		t := Task{
+			ExternalID: "trello-" + card.ID,
+			Project:    project,
+			Title:      strings.TrimSpace(card.Name),
+			Notes:      strings.TrimSpace(strings.Join(append([]string{card.Desc}, checklists[card.ID]...), "\n")),
+			Completed:  card.DueComplete || strings.EqualFold(strings.TrimSpace(l.name), "done"),
+			Due:        card.Due,
+		}
+		if l.name != "" {
+			t.Tags = addTag(t.Tags, l.name)
+		}
+		for _, label := range card.Labels {
+			if label.Name != "" {
+				t.Tags = addTag(t.Tags, label.Name)
+			} else if label.Color != "" {
+				t.Tags = addTag(t.Tags, label.Color)
+			}
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
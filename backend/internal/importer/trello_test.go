@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrello(t *testing.T) {
+	export := `{
+		"name": "Garden",
+		"lists": [
+			{"id": "l1", "name": "To Do"},
+			{"id": "l2", "name": "Done"},
+			{"id": "l3", "name": "Old", "closed": true}
+		],
+		"cards": [
+			{"id": "c1", "name": " Mow the lawn ", "desc": "front and back", "idList": "l1",
+			 "due": "2026-10-20T09:00:00.000Z", "labels": [{"name": "Weekend, Outside"}, {"color": "green"}]},
+			{"id": "c2", "name": "Plant bulbs", "idList": "l2"},
+			{"id": "c3", "name": "Fix fence", "idList": "l1", "dueComplete": true},
+			{"id": "c4", "name": "Archived", "idList": "l1", "closed": true},
+			{"id": "c5", "name": "In a closed list", "idList": "l3"}
+		],
+		"checklists": [
+			{"idCard": "c1", "name": "Tools", "checkItems": [
+				{"name": "mower", "state": "complete"},
+				{"name": "rake", "state": "incomplete"}
+			]}
+		]
+	}`
+	tasks, err := trello{}.Parse(strings.NewReader(export), "board.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Task{{
+		ExternalID: "trello-c1",
+		Project:    "Garden",
+		Title:      "Mow the lawn",
+		Notes:      "front and back\n\n### Tools\n- [x] mower\n- [ ] rake",
+		Due:        at("2026-10-20T09:00:00Z"),
+		Tags:       []string{"to-do", "weekend-outside", "green"},
+	}, {
+		ExternalID: "trello-c2",
+		Project:    "Garden",
+		Title:      "Plant bulbs",
+		Completed:  true,
+		Tags:       []string{"done"},
+	}, {
+		ExternalID: "trello-c3",
+		Project:    "Garden",
+		Title:      "Fix fence",
+		Completed:  true,
+		Tags:       []string{"to-do"},
+	}}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("tasks =\n%+v\nwant\n%+v", tasks, want)
+	}
+}
+
+func TestTrelloInvalid(t *testing.T) {
+	for _, export := range []string{
+		`{}`,
+		`[1, 2]`,
+		`{"name": "Garden", "cards": [{"id": "c1", "due": "next week"}]}`,
+	} {
+		if _, err := (trello{}).Parse(strings.NewReader(export), "board.json"); !errors.Is(err, ErrInvalidExport) {
+			t.Errorf("Parse(%s) error = %v, want ErrInvalidExport", export, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", ""},
+		{"**bold** and _em_", "<p><strong>bold</strong> and <em>em</em></p>\n"},
+		{"~~gone~~", "<p><del>gone</del></p>\n"},
+		{"- [x] done\n- [ ] todo", "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n"},
+		{"| a | b |\n|---|---|\n| 1 | 2 |", "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"![a](https://example.com/a.png)", "<p><img src=\"https://example.com/a.png\" alt=\"a\"></p>\n"},
+
+		// Links never pass on the referrer, and external ones open apart.
+		{"[x](https://example.com)", "<p><a href=\"https://example.com\" rel=\"nofollow noreferrer noopener\" target=\"_blank\">x</a></p>\n"},
+		{"https://example.com", "<p><a href=\"https://example.com\" rel=\"nofollow noreferrer noopener\" target=\"_blank\">https://example.com</a></p>\n"},
+		{"[x](/todos/1)", "<p><a href=\"/todos/1\" rel=\"nofollow noreferrer\">x</a></p>\n"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.source)
+		if err != nil {
+			t.Errorf("Render(%q): %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestRenderSanitises checks that neither raw HTML nor Markdown can smuggle
+// scripts into the output.
+func TestRenderSanitises(t *testing.T) {
+	sources := []string{
+		"<script>alert(1)</script>hi",
+		"<img src=x onerror=alert(1)>",
+		`<a href="https://example.com" onclick="alert(1)">x</a>`,
+		"[x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"![x](javascript:alert(1))",
+		`<iframe src="https://example.com"></iframe>`,
+		`<input type="text" value="x">`,
+		"<style>body{display:none}</style>",
+	}
+	for _, source := range sources {
+		got, err := Render(source)
+		if err != nil {
+			t.Errorf("Render(%q): %v", source, err)
+			continue
+		}
+		lower := strings.ToLower(got)
+		for _, bad := range []string{"<script", "javascript:", "onerror", "onclick", "<iframe", "<style", `type="text"`} {
+			if strings.Contains(lower, bad) {
+				t.Errorf("Render(%q) = %q, which contains %s", source, got, bad)
+			}
+		}
+	}
+}
@@ -36,6 +36,40 @@ func OAuth2Guard(srv *server.Server) func(http.Handler) http.Handler {
 	}
 }
 
+// AppPasswordAuthenticator resolves a username and app password to a user ID.
+type AppPasswordAuthenticator interface {
+	AuthenticateAppPassword(ctx context.Context, username, password string) (int64, error)
+}
+
+// BasicAuthGuard is OAuth2Guard for clients that sign in with HTTP Basic
+// authentication and an app password, such as CalDAV task apps. Bearer
+// tokens are accepted as well. Failures ask for Basic credentials for realm.
+func BasicAuthGuard(srv *server.Server, apps AppPasswordAuthenticator, realm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bearer := OAuth2Guard(srv)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				if r.Header.Get("Authorization") != "" || r.URL.Query().Get("access_token") != "" {
+					bearer.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+			userID, err := apps.AuthenticateAppPassword(r.Context(), username, password)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+				http.Error(w, "invalid username or app password", http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func UserIDFromContext(ctx context.Context) (int64, bool) {
 	val := ctx.Value(userIDKey)
 	if val == nil {
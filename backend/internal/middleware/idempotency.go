@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentBodyBytes    = 64 << 20
+	idempotentBodyMemoryBytes = 1 << 20
+)
+
+// idempotencyLease is how long a key stays reserved for a request that is
+// still running. The lease is renewed while the request runs, so it only
+// runs out when the server stopped before recording the response.
+var idempotencyLease = 30 * time.Second
+
+var errIdempotentBodyTooLarge = errors.New("request body too large")
+
+// IdempotencyStore keeps the responses recorded for idempotency keys.
+type IdempotencyStore interface {
+	BeginIdempotentRequest(ctx context.Context, userID int64, key, fingerprint string, lease time.Duration) (*model.IdempotentResponse, error)
+	RenewIdempotentRequest(ctx context.Context, userID int64, key string, lease time.Duration) error
+	CompleteIdempotentRequest(ctx context.Context, userID int64, key string, resp *model.IdempotentResponse, ttl time.Duration) error
+	ReleaseIdempotentRequest(ctx context.Context, userID int64, key string) error
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests carrying an
+// Idempotency-Key header safe to retry. The first request with a key runs
+// and its response is kept for ttl; retries with the same method, path and
+// body get that response again, while reusing the key for a different
+// request is rejected with 422. Keys are scoped to the authenticated user,
+// so the middleware belongs after OAuth2Guard; requests without a user are
+// passed through, since their keys would be shared by every client. Routes
+// outside the guard, such as registering with POST /api/users, are not
+// covered and ignore the header. Server errors are not kept, leaving the
+// request free to be retried.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
+				return
+			}
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fingerprint, cleanup, err := fingerprintRequest(r)
+			defer cleanup()
+			if err != nil {
+				if errors.Is(err, errIdempotentBodyTooLarge) {
+					http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+
+			recorded, err := store.BeginIdempotentRequest(r.Context(), userID, key, fingerprint, idempotencyLease)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if recorded != nil {
+				switch {
+				case recorded.Fingerprint != fingerprint:
+					http.Error(w, IdempotencyKeyHeader+" was already used for a different request", http.StatusUnprocessableEntity)
+				case recorded.Status == 0:
+					http.Error(w, "a request with this "+IdempotencyKeyHeader+" is still in progress", http.StatusConflict)
+				default:
+					replayResponse(w, recorded)
+				}
+				return
+			}
+
+			// Record the outcome even if the client has gone away: that is
+			// exactly when it will retry.
+			ctx := context.WithoutCancel(r.Context())
+			stop := renewIdempotencyLease(ctx, store, userID, key)
+			capture := &responseWriterCapture{ResponseWriter: w}
+			next.ServeHTTP(capture, r)
+			stop()
+
+			status := capture.statusCode()
+			if status >= http.StatusInternalServerError {
+				err = store.ReleaseIdempotentRequest(ctx, userID, key)
+			} else {
+				err = store.CompleteIdempotentRequest(ctx, userID, key, &model.IdempotentResponse{
+					Status: status,
+					Header: capture.header,
+					Body:   capture.body.Bytes(),
+				}, ttl)
+			}
+			if err != nil {
+				log.Printf("idempotency: recording %q for user %d: %v", key, userID, err)
+			}
+		})
+	}
+}
+
+// renewIdempotencyLease keeps key reserved until the returned function is
+// called.
+func renewIdempotencyLease(ctx context.Context, store IdempotencyStore, userID int64, key string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.RenewIdempotentRequest(ctx, userID, key, idempotencyLease); err != nil {
+					log.Printf("idempotency: renewing %q for user %d: %v", key, userID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprintRequest hashes the method, URI and body of r, replacing r.Body
+// so that the handler can still read it. Bodies larger than fit in memory are
+// spilled to a temporary file, which the returned cleanup removes.
+func fingerprintRequest(r *http.Request) (string, func(), error) {
+	cleanup := func() {}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+
+	var head bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(h, &head), io.LimitReader(r.Body, idempotentBodyMemoryBytes))
+	if err != nil {
+		return "", cleanup, err
+	}
+	body := io.Reader(&head)
+	if n == idempotentBodyMemoryBytes {
+		spill, err := os.CreateTemp("", "idempotent-body-*")
+		if err != nil {
+			return "", cleanup, err
+		}
+		cleanup = func() {
+			spill.Close()
+			os.Remove(spill.Name())
+		}
+		rest := io.LimitReader(r.Body, maxIdempotentBodyBytes-n+1)
+		m, err := io.Copy(io.MultiWriter(h, spill), rest)
+		if err != nil {
+			return "", cleanup, err
+		}
+		if n+m > maxIdempotentBodyBytes {
+			return "", cleanup, errIdempotentBodyTooLarge
+		}
+		if _, err := spill.Seek(0, io.SeekStart); err != nil {
+			return "", cleanup, err
+		}
+		body = io.MultiReader(&head, spill)
+	}
+	r.Body = io.NopCloser(body)
+	return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// replayResponse writes a recorded response, marked as a replay.
+func replayResponse(w http.ResponseWriter, recorded *model.IdempotentResponse) {
+	for name, values := range recorded.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(recorded.Status)
+	_, _ = w.Write(recorded.Body)
+}
+
+// responseWriterCapture passes a response through while keeping a copy of
+// its status, headers and body.
+type responseWriterCapture struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	header http.Header
+	status int
+}
+
+func (rw *responseWriterCapture) WriteHeader(statusCode int) {
+	if rw.status == 0 {
+		rw.status = statusCode
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriterCapture) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// statusCode is the status that was sent, which is 200 when the handler
+// wrote nothing at all.
+func (rw *responseWriterCapture) statusCode() int {
+	if rw.status == 0 {
+		rw.header = rw.ResponseWriter.Header().Clone()
+		return http.StatusOK
+	}
+	return rw.status
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// memoryIdempotencyStore is an IdempotencyStore in memory that, like the
+// database, drops entries whose lease or ttl has run out.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryIdempotencyEntry
+	renewed int
+}
+
+type memoryIdempotencyEntry struct {
+	resp    model.IdempotentResponse
+	expires time.Time
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{entries: map[string]*memoryIdempotencyEntry{}}
+}
+
+func (s *memoryIdempotencyStore) id(userID int64, key string) string {
+	return strconv.FormatInt(userID, 10) + "/" + key
+}
+
+func (s *memoryIdempotencyStore) BeginIdempotentRequest(ctx context.Context, userID int64, key, fingerprint string, lease time.Duration) (*model.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[s.id(userID, key)]; ok && time.Now().Before(e.expires) {
+		resp := e.resp
+		return &resp, nil
+	}
+	s.entries[s.id(userID, key)] = &memoryIdempotencyEntry{resp: model.IdempotentResponse{Fingerprint: fingerprint}, expires: time.Now().Add(lease)}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) RenewIdempotentRequest(ctx context.Context, userID int64, key string, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewed++
+	if e, ok := s.entries[s.id(userID, key)]; ok && e.resp.Status == 0 {
+		e.expires = time.Now().Add(lease)
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotentRequest(ctx context.Context, userID int64, key string, resp *model.IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[s.id(userID, key)]
+	fingerprint := e.resp.Fingerprint
+	e.resp = *resp
+	e.resp.Fingerprint = fingerprint
+	e.expires = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotentRequest(ctx context.Context, userID int64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, s.id(userID, key))
+	return nil
+}
+
+func (s *memoryIdempotencyStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// idempotentRequest sends a POST with key as userID, or anonymously when
+// userID is zero.
+func idempotentRequest(t *testing.T, h http.Handler, userID int64, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/todos", strings.NewReader(body))
+	r.Header.Set(IdempotencyKeyHeader, key)
+	if userID != 0 {
+		r = r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// countingHandler answers status with the number of requests it has run.
+func countingHandler(calls *atomic.Int32, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := calls.Add(1)
+		w.Header().Set("X-Call", strconv.Itoa(int(n)))
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	})
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	var calls atomic.Int32
+	h := Idempotency(store, time.Hour)(countingHandler(&calls, http.StatusCreated))
+
+	first := idempotentRequest(t, h, 1, "k", "hello")
+	second := idempotentRequest(t, h, 1, "k", "hello")
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want once", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "hello" || second.Header().Get("X-Call") != "1" {
+		t.Errorf("replay = %d %q %v, want the first response", second.Code, second.Body.String(), second.Header())
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("%s headers = %q, %q", IdempotentReplayedHeader, first.Header().Get(IdempotentReplayedHeader), second.Header().Get(IdempotentReplayedHeader))
+	}
+
+	if w := idempotentRequest(t, h, 1, "k", "other"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key = %d, want 422", w.Code)
+	}
+	// Keys belong to their user.
+	if w := idempotentRequest(t, h, 2, "k", "hello"); w.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("another user's key = %d after %d calls, want a fresh run", w.Code, calls.Load())
+	}
+}
+
+func TestIdempotencyAnonymous(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	var calls atomic.Int32
+	h := Idempotency(store, time.Hour)(countingHandler(&calls, http.StatusCreated))
+	for range 2 {
+		if w := idempotentRequest(t, h, 0, "k", "register"); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("anonymous request = %d %v, want it to run", w.Code, w.Header())
+		}
+	}
+	if calls.Load() != 2 || store.len() != 0 {
+		t.Errorf("anonymous requests ran %d times and stored %d keys, want 2 and 0", calls.Load(), store.len())
+	}
+}
+
+func TestIdempotencyServerErrorReleases(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	var calls atomic.Int32
+	h := Idempotency(store, time.Hour)(countingHandler(&calls, http.StatusServiceUnavailable))
+	idempotentRequest(t, h, 1, "k", "x")
+	idempotentRequest(t, h, 1, "k", "x")
+	if calls.Load() != 2 || store.len() != 0 {
+		t.Errorf("handler ran %d times with %d keys kept, want 2 and 0", calls.Load(), store.len())
+	}
+}
+
+func TestIdempotencyLease(t *testing.T) {
+	defer func(lease time.Duration) { idempotencyLease = lease }(idempotencyLease)
+	idempotencyLease = 30 * time.Millisecond
+
+	store := newMemoryIdempotencyStore()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		idempotentRequest(t, slow, 1, "k", "x")
+	}()
+	<-started
+
+	// The running request renews its lease, so retries keep getting 409
+	// for longer than the lease.
+	var calls atomic.Int32
+	retry := Idempotency(store, time.Hour)(countingHandler(&calls, http.StatusCreated))
+	for range 4 {
+		time.Sleep(idempotencyLease / 2)
+		if w := idempotentRequest(t, retry, 1, "k", "x"); w.Code != http.StatusConflict {
+			t.Fatalf("retry while running = %d, want 409", w.Code)
+		}
+	}
+	close(release)
+	<-done
+	if store.renewed == 0 {
+		t.Error("lease was never renewed")
+	}
+
+	// A request that never completes, as when the server crashed, holds
+	// its key only until the lease runs out.
+	if _, err := store.BeginIdempotentRequest(context.Background(), 1, "crashed", fingerprintOf(t, "x"), idempotencyLease); err != nil {
+		t.Fatal(err)
+	}
+	if w := idempotentRequest(t, retry, 1, "crashed", "x"); w.Code != http.StatusConflict {
+		t.Errorf("retry within the lease = %d, want 409", w.Code)
+	}
+	time.Sleep(2 * idempotencyLease)
+	if w := idempotentRequest(t, retry, 1, "crashed", "x"); w.Code != http.StatusCreated {
+		t.Errorf("retry after the lease = %d, want the request to run", w.Code)
+	}
+}
+
+func fingerprintOf(t *testing.T, body string) string {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/todos", strings.NewReader(body))
+	fingerprint, cleanup, err := fingerprintRequest(r)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fingerprint
+}
@@ -3,6 +3,7 @@ package migrations
 import (
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 
 	"github.com/pressly/goose/v3"
@@ -12,6 +13,9 @@ import (
 var embeddedMigrations embed.FS
 
 func Up(db *sql.DB) error {
+	if err := requireFTS5(db); err != nil {
+		return err
+	}
 	goose.SetBaseFS(embeddedMigrations)
 	if err := goose.SetDialect("sqlite3"); err != nil {
 		return fmt.Errorf("set goose dialect: %w", err)
@@ -21,3 +25,16 @@ func Up(db *sql.DB) error {
 	}
 	return nil
 }
+
+// requireFTS5 fails early with a useful message when the SQLite driver was
+// compiled without FTS5, which the todo search index needs.
+func requireFTS5(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled); err != nil {
+		return fmt.Errorf("check sqlite features: %w", err)
+	}
+	if !enabled {
+		return errors.New("sqlite was built without FTS5; build with -tags sqlite_fts5")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+//go:build sqlite_fts5
+
+package migrations
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
+)
+
+// migrateTo opens a database in a temporary directory and applies the
+// migrations up to and including version.
+func migrateTo(t *testing.T, version int64) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.TempDir()+"/test.db?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	goose.SetBaseFS(embeddedMigrations)
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := goose.UpTo(db, "sql", version); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestListStatusesMigration(t *testing.T) {
+	db := migrateTo(t, 202410190020)
+	exec := func(query string, args ...any) int64 {
+		t.Helper()
+		res, err := db.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	userID := exec(`INSERT INTO users (username, password_hash) VALUES ('alice', 'x')`)
+	home := exec(`INSERT INTO lists (name, created_by) VALUES ('Home', ?)`, userID)
+	work := exec(`INSERT INTO lists (name, created_by) VALUES ('Work', ?)`, userID)
+	want := map[int64]struct {
+		listID   int64
+		category string
+	}{}
+	for _, todo := range []struct {
+		listID    int64
+		completed int
+	}{{home, 0}, {home, 1}, {work, 1}, {work, 0}, {work, 1}} {
+		id := exec(`INSERT INTO todos (user_id, list_id, title, completed) VALUES (?, ?, 'x', ?)`, userID, todo.listID, todo.completed)
+		category := "todo"
+		if todo.completed == 1 {
+			category = "done"
+		}
+		want[id] = struct {
+			listID   int64
+			category string
+		}{todo.listID, category}
+	}
+
+	if err := goose.UpTo(db, "sql", 202410190021); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT t.id, t.list_id, s.list_id, s.name, s.category
+		FROM todos t LEFT JOIN list_statuses s ON s.id = t.status_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	seen := 0
+	for rows.Next() {
+		var (
+			id, listID     int64
+			statusListID   sql.NullInt64
+			name, category sql.NullString
+		)
+		if err := rows.Scan(&id, &listID, &statusListID, &name, &category); err != nil {
+			t.Fatal(err)
+		}
+		w, ok := want[id]
+		if !ok {
+			// The seeded todo is migrated like any other.
+			if !statusListID.Valid || statusListID.Int64 != listID {
+				t.Errorf("seeded todo %d has no status of its list", id)
+			}
+			continue
+		}
+		seen++
+		wantName := map[string]string{"todo": "To do", "done": "Done"}[w.category]
+		if statusListID.Int64 != w.listID || category.String != w.category || name.String != wantName {
+			t.Errorf("todo %d in list %d has status %q (%s) of list %d, want %q of its list",
+				id, w.listID, name.String, category.String, statusListID.Int64, wantName)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if seen != len(want) {
+		t.Errorf("saw %d of %d todos", seen, len(want))
+	}
+
+	var statuses int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM list_statuses WHERE list_id IN (?, ?)`, home, work).Scan(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if statuses != 4 {
+		t.Errorf("the two lists have %d statuses, want To do and Done each", statuses)
+	}
+	// Lists created afterwards get the same two statuses.
+	later := exec(`INSERT INTO lists (name, created_by) VALUES ('Later', ?)`, userID)
+	if err := db.QueryRow(`SELECT COUNT(*) FROM list_statuses WHERE list_id = ? AND name IN ('To do', 'Done')`, later).Scan(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if statuses != 2 {
+		t.Errorf("a new list has %d default statuses, want 2", statuses)
+	}
+}
+
+func TestIdempotencyKeysUserFKMigration(t *testing.T) {
+	db := migrateTo(t, 202410190024)
+	res, err := db.Exec(`INSERT INTO users (username, password_hash) VALUES ('alice', 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []int64{userID, 0, 9999} {
+		if _, err := db.Exec(`INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at)
+			VALUES (?, 'k', 'fp', '2026-10-19 10:00:00.000', '2026-10-20 10:00:00.000')`, owner); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := goose.UpTo(db, "sql", 202410190025); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM idempotency_keys`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(); n != 1 {
+		t.Errorf("%d keys after the migration, want only alice's", n)
+	}
+	if _, err := db.Exec(`DELETE FROM users WHERE id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("%d keys after deleting alice, want none", n)
+	}
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE todos ADD COLUMN due_at DATETIME;
+ALTER TABLE todos ADD COLUMN rrule TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN repeat_mode TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE todos DROP COLUMN repeat_mode;
+ALTER TABLE todos DROP COLUMN timezone;
+ALTER TABLE todos DROP COLUMN rrule;
+ALTER TABLE todos DROP COLUMN due_at;
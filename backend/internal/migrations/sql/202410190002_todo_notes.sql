@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE todos ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE todos DROP COLUMN notes;
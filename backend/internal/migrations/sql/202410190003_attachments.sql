@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_todo_id ON attachments(todo_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments(user_id);
+
+-- Blobs outlive their metadata rows until the background sweeper removes
+-- them, so cascading deletes of todos and users never leak stored files.
+CREATE TABLE IF NOT EXISTS orphaned_blobs (
+    storage_key TEXT PRIMARY KEY,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS attachments_orphan_blob AFTER DELETE ON attachments
+BEGIN
+    INSERT OR IGNORE INTO orphaned_blobs (storage_key) VALUES (OLD.storage_key);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS attachments_orphan_blob;
+DROP TABLE IF EXISTS orphaned_blobs;
+DROP TABLE IF EXISTS attachments;
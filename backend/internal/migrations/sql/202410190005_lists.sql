@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    is_default INTEGER NOT NULL DEFAULT 0,
+    created_by INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS list_members (
+    list_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, user_id),
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_list_members_user_id ON list_members(user_id);
+
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists(id) ON DELETE CASCADE;
+
+-- Every user owns a default list that todos land in unless another is given.
+INSERT INTO lists (name, is_default, created_by)
+SELECT 'Inbox', 1, id FROM users;
+
+INSERT INTO list_members (list_id, user_id, role)
+SELECT id, created_by, 'owner' FROM lists;
+
+UPDATE todos SET list_id = (
+    SELECT l.id FROM lists l WHERE l.created_by = todos.user_id AND l.is_default = 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_todos_list_id ON todos(list_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS users_default_list AFTER INSERT ON users
+BEGIN
+    INSERT INTO lists (name, is_default, created_by) VALUES ('Inbox', 1, NEW.id);
+    INSERT INTO list_members (list_id, user_id, role) VALUES (last_insert_rowid(), NEW.id, 'owner');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS users_default_list;
+DROP INDEX IF EXISTS idx_todos_list_id;
+ALTER TABLE todos DROP COLUMN list_id;
+DROP TABLE IF EXISTS list_members;
+DROP TABLE IF EXISTS lists;
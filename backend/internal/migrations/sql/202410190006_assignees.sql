@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE todos ADD COLUMN assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_assignee_id ON todos(assignee_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    todo_id INTEGER,
+    actor_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    read_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+DROP INDEX IF EXISTS idx_todos_assignee_id;
+ALTER TABLE todos DROP COLUMN assignee_id;
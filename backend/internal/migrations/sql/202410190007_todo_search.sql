@@ -0,0 +1,40 @@
+-- +goose Up
+-- External-content FTS5 index over todo titles and notes. Requires SQLite
+-- built with FTS5 (go build -tags sqlite_fts5).
+CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(
+    title,
+    notes,
+    content='todos',
+    content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+INSERT INTO todos_fts(todos_fts) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos
+BEGIN
+    INSERT INTO todos_fts(rowid, title, notes) VALUES (new.id, new.title, new.notes);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos
+BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, title, notes) VALUES ('delete', old.id, old.title, old.notes);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE OF title, notes ON todos
+BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, title, notes) VALUES ('delete', old.id, old.title, old.notes);
+    INSERT INTO todos_fts(rowid, title, notes) VALUES (new.id, new.title, new.notes);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todos_fts_update;
+DROP TRIGGER IF EXISTS todos_fts_delete;
+DROP TRIGGER IF EXISTS todos_fts_insert;
+DROP TABLE IF EXISTS todos_fts;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Keyset pagination of todos within a list. The expressions must match the
+-- sort keys in repository.todoSortKeys for SQLite to use these indexes.
+CREATE INDEX IF NOT EXISTS idx_todos_list_created_at ON todos(list_id, COALESCE(created_at, ''), id);
+CREATE INDEX IF NOT EXISTS idx_todos_list_due_at ON todos(list_id, COALESCE(due_at, '9999-12-31'), id);
+CREATE INDEX IF NOT EXISTS idx_todos_list_title ON todos(list_id, title COLLATE NOCASE, id);
+CREATE INDEX IF NOT EXISTS idx_todos_list_completed ON todos(list_id, completed, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_list_completed;
+DROP INDEX IF EXISTS idx_todos_list_title;
+DROP INDEX IF EXISTS idx_todos_list_due_at;
+DROP INDEX IF EXISTS idx_todos_list_created_at;
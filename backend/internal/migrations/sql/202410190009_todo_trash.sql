@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE todos ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_deleted_at;
+ALTER TABLE todos DROP COLUMN deleted_at;
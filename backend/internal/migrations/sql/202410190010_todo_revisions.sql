@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS todo_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL,
+    user_id INTEGER,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL DEFAULT '{}',
+    revert_of INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_revisions_todo_id ON todo_revisions(todo_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS todo_revisions;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE todos ADD COLUMN updated_at DATETIME;
+
+UPDATE todos SET updated_at = created_at;
+
+-- +goose Down
+ALTER TABLE todos DROP COLUMN updated_at;
+ALTER TABLE todos DROP COLUMN version;
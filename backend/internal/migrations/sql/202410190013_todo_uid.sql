@@ -0,0 +1,12 @@
+-- +goose Up
+-- uid is the stable identifier todos are exported and imported under
+-- (the iCalendar UID).
+ALTER TABLE todos ADD COLUMN uid TEXT;
+
+UPDATE todos SET uid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(6)));
+
+CREATE INDEX IF NOT EXISTS idx_todos_uid ON todos(uid);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_uid;
+ALTER TABLE todos DROP COLUMN uid;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Only a SHA-256 hash of each generated password is kept.
+CREATE TABLE IF NOT EXISTS app_passwords (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    password_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_passwords_user_id ON app_passwords(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app_passwords;
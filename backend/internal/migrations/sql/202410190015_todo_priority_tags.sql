@@ -0,0 +1,9 @@
+-- +goose Up
+-- priority is a letter from A (highest) to Z, or empty; tags is a JSON array
+-- of lowercase labels.
+ALTER TABLE todos ADD COLUMN priority TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE todos DROP COLUMN tags;
+ALTER TABLE todos DROP COLUMN priority;
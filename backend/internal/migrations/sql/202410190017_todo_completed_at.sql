@@ -0,0 +1,11 @@
+-- +goose Up
+-- completed_at records when a todo was last completed. Todos completed before
+-- it existed take their last change as the closest approximation.
+ALTER TABLE todos ADD COLUMN completed_at DATETIME;
+UPDATE todos SET completed_at = updated_at WHERE completed = 1;
+
+CREATE INDEX IF NOT EXISTS idx_todos_list_completed_at ON todos(list_id, completed_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_todos_list_completed_at;
+ALTER TABLE todos DROP COLUMN completed_at;
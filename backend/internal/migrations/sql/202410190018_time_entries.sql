@@ -0,0 +1,20 @@
+-- +goose Up
+-- A time entry without ended_at is a running timer; each user may have one.
+CREATE TABLE IF NOT EXISTS time_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME,
+    note TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_todo_id ON time_entries(todo_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_started_at ON time_entries(started_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS time_entries;
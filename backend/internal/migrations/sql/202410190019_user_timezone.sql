@@ -0,0 +1,7 @@
+-- +goose Up
+-- timezone is the IANA name dates typed by the user are read in; empty means
+-- UTC.
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN timezone;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- todo_id is blocked by blocker_id until the blocker is completed.
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+    todo_id INTEGER NOT NULL,
+    blocker_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (todo_id, blocker_id),
+    CHECK (todo_id <> blocker_id),
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocker_id) REFERENCES todos(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
+
+-- Lists enforcing dependencies refuse to complete blocked todos.
+ALTER TABLE lists ADD COLUMN enforce_dependencies INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE lists DROP COLUMN enforce_dependencies;
+DROP TABLE IF EXISTS todo_dependencies;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- Statuses are the workflow columns of a list, in position order. The
+-- category groups them into open, in progress and done; todos in a done
+-- status are completed.
+CREATE TABLE IF NOT EXISTS list_statuses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    list_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    category TEXT NOT NULL CHECK (category IN ('todo', 'in_progress', 'done')),
+    position INTEGER NOT NULL,
+    wip_limit INTEGER,
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_list_statuses_list_id ON list_statuses(list_id, position);
+
+INSERT INTO list_statuses (list_id, name, category, position)
+SELECT id, 'To do', 'todo', 0 FROM lists;
+INSERT INTO list_statuses (list_id, name, category, position)
+SELECT id, 'Done', 'done', 1 FROM lists;
+
+ALTER TABLE todos ADD COLUMN status_id INTEGER REFERENCES list_statuses(id) ON DELETE SET NULL;
+
+UPDATE todos SET status_id = (
+    SELECT s.id FROM list_statuses s
+    WHERE s.list_id = todos.list_id AND s.category = CASE WHEN todos.completed = 1 THEN 'done' ELSE 'todo' END
+);
+
+CREATE INDEX IF NOT EXISTS idx_todos_status_id ON todos(status_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS lists_default_statuses AFTER INSERT ON lists
+BEGIN
+    INSERT INTO list_statuses (list_id, name, category, position) VALUES (NEW.id, 'To do', 'todo', 0);
+    INSERT INTO list_statuses (list_id, name, category, position) VALUES (NEW.id, 'Done', 'done', 1);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS lists_default_statuses;
+DROP INDEX IF EXISTS idx_todos_status_id;
+ALTER TABLE todos DROP COLUMN status_id;
+DROP TABLE IF EXISTS list_statuses;
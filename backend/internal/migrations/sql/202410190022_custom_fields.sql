@@ -0,0 +1,29 @@
+-- +goose Up
+-- Custom fields are defined per list. options holds the JSON array of
+-- choices of a select field.
+CREATE TABLE IF NOT EXISTS list_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    list_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('text', 'number', 'date', 'select', 'checkbox', 'user')),
+    options TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    UNIQUE (list_id, name),
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE
+);
+
+-- value is JSON, so that json_extract compares numbers as numbers.
+CREATE TABLE IF NOT EXISTS todo_field_values (
+    todo_id INTEGER NOT NULL,
+    field_id INTEGER NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (todo_id, field_id),
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (field_id) REFERENCES list_fields(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_field_values_field_id ON todo_field_values(field_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS todo_field_values;
+DROP TABLE IF EXISTS list_fields;
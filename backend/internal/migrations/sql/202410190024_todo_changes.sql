@@ -0,0 +1,45 @@
+-- +goose Up
+-- todo_changes holds the latest change of each todo in each list it is or
+-- was in, for CalDAV sync tokens. Triggers write it on every insert, version
+-- bump and move, whatever code made them, and a change replaces the previous
+-- one with a higher id. AUTOINCREMENT never reuses ids and the rows outlive
+-- purged todos, so a list's latest id only ever grows.
+CREATE TABLE IF NOT EXISTS todo_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    list_id INTEGER NOT NULL,
+    todo_id INTEGER NOT NULL,
+    uid TEXT NOT NULL,
+    UNIQUE (list_id, todo_id),
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_changes_list_id ON todo_changes(list_id, id);
+
+-- Sync tokens used to be revision ids. Start above them so that tokens
+-- handed out before this migration report every todo as changed.
+INSERT INTO sqlite_sequence (name, seq) SELECT 'todo_changes', COALESCE(MAX(id), 0) FROM todo_revisions;
+
+INSERT INTO todo_changes (list_id, todo_id, uid)
+SELECT list_id, id, COALESCE(uid, '') FROM todos ORDER BY updated_at, id;
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS todos_change_insert AFTER INSERT ON todos
+BEGIN
+    INSERT OR REPLACE INTO todo_changes (list_id, todo_id, uid) VALUES (NEW.list_id, NEW.id, COALESCE(NEW.uid, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS todos_change_update AFTER UPDATE OF version, list_id ON todos
+WHEN NEW.version <> OLD.version OR NEW.list_id <> OLD.list_id
+BEGIN
+    INSERT OR REPLACE INTO todo_changes (list_id, todo_id, uid)
+    SELECT OLD.list_id, OLD.id, COALESCE(OLD.uid, '') WHERE NEW.list_id <> OLD.list_id;
+    INSERT OR REPLACE INTO todo_changes (list_id, todo_id, uid) VALUES (NEW.list_id, NEW.id, COALESCE(NEW.uid, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todos_change_update;
+DROP TRIGGER IF EXISTS todos_change_insert;
+DROP TABLE IF EXISTS todo_changes;
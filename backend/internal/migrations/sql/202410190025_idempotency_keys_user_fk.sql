@@ -0,0 +1,45 @@
+-- +goose Up
+-- idempotency_keys only ever holds keys of authenticated users, so they go
+-- with the user. SQLite cannot add a foreign key to a table, so it is
+-- rebuilt; keys of users that no longer exist are dropped.
+CREATE TABLE idempotency_keys_new (
+    user_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    header TEXT,
+    body BLOB,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, key),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO idempotency_keys_new
+SELECT user_id, key, fingerprint, status, header, body, created_at, expires_at
+FROM idempotency_keys WHERE user_id IN (SELECT id FROM users);
+
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_new RENAME TO idempotency_keys;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- +goose Down
+CREATE TABLE idempotency_keys_old (
+    user_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    header TEXT,
+    body BLOB,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+INSERT INTO idempotency_keys_old SELECT * FROM idempotency_keys;
+
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_old RENAME TO idempotency_keys;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
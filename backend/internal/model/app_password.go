@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// AppPassword lets a client that cannot sign in with OAuth2, such as a
+// CalDAV task app, use HTTP Basic authentication instead. Password is only
+// set in the response that creates it.
+type AppPassword struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Password   string     `json:"password,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Attachment describes a file uploaded to a todo. The contents live in a
+// blob store under StorageKey.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	TodoID      int64     `json:"todoId"`
+	UserID      int64     `json:"userId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
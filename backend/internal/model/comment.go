@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Comment is a message left on a todo.
+type Comment struct {
+	ID        int64       `json:"id"`
+	TodoID    int64       `json:"todoId"`
+	Author    UserSummary `json:"author"`
+	Body      string      `json:"body"`
+	CreatedAt time.Time   `json:"createdAt"`
+	EditedAt  *time.Time  `json:"editedAt,omitempty"`
+}
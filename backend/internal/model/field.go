@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Custom field types and the JSON values they hold.
+const (
+	FieldText     = "text"     // a string
+	FieldNumber   = "number"   // a number
+	FieldDate     = "date"     // a YYYY-MM-DD string
+	FieldSelect   = "select"   // one of the field's options
+	FieldCheckbox = "checkbox" // true or false
+	FieldUser     = "user"     // the ID of a member of the list
+)
+
+// ListField is a custom field the todos of a list can have a value for.
+type ListField struct {
+	ID        int64     `json:"id"`
+	ListID    int64     `json:"listId"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options"` // the choices of a select field
+	CreatedAt time.Time `json:"createdAt"`
+}
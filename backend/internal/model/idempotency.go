@@ -0,0 +1,12 @@
+package model
+
+import "net/http"
+
+// IdempotentResponse is the response recorded for an Idempotency-Key. Status
+// is zero while the original request is still being handled.
+type IdempotentResponse struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Import job statuses.
+const (
+	ImportJobPending   = "pending"
+	ImportJobRunning   = "running"
+	ImportJobSucceeded = "succeeded"
+	ImportJobFailed    = "failed"
+)
+
+// ImportJob is an import of another tool's export file, run in the
+// background. A dry run only previews what the import would do.
+type ImportJob struct {
+	ID       int64  `json:"id"`
+	Format   string `json:"format"`
+	Filename string `json:"filename,omitempty"`
+	// ListID, when set, is the list every task is imported into instead of
+	// the list named after its project.
+	ListID    *int64 `json:"listId,omitempty"`
+	DryRun    bool   `json:"dryRun"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Failed    int    `json:"failed"`
+	// Error says why a failed job stopped.
+	Error      string          `json:"error,omitempty"`
+	Items      []ImportJobItem `json:"items,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// ImportJobItem is the outcome of importing one task, or in a dry run the
+// outcome it would have.
+type ImportJobItem struct {
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	// List is the name of the list the task goes to; NewList says the
+	// import creates it.
+	List     string   `json:"list,omitempty"`
+	NewList  bool     `json:"newList,omitempty"`
+	Result   string   `json:"result,omitempty"`
+	TodoID   int64    `json:"todoId,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
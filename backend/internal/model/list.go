@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Member roles on a list, from least to most privileged.
+const (
+	// RoleViewer can read the list's todos and comment on them.
+	RoleViewer = "viewer"
+	// RoleEditor can also create, change and delete todos.
+	RoleEditor = "editor"
+	// RoleOwner can also rename or delete the list and manage its members.
+	RoleOwner = "owner"
+)
+
+// List groups todos and is shared with its members.
+type List struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	IsDefault bool      `json:"isDefault"`
+	CreatedBy int64     `json:"createdBy"`
+	Role      string    `json:"role"` // the requesting user's role
+	CreatedAt time.Time `json:"createdAt"`
+	// EnforceDependencies keeps the list's todos from being completed while
+	// they are blocked.
+	EnforceDependencies bool `json:"enforceDependencies"`
+}
+
+// ListMember is a user's membership of a list.
+type ListMember struct {
+	ListID    int64       `json:"listId"`
+	User      UserSummary `json:"user"`
+	Role      string      `json:"role"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Notification kinds.
+const (
+	// NotificationAssigned tells a user a todo was assigned to them.
+	NotificationAssigned = "assigned"
+)
+
+// Notification is a message addressed to a single user.
+type Notification struct {
+	ID        int64        `json:"id"`
+	Kind      string       `json:"kind"`
+	TodoID    *int64       `json:"todoId,omitempty"`
+	Actor     *UserSummary `json:"actor,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+	ReadAt    *time.Time   `json:"readAt,omitempty"`
+}
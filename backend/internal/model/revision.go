@@ -0,0 +1,33 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision actions.
+const (
+	RevisionCreated  = "created"
+	RevisionUpdated  = "updated"
+	RevisionDeleted  = "deleted"
+	RevisionRestored = "restored"
+	RevisionReverted = "reverted"
+)
+
+// TodoRevision records one change to a todo. Changes maps the JSON name of
+// each field that changed to its values before and after.
+type TodoRevision struct {
+	ID        int64                  `json:"id"`
+	TodoID    int64                  `json:"todoId"`
+	Actor     *UserSummary           `json:"actor,omitempty"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	RevertOf  *int64                 `json:"revertOf,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// FieldChange holds the JSON values of a field before and after a revision.
+type FieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
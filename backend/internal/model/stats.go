@@ -0,0 +1,38 @@
+package model
+
+// Stats summarises how todos were created and completed over a range of
+// days in a timezone. Dates are YYYY-MM-DD in that timezone.
+type Stats struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Timezone  string `json:"timezone"`
+	Interval  string `json:"interval"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	// AverageCompletionSeconds is the mean time from creation to completion
+	// of the todos completed in the range, or nil when there are none.
+	AverageCompletionSeconds *float64 `json:"averageCompletionSeconds"`
+	// CompletedLate counts the todos completed in the range after they were
+	// due.
+	CompletedLate int `json:"completedLate"`
+	// Overdue counts the open todos whose due date has passed, as of now.
+	Overdue int           `json:"overdue"`
+	Streak  Streak        `json:"streak"`
+	Buckets []StatsBucket `json:"buckets"`
+}
+
+// Streak counts consecutive days with at least one completed todo. Current
+// ends today, or yesterday while nothing has been completed today; Longest
+// is the longest within the range.
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// StatsBucket counts the todos created and completed in the day or week
+// starting on Start.
+type StatsBucket struct {
+	Start     string `json:"start"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
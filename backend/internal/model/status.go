@@ -0,0 +1,41 @@
+package model
+
+// Status categories. Every list keeps at least one open status and one done
+// status; todos in a done status are completed.
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// ListStatus is a workflow column of a list. WIPLimit caps how many todos
+// can be in it at once; nil means no limit.
+type ListStatus struct {
+	ID       int64  `json:"id"`
+	ListID   int64  `json:"listId"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Position int    `json:"position"`
+	WIPLimit *int   `json:"wipLimit"`
+}
+
+// StatusSummary is the status embedded in a todo.
+type StatusSummary struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// Board is a list's todos grouped by status, in status order.
+type Board struct {
+	List    List          `json:"list"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+// BoardColumn holds up to the requested number of todos in a status; Count
+// is the number of todos in it altogether.
+type BoardColumn struct {
+	Status ListStatus `json:"status"`
+	Count  int        `json:"count"`
+	Todos  []Todo     `json:"todos"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// TimeEntry is time a user spent on a todo. EndedAt is nil while the entry
+// is a running timer, whose Seconds count up to now.
+type TimeEntry struct {
+	ID        int64       `json:"id"`
+	TodoID    int64       `json:"todoId"`
+	TodoTitle string      `json:"todoTitle"`
+	ListID    int64       `json:"listId"`
+	User      UserSummary `json:"user"`
+	StartedAt time.Time   `json:"startedAt"`
+	EndedAt   *time.Time  `json:"endedAt"`
+	Seconds   int64       `json:"seconds"`
+	Note      string      `json:"note"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// TimeTotal is the time spent on a todo or list. TodoID is zero in totals
+// per list; Name is the todo's title or the list's name.
+type TimeTotal struct {
+	TodoID  int64  `json:"todoId,omitempty"`
+	ListID  int64  `json:"listId"`
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
@@ -2,11 +2,66 @@ package model
 
 import "time"
 
-// Todo represents a task that belongs to a user.
+// Repeat modes for recurring todos.
+const (
+	// RepeatFixed schedules the next occurrence from the previous due date.
+	RepeatFixed = "fixed"
+	// RepeatAfterCompletion schedules the next occurrence from the moment
+	// the current one is completed.
+	RepeatAfterCompletion = "after_completion"
+)
+
+// Todo represents a task in a list. Access is granted through list
+// membership; UserID records who created it.
 type Todo struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"userId"`
-	Title     string    `json:"title"`
-	Completed bool      `json:"completed"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID         int64        `json:"id"`
+	ListID     int64        `json:"listId"`
+	UserID     int64        `json:"userId"` // creator
+	Creator    UserSummary  `json:"creator"`
+	AssigneeID *int64       `json:"assigneeId"`
+	Assignee   *UserSummary `json:"assignee,omitempty"`
+	Title      string       `json:"title"`
+	Notes      string       `json:"notes"`
+	NotesHTML  string       `json:"notesHtml,omitempty"`
+	Completed  bool         `json:"completed"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	DueAt      *time.Time   `json:"dueAt,omitempty"`
+	RRule      string       `json:"rrule,omitempty"`
+	Timezone   string       `json:"timezone,omitempty"`
+	RepeatMode string       `json:"repeatMode,omitempty"`
+	Priority   string       `json:"priority,omitempty"` // A (highest) to Z, or empty
+	Tags       []string     `json:"tags"`
+	DeletedAt  *time.Time   `json:"deletedAt,omitempty"`
+	// Version increases by one with every change to the todo.
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// CompletedAt is when the todo was completed, and nil while it is open.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// UID identifies the todo in exported calendars; imports match on it.
+	UID string `json:"uid"`
+	// Blocked tells whether another todo that is still open blocks it.
+	Blocked bool `json:"blocked"`
+	// StatusID is the workflow status of the todo within its list. Completed
+	// follows it: a todo is completed exactly when its status is done.
+	StatusID int64          `json:"statusId"`
+	Status   *StatusSummary `json:"status,omitempty"`
+	// Fields holds the values of the list's custom fields, keyed by field
+	// ID. Fields without a value are left out.
+	Fields map[int64]any `json:"fields"`
+}
+
+// Dependencies are the todos blocking a todo and those it blocks.
+type Dependencies struct {
+	Blocked   bool   `json:"blocked"`
+	BlockedBy []Todo `json:"blockedBy"`
+	Blocking  []Todo `json:"blocking"`
+}
+
+// SearchResult is a todo matched by a full-text search. TitleHighlight and
+// NotesSnippet are HTML-escaped with the matched terms wrapped in <mark>.
+type SearchResult struct {
+	Todo
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"titleHighlight"`
+	NotesSnippet   string  `json:"notesSnippet,omitempty"`
 }
@@ -7,5 +7,12 @@ type User struct {
 	ID           int64     `json:"id"`
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"`
+	Timezone     string    `json:"timezone"` // IANA name, or empty for UTC
 	CreatedAt    time.Time `json:"createdAt"`
 }
+
+// UserSummary is the public subset of a user embedded in other resources.
+type UserSummary struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// View is a todo query a user saved under a name: a filter query and a
+// sort, both in the syntax GET /api/todos takes.
+type View struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	Sort      string    `json:"sort"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
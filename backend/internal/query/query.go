@@ -0,0 +1,354 @@
+// Package query parses the filter language of todo queries, such as
+//
+//	is:open tag:urgent due<=this-week assignee:me
+//
+// into an expression tree. Terms are field:value comparisons or bare words;
+// terms next to each other must all match, OR matches either side, and NOT
+// or a leading - negates a term or a parenthesised group. AND binds tighter
+// than OR. Values with spaces or parentheses are quoted: title:"oat milk".
+//
+// The package only reads the syntax. Which fields exist and what their
+// values mean is up to whoever compiles the tree.
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comparison operators of a Term. ":" is read as Eq.
+const (
+	Eq = "="
+	Ne = "!="
+	Lt = "<"
+	Le = "<="
+	Gt = ">"
+	Ge = ">="
+)
+
+const (
+	// MaxLength bounds the length of a query, in bytes.
+	MaxLength = 1000
+	// maxDepth bounds the nesting of negations and parentheses.
+	maxDepth = 32
+)
+
+// Expr is a node of a parsed query: *And, *Or, *Not or *Term.
+type Expr interface {
+	expr()
+}
+
+// And matches when both sides match.
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches when either side matches.
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches when Expr does not.
+type Not struct {
+	Expr Expr
+}
+
+// Term compares Field with Value. Field is lower case, and empty for a bare
+// word, which always has Op Eq. Pos is the byte offset of the term in the
+// query.
+type Term struct {
+	Field string
+	Op    string
+	Value string
+	Pos   int
+}
+
+func (*And) expr()  {}
+func (*Or) expr()   {}
+func (*Not) expr()  {}
+func (*Term) expr() {}
+
+// SyntaxError reports a query that cannot be parsed, at byte offset Pos.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Parse parses query. An empty query parses to nil, which matches
+// everything.
+func Parse(query string) (Expr, error) {
+	if len(query) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("query is longer than %d bytes", MaxLength)}
+	}
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, end: len(query)}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	expr, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return expr, nil
+}
+
+// Walk calls fn for every term of expr, from left to right, and stops at the
+// first error.
+func Walk(expr Expr, fn func(*Term) error) error {
+	switch e := expr.(type) {
+	case *And:
+		if err := Walk(e.Left, fn); err != nil {
+			return err
+		}
+		return Walk(e.Right, fn)
+	case *Or:
+		if err := Walk(e.Left, fn); err != nil {
+			return err
+		}
+		return Walk(e.Right, fn)
+	case *Not:
+		return Walk(e.Expr, fn)
+	case *Term:
+		return fn(e)
+	}
+	return nil
+}
+
+const (
+	tokenTerm = iota
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+	term *Term
+}
+
+// lex splits query into parentheses, the keywords AND, OR and NOT, negating
+// dashes and terms.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i})
+			i++
+			continue
+		case c == '-' && i+1 < len(query) && !isSpace(query[i+1]) && query[i+1] != ')':
+			tokens = append(tokens, token{kind: tokenNot, text: "-", pos: i})
+			i++
+			continue
+		}
+		start := i
+		term, n, err := lexTerm(query[i:], i)
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		if term.Field == "" && query[start] != '"' {
+			switch term.Value {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd, text: term.Value, pos: start})
+				continue
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr, text: term.Value, pos: start})
+				continue
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot, text: term.Value, pos: start})
+				continue
+			}
+		}
+		tokens = append(tokens, token{kind: tokenTerm, text: query[start:i], pos: start, term: term})
+	}
+	return tokens, nil
+}
+
+// lexTerm reads the term at the start of s, which is at offset pos of the
+// query, and returns it with its length.
+func lexTerm(s string, pos int) (*Term, int, error) {
+	term := &Term{Op: Eq, Pos: pos}
+	i := 0
+	if s[0] != '"' {
+		for i < len(s) && isFieldChar(s[i], i) {
+			i++
+		}
+		if op := operatorAt(s[i:]); i > 0 && op != "" {
+			term.Field = strings.ToLower(s[:i])
+			term.Op = op
+			if op == ":" {
+				term.Op = Eq
+			}
+			i += len(op)
+		} else {
+			i = 0
+		}
+	}
+	value, n, err := lexValue(s[i:], pos+i)
+	if err != nil {
+		return nil, 0, err
+	}
+	if value == "" && term.Field != "" {
+		return nil, 0, &SyntaxError{Pos: pos + i, Msg: fmt.Sprintf("missing value for %s", term.Field)}
+	}
+	term.Value = value
+	return term, i + n, nil
+}
+
+// lexValue reads a quoted string or a word at the start of s.
+func lexValue(s string, pos int) (string, int, error) {
+	if s == "" || s[0] != '"' {
+		i := 0
+		for i < len(s) && !isSpace(s[i]) && s[i] != '(' && s[i] != ')' && s[i] != '"' {
+			i++
+		}
+		return s[:i], i, nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, &SyntaxError{Pos: pos, Msg: "unterminated quote"}
+}
+
+// operatorAt returns the operator at the start of s, or "".
+func operatorAt(s string) string {
+	for _, op := range []string{"!=", "<=", ">=", ":", "=", "<", ">"} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// isFieldChar reports whether c, at offset i of a term, can be part of a
+// field name such as due or field.12.
+func isFieldChar(c byte, i int) bool {
+	letter := 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+	if i == 0 {
+		return letter
+	}
+	return letter || '0' <= c && c <= '9' || c == '.' || c == '_'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type parser struct {
+	tokens []token
+	next   int
+	end    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.next == len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.next], true
+}
+
+func (p *parser) or(depth int) (Expr, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			return left, nil
+		}
+		p.next++
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+}
+
+func (p *parser) and(depth int) (Expr, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			return left, nil
+		}
+		if tok.kind == tokenAnd {
+			p.next++
+		}
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) unary(depth int) (Expr, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &SyntaxError{Pos: p.end, Msg: "unexpected end of query"}
+	}
+	if depth > maxDepth {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "query is nested too deeply"}
+	}
+	p.next++
+	switch tok.kind {
+	case tokenTerm:
+		return tok.term, nil
+	case tokenNot:
+		expr, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	case tokenOpen:
+		expr, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenClose {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "missing )"}
+		}
+		p.next++
+		return expr, nil
+	}
+	return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
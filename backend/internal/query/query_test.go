@@ -0,0 +1,169 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// show renders expr as an s-expression, with terms as field op "value".
+func show(expr Expr) string {
+	switch e := expr.(type) {
+	case *And:
+		return "(and " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Or:
+		return "(or " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Not:
+		return "(not " + show(e.Expr) + ")"
+	case *Term:
+		return fmt.Sprintf("%s%s%q", e.Field, e.Op, e.Value)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "nil"},
+		{"  \t\n", "nil"},
+		{"milk", `="milk"`},
+		{"is:open", `is="open"`},
+		{"Tag:Urgent", `tag="Urgent"`},
+		{"due<=this-week", `due<="this-week"`},
+		{"priority>=b created<2026-01-01 updated>yesterday", `(and (and priority>="b" created<"2026-01-01") updated>"yesterday")`},
+		{"tag!=home", `tag!="home"`},
+		{"status=doing", `status="doing"`},
+		{"field.12>=3", `field.12>="3"`},
+
+		// Precedence: juxtaposition and AND bind tighter than OR, and NOT
+		// tighter than both.
+		{"a b", `(and ="a" ="b")`},
+		{"a AND b", `(and ="a" ="b")`},
+		{"a b OR c", `(or (and ="a" ="b") ="c")`},
+		{"a OR b c", `(or ="a" (and ="b" ="c"))`},
+		{"a OR b OR c", `(or (or ="a" ="b") ="c")`},
+		{"a AND b OR c AND d", `(or (and ="a" ="b") (and ="c" ="d"))`},
+		{"NOT a b", `(and (not ="a") ="b")`},
+		{"-a OR b", `(or (not ="a") ="b")`},
+		{"NOT NOT a", `(not (not ="a"))`},
+		{"(a OR b) c", `(and (or ="a" ="b") ="c")`},
+		{"-(a OR b) c", `(and (not (or ="a" ="b")) ="c")`},
+		{"a (b OR (c d))", `(and ="a" (or ="b" (and ="c" ="d")))`},
+		{"tag:x(y)", `(and tag="x" ="y")`},
+
+		// Quoting.
+		{`title:"oat milk"`, `title="oat milk"`},
+		{`"oat milk"`, `="oat milk"`},
+		{`title:"say \"hi\" \\ ok"`, `title="say \"hi\" \\ ok"`},
+		{`"AND" OR "NOT"`, `(or ="AND" ="NOT")`},
+		{`list:"a (b) c"`, `list="a (b) c"`},
+		{`title:"a"b`, `(and title="a" ="b")`},
+
+		// Keywords are upper case; other words are terms.
+		{"a and b", `(and (and ="a" ="and") ="b")`},
+		{"pre-order x-y", `(and ="pre-order" ="x-y")`},
+		{"a - b", `(and (and ="a" ="-") ="b")`},
+		{"1:2 :x", `(and ="1:2" =":x")`},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := show(expr); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{`title:"oat`, 6, "unterminated quote"},
+		{`a "b`, 2, "unterminated quote"},
+		{"(a OR b", 0, "missing )"},
+		{"a (b (c)", 2, "missing )"},
+		{"a OR", 4, "unexpected end of query"},
+		{"NOT", 3, "unexpected end of query"},
+		{"a AND", 5, "unexpected end of query"},
+		{"tag:", 4, "missing value for tag"},
+		{`title:""`, 6, "missing value for title"},
+		{"a due< b", 6, "missing value for due"},
+		{"a )", 2, `unexpected ")"`},
+		{"()", 1, `unexpected ")"`},
+		{"OR a", 0, `unexpected "OR"`},
+		{"a AND OR b", 6, `unexpected "OR"`},
+		{strings.Repeat("(", 40) + "a" + strings.Repeat(")", 40), 33, "query is nested too deeply"},
+		{strings.Repeat("-", 40) + "a", 33, "query is nested too deeply"},
+		{strings.Repeat("a ", 501), MaxLength, "query is longer than 1000 bytes"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("Parse(%.20q) = %s, %v, want a syntax error", tt.query, show(expr), err)
+			continue
+		}
+		if syntax.Pos != tt.pos || syntax.Msg != tt.msg {
+			t.Errorf("Parse(%.20q) error = %q at %d, want %q at %d", tt.query, syntax.Msg, syntax.Pos, tt.msg, tt.pos)
+		}
+		if want := fmt.Sprintf("%s at position %d", tt.msg, tt.pos); err.Error() != want {
+			t.Errorf("Error() = %q, want %q", err.Error(), want)
+		}
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	expr, err := Parse(`  milk  tag:"a b" -(due<today)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	_ = Walk(expr, func(term *Term) error {
+		got = append(got, term.Pos)
+		return nil
+	})
+	if want := []int{2, 8, 20}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("term positions = %v, want %v", got, want)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	expr, err := Parse("a (b OR -c) OR d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	err = Walk(expr, func(term *Term) error {
+		seen = append(seen, term.Value)
+		return nil
+	})
+	if err != nil || strings.Join(seen, " ") != "a b c d" {
+		t.Errorf("Walk visited %q, %v, want a b c d", seen, err)
+	}
+
+	stop := errors.New("stop")
+	seen = nil
+	err = Walk(expr, func(term *Term) error {
+		seen = append(seen, term.Value)
+		if term.Value == "b" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || strings.Join(seen, " ") != "a b" {
+		t.Errorf("Walk visited %q, %v, want it to stop after b", seen, err)
+	}
+	if err := Walk(nil, func(*Term) error { return stop }); err != nil {
+		t.Errorf("Walk(nil) = %v", err)
+	}
+}
@@ -0,0 +1,560 @@
+// Package quickadd reads the details of a todo out of a line of text typed
+// into a quick-add field. "Pay rent tomorrow 9am #finance !high every month"
+// is a todo titled "Pay rent", due tomorrow at 9:00, tagged finance, with
+// priority A, repeating monthly.
+//
+// Parsing is deterministic: the result depends only on the text, the
+// current time and the known lists. Words are read from left to right, and
+// the first date, time, recurrence, priority and list found win; later ones
+// stay in the title. Anything that is not recognised is part of the title.
+package quickadd
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kinds of the parts of a text Parse recognises.
+const (
+	KindDate       = "date"
+	KindTime       = "time"
+	KindRecurrence = "recurrence"
+	KindTag        = "tag"
+	KindPriority   = "priority"
+	KindList       = "list"
+)
+
+// Options are what Parse reads a text against.
+type Options struct {
+	// Now is the current time, in the timezone dates and times are read in.
+	Now time.Time
+	// Lists are the names of the lists a +list may name.
+	Lists []string
+}
+
+// Match is a part of the text Parse took out of the title. Start and End
+// are byte offsets into the text.
+type Match struct {
+	Kind  string
+	Text  string
+	Start int
+	End   int
+}
+
+// Result is what Parse found in a text.
+type Result struct {
+	// Title is the text without the parts that were recognised.
+	Title string
+	// Due is in the location of Options.Now, or nil when the text names
+	// neither a date, a time nor a recurrence. A date without a time is due
+	// at its midnight; a time without a date is due at its next occurrence.
+	// A recurring todo without a date is due on its first occurrence from
+	// today.
+	Due *time.Time
+	// RRule is an RFC 5545 recurrence rule such as "FREQ=WEEKLY;BYDAY=MO",
+	// or empty.
+	RRule string
+	// Priority is a letter from A (highest) to Z, or empty.
+	Priority string
+	// Tags are lowercase and distinct, in the order they appear.
+	Tags []string
+	// List is the Options.Lists entry the text names, or empty.
+	List    string
+	Matches []Match
+}
+
+// Parse reads text against opts.
+func Parse(text string, opts Options) Result {
+	now := opts.Now
+	p := &parser{
+		words: split(text),
+		now:   now,
+		today: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		lists: opts.Lists,
+		res:   Result{Tags: []string{}, Matches: []Match{}},
+	}
+	var title []string
+	for i := 0; i < len(p.words); {
+		if n, kind := p.match(p.words[i:]); n > 0 {
+			first, last := p.words[i], p.words[i+n-1]
+			p.res.Matches = append(p.res.Matches, Match{Kind: kind, Text: text[first.start:last.end], Start: first.start, End: last.end})
+			i += n
+			continue
+		}
+		title = append(title, p.words[i].text)
+		i++
+	}
+	p.res.Title = strings.Join(title, " ")
+	p.res.Due = p.due()
+	return p.res
+}
+
+// word is a run of text between spaces. key is the word in lower case,
+// without trailing punctuation, for comparisons.
+type word struct {
+	text, key  string
+	start, end int
+}
+
+func split(text string) []word {
+	var words []word
+	start := -1
+	for i, r := range text + " " {
+		space := r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		switch {
+		case space && start >= 0:
+			w := text[start:i]
+			words = append(words, word{text: w, key: strings.ToLower(strings.TrimRight(w, ",;.")), start: start, end: i})
+			start = -1
+		case !space && start < 0:
+			start = i
+		}
+	}
+	return words
+}
+
+type parser struct {
+	words      []word
+	now, today time.Time
+	lists      []string
+	res        Result
+
+	// date is a midnight, and hour and minute the time of day.
+	date          time.Time
+	hasDate       bool
+	hour, minute  int
+	hasTime       bool
+	hasRecurrence bool
+	// byDay are the days a weekly recurrence falls on.
+	byDay []time.Weekday
+}
+
+// match reads the part of the text starting at words, returning how many
+// words it spans and its kind, or zero when it is not recognised.
+func (p *parser) match(words []word) (int, string) {
+	w := words[0]
+	switch {
+	case len(w.key) > 1 && w.key[0] == '#':
+		if tag := w.key[1:]; strings.ContainsFunc(tag, unicode.IsLetter) && !strings.Contains(tag, "#") {
+			if !slices.Contains(p.res.Tags, tag) {
+				p.res.Tags = append(p.res.Tags, tag)
+			}
+			return 1, KindTag
+		}
+	case len(w.key) > 1 && w.key[0] == '!':
+		if priority, ok := priorities[w.key[1:]]; ok && p.res.Priority == "" {
+			p.res.Priority = priority
+			return 1, KindPriority
+		}
+		if len(w.key) == 2 && w.key[1] >= 'a' && w.key[1] <= 'z' && p.res.Priority == "" {
+			p.res.Priority = strings.ToUpper(w.key[1:])
+			return 1, KindPriority
+		}
+	case len(w.key) > 1 && w.key[0] == '+':
+		if p.res.List == "" {
+			for _, name := range p.lists {
+				if strings.EqualFold(strings.Join(strings.Fields(name), "_"), w.key[1:]) {
+					p.res.List = name
+					return 1, KindList
+				}
+			}
+		}
+	}
+	if !p.hasRecurrence {
+		if n := p.matchRecurrence(words); n > 0 {
+			return n, KindRecurrence
+		}
+	}
+	if !p.hasDate {
+		if n, withTime := p.matchDate(words); n > 0 {
+			if withTime {
+				return n, KindTime
+			}
+			return n, KindDate
+		}
+	}
+	if !p.hasTime {
+		if n := p.matchTime(words); n > 0 {
+			return n, KindTime
+		}
+	}
+	return 0, ""
+}
+
+// priorities maps the words of !high and the like to priority letters.
+var priorities = map[string]string{
+	"urgent": "A",
+	"high":   "A",
+	"1":      "A",
+	"medium": "B",
+	"med":    "B",
+	"2":      "B",
+	"low":    "C",
+	"3":      "C",
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// weekday reads the name of a day. Abbreviations such as "sun" are common
+// words too, so they only count when short is set, after "next", "this" and
+// "every".
+func weekday(key string, short bool) (time.Weekday, bool) {
+	d, ok := weekdays[key]
+	if !ok || (!short && len(key) < 6) {
+		return 0, false
+	}
+	return d, true
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// units maps the units of "every 2 weeks" and "in 3 days" to recurrence
+// frequencies.
+var units = map[string]string{
+	"day": "DAILY", "days": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY",
+}
+
+var dayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// matchRecurrence reads "every day", "every 2 weeks", "every other month",
+// "every weekday", "every weekend" and "every monday, wednesday and friday".
+func (p *parser) matchRecurrence(words []word) int {
+	if len(words) < 2 || words[0].key != "every" {
+		return 0
+	}
+	n, interval := 1, 1
+	if words[n].key == "other" {
+		n, interval = 2, 2
+	} else if v, err := strconv.Atoi(words[n].key); err == nil && v > 0 && v < 1000 {
+		n, interval = 2, v
+	}
+	if n >= len(words) {
+		return 0
+	}
+	key := words[n].key
+	if freq, ok := units[key]; ok && (interval > 1 || !strings.HasSuffix(key, "s")) {
+		p.recur(freq, interval, nil)
+		return n + 1
+	}
+	switch {
+	case interval == 1 && (key == "weekday" || key == "weekdays"):
+		p.recur("WEEKLY", 1, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday})
+		return n + 1
+	case interval == 1 && (key == "weekend" || key == "weekends"):
+		p.recur("WEEKLY", 1, []time.Weekday{time.Saturday, time.Sunday})
+		return n + 1
+	}
+	var days []time.Weekday
+	end := n
+	for i := n; i < len(words); i++ {
+		if d, ok := weekday(words[i].key, true); ok {
+			days = append(days, d)
+			end = i + 1
+		} else if words[i].key != "and" || len(days) == 0 {
+			break
+		}
+	}
+	if len(days) == 0 {
+		return 0
+	}
+	p.recur("WEEKLY", interval, days)
+	return end
+}
+
+func (p *parser) recur(freq string, interval int, days []time.Weekday) {
+	rule := "FREQ=" + freq
+	if interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(interval)
+	}
+	if len(days) > 0 {
+		codes := make([]string, 0, len(days))
+		seen := map[time.Weekday]bool{}
+		for _, d := range days {
+			if !seen[d] {
+				seen[d] = true
+				codes = append(codes, dayCodes[d])
+			}
+		}
+		rule += ";BYDAY=" + strings.Join(codes, ",")
+	}
+	p.res.RRule = rule
+	p.hasRecurrence = true
+	p.byDay = days
+}
+
+// matchDate reads a date, optionally after "on", "due" or "by":
+// "today", "tomorrow", "friday", "next week", "next month", "in 3 days",
+// "oct 25", "25th october 2025" and "2025-10-25". "in 2 hours" and "in 30
+// minutes" are a date and a time, which withTime reports.
+func (p *parser) matchDate(words []word) (n int, withTime bool) {
+	prefix := 0
+	switch words[0].key {
+	case "on", "due", "by":
+		prefix = 1
+	}
+	if prefix >= len(words) {
+		return 0, false
+	}
+	rest := words[prefix:]
+	key := rest[0].key
+	switch key {
+	case "today":
+		p.setDate(p.today)
+		return prefix + 1, false
+	case "tomorrow", "tmrw", "tmr":
+		p.setDate(p.today.AddDate(0, 0, 1))
+		return prefix + 1, false
+	case "next", "this":
+		if len(rest) < 2 {
+			return 0, false
+		}
+		switch next := rest[1].key; {
+		case key == "next" && next == "week":
+			p.setDate(p.nextWeekday(time.Monday))
+		case key == "next" && next == "month":
+			p.setDate(time.Date(p.today.Year(), p.today.Month()+1, 1, 0, 0, 0, 0, p.today.Location()))
+		case key == "next" && next == "year":
+			p.setDate(time.Date(p.today.Year()+1, time.January, 1, 0, 0, 0, 0, p.today.Location()))
+		default:
+			d, ok := weekday(next, true)
+			if !ok {
+				return 0, false
+			}
+			p.setDate(p.nextWeekday(d))
+		}
+		return prefix + 2, false
+	case "in":
+		if n, withTime := p.matchIn(rest[1:]); n > 0 {
+			return prefix + 1 + n, withTime
+		}
+		return 0, false
+	}
+	if d, ok := weekday(key, false); ok {
+		p.setDate(p.nextWeekday(d))
+		return prefix + 1, false
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, key, p.today.Location()); err == nil {
+		p.setDate(date)
+		return prefix + 1, false
+	}
+	if n := p.matchMonthDay(rest); n > 0 {
+		return prefix + n, false
+	}
+	return 0, false
+}
+
+// matchIn reads the "3 days" of "in 3 days", with "a" or "an" for one.
+func (p *parser) matchIn(words []word) (n int, withTime bool) {
+	if len(words) < 2 {
+		return 0, false
+	}
+	count, err := strconv.Atoi(words[0].key)
+	if words[0].key == "a" || words[0].key == "an" {
+		count, err = 1, nil
+	}
+	if err != nil || count < 1 || count > 1000 {
+		return 0, false
+	}
+	switch words[1].key {
+	case "minute", "minutes", "min", "mins":
+		p.setMoment(p.now.Add(time.Duration(count) * time.Minute))
+		return 2, true
+	case "hour", "hours":
+		p.setMoment(p.now.Add(time.Duration(count) * time.Hour))
+		return 2, true
+	}
+	switch units[words[1].key] {
+	case "DAILY":
+		p.setDate(p.today.AddDate(0, 0, count))
+	case "WEEKLY":
+		p.setDate(p.today.AddDate(0, 0, 7*count))
+	case "MONTHLY":
+		p.setDate(p.today.AddDate(0, count, 0))
+	case "YEARLY":
+		p.setDate(p.today.AddDate(count, 0, 0))
+	default:
+		return 0, false
+	}
+	return 2, false
+}
+
+// matchMonthDay reads "oct 25", "october 25th" or "25 oct", optionally
+// followed by a year. Without a year, a day that has already passed this
+// year is next year's.
+func (p *parser) matchMonthDay(words []word) int {
+	if len(words) < 2 {
+		return 0
+	}
+	month, ok := months[words[0].key]
+	day, dayOK := dayOfMonth(words[1].key)
+	if !ok || !dayOK {
+		month, ok = months[words[1].key]
+		day, dayOK = dayOfMonth(words[0].key)
+		if !ok || !dayOK {
+			return 0
+		}
+	}
+	n, year := 2, p.today.Year()
+	explicitYear := false
+	if len(words) > 2 && len(words[2].key) == 4 {
+		if y, err := strconv.Atoi(words[2].key); err == nil {
+			n, year, explicitYear = 3, y, true
+		}
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Day() != day {
+		return 0
+	}
+	if !explicitYear && date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	p.setDate(date)
+	return n
+}
+
+// dayOfMonth reads "25" or "25th".
+func dayOfMonth(key string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		key = strings.TrimSuffix(key, suffix)
+	}
+	day, err := strconv.Atoi(key)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+// matchTime reads a time of day: "9am", "9:30 pm", "21:00", "noon" and
+// "midnight", optionally after "at", and "at 9" for 9:00.
+func (p *parser) matchTime(words []word) int {
+	prefix := 0
+	if words[0].key == "at" {
+		prefix = 1
+	}
+	if prefix >= len(words) {
+		return 0
+	}
+	key := words[prefix].key
+	switch key {
+	case "noon":
+		p.setTime(12, 0)
+		return prefix + 1
+	case "midnight":
+		p.setTime(0, 0)
+		return prefix + 1
+	}
+	n := prefix + 1
+	meridiem := ""
+	if strings.HasSuffix(key, "am") || strings.HasSuffix(key, "pm") {
+		key, meridiem = key[:len(key)-2], key[len(key)-2:]
+	} else if n < len(words) && (words[n].key == "am" || words[n].key == "pm") {
+		meridiem = words[n].key
+		n++
+	}
+	hourText, minuteText, hasMinutes := strings.Cut(key, ":")
+	if meridiem == "" && !hasMinutes && prefix == 0 {
+		return 0
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil || len(hourText) > 2 {
+		return 0
+	}
+	minute := 0
+	if hasMinutes {
+		if minute, err = strconv.Atoi(minuteText); err != nil || len(minuteText) != 2 || minute > 59 {
+			return 0
+		}
+	}
+	switch meridiem {
+	case "":
+		if hour > 23 {
+			return 0
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	p.setTime(hour, minute)
+	return n
+}
+
+func (p *parser) setDate(date time.Time) {
+	p.date, p.hasDate = date, true
+}
+
+func (p *parser) setTime(hour, minute int) {
+	p.hour, p.minute, p.hasTime = hour, minute, true
+}
+
+// setMoment sets the date and time to t, to the minute.
+func (p *parser) setMoment(t time.Time) {
+	p.setDate(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+	p.setTime(t.Hour(), t.Minute())
+}
+
+// nextWeekday is the first day d after today.
+func (p *parser) nextWeekday(d time.Weekday) time.Time {
+	days := (int(d)-int(p.today.Weekday())+6)%7 + 1
+	return p.today.AddDate(0, 0, days)
+}
+
+func (p *parser) at(day time.Time) time.Time {
+	if !p.hasTime {
+		return day
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location())
+}
+
+func (p *parser) due() *time.Time {
+	if p.hasDate {
+		due := p.at(p.date)
+		return &due
+	}
+	if !p.hasTime && !p.hasRecurrence {
+		return nil
+	}
+	// The first day from today, on one of the recurrence's days, with the
+	// time still ahead.
+	day := p.today
+	for i := 0; i < 8; i++ {
+		if p.onRecurrenceDay(day.Weekday()) && (!p.hasTime || p.at(day).After(p.now)) {
+			break
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	due := p.at(day)
+	return &due
+}
+
+func (p *parser) onRecurrenceDay(d time.Weekday) bool {
+	return len(p.byDay) == 0 || slices.Contains(p.byDay, d)
+}
@@ -0,0 +1,162 @@
+package quickadd
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Monday 19 October 2026, 10:00 in Berlin.
+	monday := time.Date(2026, 10, 19, 10, 0, 0, 0, berlin)
+	// Saturday 24 October 2026, the day before Berlin leaves daylight
+	// saving time.
+	beforeDST := time.Date(2026, 10, 24, 10, 0, 0, 0, berlin)
+	lists := []string{"Work Stuff", "Home"}
+
+	const layout = "Mon 2006-01-02 15:04 -0700"
+	tests := []struct {
+		name     string
+		text     string
+		now      time.Time
+		title    string
+		due      string // in layout, empty for none
+		rrule    string
+		priority string
+		tags     []string
+		list     string
+	}{
+		{
+			name:     "everything",
+			text:     "Pay rent tomorrow 9am #finance !high every month",
+			title:    "Pay rent",
+			due:      "Tue 2026-10-20 09:00 +0200",
+			rrule:    "FREQ=MONTHLY",
+			priority: "A",
+			tags:     []string{"finance"},
+		},
+		{name: "plain", text: "buy milk", title: "buy milk"},
+		{name: "today", text: "buy milk today", title: "buy milk", due: "Mon 2026-10-19 00:00 +0200"},
+		{name: "weekday", text: "call mom friday", title: "call mom", due: "Fri 2026-10-23 00:00 +0200"},
+		{name: "same weekday is next week", text: "call mom monday", title: "call mom", due: "Mon 2026-10-26 00:00 +0100"},
+		{name: "next weekday", text: "review next fri at 3pm", title: "review", due: "Fri 2026-10-23 15:00 +0200"},
+		{name: "next week", text: "plan next week", title: "plan", due: "Mon 2026-10-26 00:00 +0100"},
+		{name: "in days", text: "renew in 3 days", title: "renew", due: "Thu 2026-10-22 00:00 +0200"},
+		{name: "in hours", text: "check oven in 2 hours", title: "check oven", due: "Mon 2026-10-19 12:00 +0200"},
+		{name: "month day", text: "party oct 25th", title: "party", due: "Sun 2026-10-25 00:00 +0200"},
+		{name: "past month day is next year", text: "taxes 1 march", title: "taxes", due: "Mon 2027-03-01 00:00 +0100"},
+		{name: "iso date", text: "buy 2026-12-01 milk", title: "buy milk", due: "Tue 2026-12-01 00:00 +0100"},
+		{name: "time ahead is today", text: "lunch at noon", title: "lunch", due: "Mon 2026-10-19 12:00 +0200"},
+		{name: "time passed is tomorrow", text: "coffee 9:30", title: "coffee", due: "Tue 2026-10-20 09:30 +0200"},
+		{name: "pm with space", text: "call at 7 pm", title: "call", due: "Mon 2026-10-19 19:00 +0200"},
+		{
+			name:  "tomorrow across DST",
+			text:  "brunch tomorrow 9am",
+			now:   beforeDST,
+			title: "brunch",
+			due:   "Sun 2026-10-25 09:00 +0100",
+		},
+		{
+			// 24 hours after 10:00 CEST is 9:00 CET.
+			name:  "in hours across DST",
+			text:  "reminder in 24 hours",
+			now:   beforeDST,
+			title: "reminder",
+			due:   "Sun 2026-10-25 09:00 +0100",
+		},
+		{name: "every month", text: "water plants every month", title: "water plants", due: "Mon 2026-10-19 00:00 +0200", rrule: "FREQ=MONTHLY"},
+		{name: "every other week", text: "clean every other week", title: "clean", due: "Mon 2026-10-19 00:00 +0200", rrule: "FREQ=WEEKLY;INTERVAL=2"},
+		{name: "every 3 days", text: "run every 3 days", title: "run", due: "Mon 2026-10-19 00:00 +0200", rrule: "FREQ=DAILY;INTERVAL=3"},
+		{
+			name:  "every weekday with a passed time",
+			text:  "standup every weekday at 9:30",
+			title: "standup",
+			due:   "Tue 2026-10-20 09:30 +0200",
+			rrule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		{
+			name:  "every listed days",
+			text:  "gym every tue and thu",
+			title: "gym",
+			due:   "Tue 2026-10-20 00:00 +0200",
+			rrule: "FREQ=WEEKLY;BYDAY=TU,TH",
+		},
+		{name: "tags", text: "#Home fix #sink #home", title: "fix", tags: []string{"home", "sink"}},
+		{name: "priority letter", text: "!b fix bug", title: "fix bug", priority: "B"},
+		{name: "first priority wins", text: "!high !low fix bug", title: "!low fix bug", priority: "A"},
+		{name: "list", text: "+work_stuff report", title: "report", list: "Work Stuff"},
+		{name: "first list wins", text: "+home +work_stuff report", title: "+work_stuff report", list: "Home"},
+		{name: "unknown list", text: "+garden report", title: "+garden report"},
+		{name: "first date wins", text: "move today tomorrow", title: "move tomorrow", due: "Mon 2026-10-19 00:00 +0200"},
+		{
+			name:  "literal words",
+			text:  "email sun team about may release #1 at the office !!",
+			title: "email sun team about may release #1 at the office !!",
+		},
+		{name: "every alone", text: "every", title: "every"},
+		{name: "in without unit", text: "put it in 3 boxes", title: "put it in 3 boxes"},
+		{name: "hours beyond a day", text: "route 66 at 25:00", title: "route 66 at 25:00"},
+		{name: "invalid date", text: "feb 30 notes", title: "feb 30 notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			if now.IsZero() {
+				now = monday
+			}
+			got := Parse(tt.text, Options{Now: now, Lists: lists})
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			due := ""
+			if got.Due != nil {
+				due = got.Due.Format(layout)
+			}
+			if due != tt.due {
+				t.Errorf("due = %q, want %q", due, tt.due)
+			}
+			if got.RRule != tt.rrule {
+				t.Errorf("rrule = %q, want %q", got.RRule, tt.rrule)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("priority = %q, want %q", got.Priority, tt.priority)
+			}
+			tags := tt.tags
+			if tags == nil {
+				tags = []string{}
+			}
+			if !slices.Equal(got.Tags, tags) {
+				t.Errorf("tags = %q, want %q", got.Tags, tags)
+			}
+			if got.List != tt.list {
+				t.Errorf("list = %q, want %q", got.List, tt.list)
+			}
+		})
+	}
+}
+
+func TestParseMatches(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	text := "Pay rent on oct 25 at 9:30 pm #finance !high every 2 weeks +home"
+	got := Parse(text, Options{Now: now, Lists: []string{"Home"}})
+	want := []Match{
+		{Kind: KindDate, Text: "on oct 25"},
+		{Kind: KindTime, Text: "at 9:30 pm"},
+		{Kind: KindTag, Text: "#finance"},
+		{Kind: KindPriority, Text: "!high"},
+		{Kind: KindRecurrence, Text: "every 2 weeks"},
+		{Kind: KindList, Text: "+home"},
+	}
+	if len(got.Matches) != len(want) {
+		t.Fatalf("matches = %+v, want %+v", got.Matches, want)
+	}
+	for i, m := range got.Matches {
+		if m.Kind != want[i].Kind || m.Text != want[i].Text || text[m.Start:m.End] != m.Text {
+			t.Errorf("match %d = %+v, want %s %q", i, m, want[i].Kind, want[i].Text)
+		}
+	}
+}
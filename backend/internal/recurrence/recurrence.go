@@ -0,0 +1,118 @@
+// Package recurrence expands RFC 5545 RRULE values into concrete occurrences.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// MaxOccurrences caps how many occurrences a single preview may expand.
+const MaxOccurrences = 100
+
+// Rule is a parsed RRULE anchored at a start time in a specific location.
+type Rule struct {
+	rule *rrule.RRule
+	// value is the RRULE as given, without an "RRULE:" prefix.
+	value string
+}
+
+// LoadLocation resolves an IANA timezone name, treating an empty name as UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// Parse parses a single RRULE (with or without the "RRULE:" prefix) and
+// anchors it at start, expanded in the given timezone. DTSTART and other
+// properties are not accepted; the anchor always comes from the todo.
+func Parse(value, timezone string, start time.Time) (*Rule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= len("RRULE:") && strings.EqualFold(value[:len("RRULE:")], "RRULE:") {
+		value = value[len("RRULE:"):]
+	}
+	if value == "" || strings.ContainsAny(value, "\r\n") {
+		return nil, ErrInvalidRule
+	}
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	opt, err := rrule.StrToROptionInLocation(value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	opt.Dtstart = start.In(loc)
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return &Rule{rule: r, value: value}, nil
+}
+
+// Next returns the first occurrence strictly after t. It reports false once
+// the rule is exhausted by COUNT or UNTIL.
+func (r *Rule) Next(t time.Time) (time.Time, bool) {
+	next := r.rule.After(t, false)
+	if next.IsZero() {
+		return time.Time{}, false
+	}
+	return next.UTC(), true
+}
+
+// From returns the RRULE that continues the series from start, one of its
+// occurrences, when start becomes the anchor of the next todo. A COUNT is
+// lowered by the occurrences before start, so the series still ends where it
+// would have; UNTIL and rules without an end are kept as they are.
+func (r *Rule) From(start time.Time) string {
+	count := r.rule.OrigOptions.Count
+	if count == 0 {
+		return r.value
+	}
+	next := r.rule.Iterator()
+	for {
+		t, ok := next()
+		if !ok || !t.Before(start) {
+			break
+		}
+		count--
+	}
+	parts := strings.Split(r.value, ";")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(part, "=")
+		if strings.EqualFold(strings.TrimSpace(name), "COUNT") {
+			parts[i] = "COUNT=" + strconv.Itoa(max(count, 1))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns up to n occurrences strictly after t.
+func (r *Rule) Occurrences(t time.Time, n int) []time.Time {
+	if n > MaxOccurrences {
+		n = MaxOccurrences
+	}
+	var out []time.Time
+	for len(out) < n {
+		next, ok := r.Next(t)
+		if !ok {
+			break
+		}
+		out = append(out, next)
+		t = next
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, value, timezone string, start time.Time) *Rule {
+	t.Helper()
+	r, err := Parse(value, timezone, start)
+	if err != nil {
+		t.Fatalf("Parse(%q, %q): %v", value, timezone, err)
+	}
+	return r
+}
+
+func date(y int, m time.Month, d, hh, mm int, loc *time.Location) time.Time {
+	return time.Date(y, m, d, hh, mm, 0, 0, loc)
+}
+
+func TestParseInvalid(t *testing.T) {
+	start := date(2026, 11, 1, 9, 0, time.UTC)
+	tests := []struct {
+		value, timezone string
+		want            error
+	}{
+		{"", "", ErrInvalidRule},
+		{"FREQ=SOMETIMES", "", ErrInvalidRule},
+		{"FREQ=DAILY\r\nDTSTART:20260101T000000Z", "", ErrInvalidRule},
+		{"FREQ=DAILY", "Mars/Olympus_Mons", ErrInvalidTimezone},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.value, tt.timezone, start); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q, %q) = %v, want %v", tt.value, tt.timezone, err, tt.want)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	ny, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		value    string
+		timezone string
+		start    time.Time
+		after    time.Time
+		n        int
+		want     []time.Time
+	}{
+		{
+			name:  "count includes the anchor",
+			value: "FREQ=DAILY;COUNT=3",
+			start: date(2026, 11, 1, 9, 0, time.UTC),
+			after: date(2026, 11, 1, 9, 0, time.UTC),
+			n:     10,
+			want:  []time.Time{date(2026, 11, 2, 9, 0, time.UTC), date(2026, 11, 3, 9, 0, time.UTC)},
+		},
+		{
+			name:  "until is inclusive",
+			value: "FREQ=WEEKLY;UNTIL=20261115T090000Z",
+			start: date(2026, 11, 1, 9, 0, time.UTC),
+			after: date(2026, 11, 1, 9, 0, time.UTC),
+			n:     10,
+			want:  []time.Time{date(2026, 11, 8, 9, 0, time.UTC), date(2026, 11, 15, 9, 0, time.UTC)},
+		},
+		{
+			name:  "prefix and byday",
+			value: "RRULE:FREQ=WEEKLY;BYDAY=MO,FR",
+			start: date(2026, 10, 19, 8, 0, time.UTC), // a Monday
+			after: date(2026, 10, 19, 8, 0, time.UTC),
+			n:     3,
+			want:  []time.Time{date(2026, 10, 23, 8, 0, time.UTC), date(2026, 10, 26, 8, 0, time.UTC), date(2026, 10, 30, 8, 0, time.UTC)},
+		},
+		{
+			// New York leaves daylight saving time on 1 November 2026; 9:00
+			// local is 13:00 UTC before and 14:00 UTC after.
+			name:     "wall clock kept across DST",
+			value:    "FREQ=DAILY",
+			timezone: "America/New_York",
+			start:    date(2026, 10, 31, 9, 0, ny),
+			after:    date(2026, 10, 31, 9, 0, ny),
+			n:        2,
+			want:     []time.Time{date(2026, 11, 1, 14, 0, time.UTC), date(2026, 11, 2, 14, 0, time.UTC)},
+		},
+		{
+			name:     "UTC anchor in another zone",
+			value:    "FREQ=DAILY",
+			timezone: "America/New_York",
+			start:    date(2026, 10, 31, 13, 0, time.UTC), // 9:00 in New York
+			after:    date(2026, 10, 31, 13, 0, time.UTC),
+			n:        1,
+			want:     []time.Time{date(2026, 11, 1, 14, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mustParse(t, tt.value, tt.timezone, tt.start)
+			got := r.Occurrences(tt.after, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) || got[i].Location() != time.UTC {
+					t.Errorf("occurrence %d = %v, want %v in UTC", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesCapped(t *testing.T) {
+	r := mustParse(t, "FREQ=DAILY", "", date(2026, 1, 1, 0, 0, time.UTC))
+	if got := len(r.Occurrences(date(2026, 1, 1, 0, 0, time.UTC), MaxOccurrences+50)); got != MaxOccurrences {
+		t.Errorf("len(Occurrences) = %d, want %d", got, MaxOccurrences)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	start := date(2026, 11, 1, 9, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		next  time.Time
+		want  string
+	}{
+		{"no end", "FREQ=DAILY", date(2026, 11, 2, 9, 0, time.UTC), "FREQ=DAILY"},
+		{"until kept", "FREQ=DAILY;UNTIL=20261110T000000Z", date(2026, 11, 5, 9, 0, time.UTC), "FREQ=DAILY;UNTIL=20261110T000000Z"},
+		{"count lowered by one", "FREQ=DAILY;COUNT=2", date(2026, 11, 2, 9, 0, time.UTC), "FREQ=DAILY;COUNT=1"},
+		{"skipped occurrences count", "FREQ=DAILY;COUNT=5;INTERVAL=1", date(2026, 11, 4, 9, 0, time.UTC), "FREQ=DAILY;COUNT=2;INTERVAL=1"},
+		{"prefix dropped", "rrule:COUNT=3;FREQ=WEEKLY", date(2026, 11, 8, 9, 0, time.UTC), "COUNT=2;FREQ=WEEKLY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParse(t, tt.value, "", start).From(tt.next); got != tt.want {
+				t.Errorf("From = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFromEndsSeries completes every occurrence of a series in turn, each
+// time anchoring the rule at the new due date as the service does, and
+// checks that it stops after COUNT occurrences.
+func TestFromEndsSeries(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"FREQ=DAILY;COUNT=1", 1},
+		{"FREQ=DAILY;COUNT=2", 2},
+		{"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5", 5},
+		{"FREQ=DAILY;UNTIL=20261105T235959Z", 4},
+	}
+	for _, tt := range tests {
+		due := date(2026, 11, 2, 9, 0, time.UTC) // a Monday
+		value := tt.value
+		seen := 1
+		for ; seen <= MaxOccurrences; seen++ {
+			next, ok := mustParse(t, value, "Europe/Berlin", due).Next(due)
+			if !ok {
+				break
+			}
+			value = mustParse(t, value, "Europe/Berlin", due).From(next)
+			due = next
+		}
+		if seen != tt.want {
+			t.Errorf("%s: series had %d occurrences, want %d", tt.value, seen, tt.want)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := LoadLocation(""); err != nil || loc != time.UTC {
+		t.Errorf("LoadLocation(\"\") = %v, %v, want UTC", loc, err)
+	}
+	if loc, err := LoadLocation("Europe/Tallinn"); err != nil || loc.String() != "Europe/Tallinn" {
+		t.Errorf("LoadLocation(Europe/Tallinn) = %v, %v", loc, err)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// CreateAppPassword stores p for userID under passwordHash.
+func (db *DB) CreateAppPassword(ctx context.Context, userID int64, p *model.AppPassword, passwordHash string) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO app_passwords (user_id, name, password_hash, created_at) VALUES (?, ?, ?, ?)`,
+		userID, p.Name, passwordHash, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	p.CreatedAt = now
+	return nil
+}
+
+// ListAppPasswords returns userID's app passwords, oldest first.
+func (db *DB) ListAppPasswords(ctx context.Context, userID int64) ([]model.AppPassword, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, name, created_at, last_used_at FROM app_passwords WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passwords := []model.AppPassword{}
+	for rows.Next() {
+		var (
+			p                   model.AppPassword
+			createdAt, lastUsed any
+		)
+		if err := rows.Scan(&p.ID, &p.Name, &createdAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		p.CreatedAt, _ = parseTime(createdAt)
+		if t, ok := parseTime(lastUsed); ok {
+			p.LastUsedAt = &t
+		}
+		passwords = append(passwords, p)
+	}
+	return passwords, rows.Err()
+}
+
+// DeleteAppPassword revokes one of userID's app passwords.
+func (db *DB) DeleteAppPassword(ctx context.Context, userID, id int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM app_passwords WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// appPasswordUseInterval is how stale last_used_at may get before a use of
+// the app password writes it again. Sync clients authenticate every request,
+// and rewriting the row each time would serialize them on the database.
+const appPasswordUseInterval = time.Minute
+
+// UseAppPassword returns the ID of the user called username who owns the app
+// password with passwordHash, recording that it was used unless it already
+// was within appPasswordUseInterval.
+func (db *DB) UseAppPassword(ctx context.Context, username, passwordHash string) (int64, error) {
+	var id, userID int64
+	err := db.QueryRowContext(ctx, `SELECT p.id, p.user_id FROM app_passwords p JOIN users u ON u.id = p.user_id WHERE u.username = ? AND p.password_hash = ?`,
+		username, passwordHash).Scan(&id, &userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	_, err = db.ExecContext(ctx, `UPDATE app_passwords SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`,
+		formatTime(now), id, formatTime(now.Add(-appPasswordUseInterval)))
+	return userID, err
+}
@@ -0,0 +1,62 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestUseAppPassword(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	userID, _ := newTestList(t, db, "alice")
+	p := &model.AppPassword{Name: "phone"}
+	if err := db.CreateAppPassword(ctx, userID, p, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := func() time.Time {
+		t.Helper()
+		passwords, err := db.ListAppPasswords(ctx, userID)
+		if err != nil || len(passwords) != 1 || passwords[0].LastUsedAt == nil {
+			t.Fatalf("ListAppPasswords = %+v, %v, want one used password", passwords, err)
+		}
+		return *passwords[0].LastUsedAt
+	}
+
+	if _, err := db.UseAppPassword(ctx, "alice", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UseAppPassword with a wrong hash = %v, want ErrNotFound", err)
+	}
+	if _, err := db.UseAppPassword(ctx, "bob", "hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UseAppPassword for another user = %v, want ErrNotFound", err)
+	}
+	got, err := db.UseAppPassword(ctx, "alice", "hash")
+	if err != nil || got != userID {
+		t.Fatalf("UseAppPassword = %d, %v, want %d", got, err, userID)
+	}
+	first := lastUsed()
+
+	// A second use straight away leaves last_used_at alone.
+	if _, err := db.UseAppPassword(ctx, "alice", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if second := lastUsed(); !second.Equal(first) {
+		t.Errorf("last_used_at moved from %v to %v within a minute", first, second)
+	}
+
+	// Once it is older than appPasswordUseInterval a use records it again.
+	stale := first.Add(-2 * appPasswordUseInterval)
+	if _, err := db.ExecContext(ctx, `UPDATE app_passwords SET last_used_at = ?`, formatTime(stale)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UseAppPassword(ctx, "alice", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if third := lastUsed(); !third.After(stale.Add(appPasswordUseInterval)) {
+		t.Errorf("last_used_at = %v, want it refreshed from %v", third, stale)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const attachmentColumns = `a.id, a.todo_id, a.user_id, a.filename, a.content_type, a.size, a.storage_key, a.created_at`
+
+func (db *DB) CreateAttachment(ctx context.Context, a *model.Attachment) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO attachments (todo_id, user_id, filename, content_type, size, storage_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.TodoID, a.UserID, a.Filename, a.ContentType, a.Size, a.StorageKey, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	a.CreatedAt = now
+	return nil
+}
+
+func (db *DB) ListAttachments(ctx context.Context, todoID int64) ([]model.Attachment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments a WHERE a.todo_id = ? ORDER BY a.id`, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (db *DB) GetAttachment(ctx context.Context, todoID, attachmentID int64) (*model.Attachment, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+attachmentColumns+` FROM attachments a WHERE a.id = ? AND a.todo_id = ?`, attachmentID, todoID)
+	return scanAttachment(row)
+}
+
+func (db *DB) DeleteAttachment(ctx context.Context, todoID, attachmentID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ? AND todo_id = ?`, attachmentID, todoID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AttachmentBytesByUser sums the size of every attachment uploaded by userID.
+func (db *DB) AttachmentBytesByUser(ctx context.Context, userID int64) (int64, error) {
+	var total int64
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = ?`, userID).Scan(&total)
+	return total, err
+}
+
+// ListOrphanedBlobs returns storage keys whose attachment rows have been
+// deleted but whose blobs have not been removed yet.
+func (db *DB) ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT storage_key FROM orphaned_blobs ORDER BY created_at LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (db *DB) DeleteOrphanedBlob(ctx context.Context, key string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM orphaned_blobs WHERE storage_key = ?`, key)
+	return err
+}
+
+func scanAttachment(scanner rowScanner) (*model.Attachment, error) {
+	var (
+		a         model.Attachment
+		createdAt any
+	)
+	if err := scanner.Scan(&a.ID, &a.TodoID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &createdAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if parsed, ok := parseTime(createdAt); ok {
+		a.CreatedAt = parsed
+	}
+	return &a, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const commentColumns = `c.id, c.todo_id, c.user_id, u.username, c.body, c.created_at, c.edited_at`
+
+// ListComments returns up to limit comments of todoID with IDs greater than
+// afterID, oldest first.
+func (db *DB) ListComments(ctx context.Context, todoID, afterID int64, limit int) ([]model.Comment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+commentColumns+` FROM comments c JOIN users u ON u.id = c.user_id
+		WHERE c.todo_id = ? AND c.id > ? ORDER BY c.id LIMIT ?`, todoID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []model.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (db *DB) GetComment(ctx context.Context, todoID, commentID int64) (*model.Comment, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments c JOIN users u ON u.id = c.user_id
+		WHERE c.id = ? AND c.todo_id = ?`, commentID, todoID)
+	return scanComment(row)
+}
+
+func (db *DB) CreateComment(ctx context.Context, c *model.Comment) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO comments (todo_id, user_id, body, created_at) VALUES (?, ?, ?, ?)`,
+		c.TodoID, c.Author.ID, c.Body, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = id
+	c.CreatedAt = now
+	return db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = ?`, c.Author.ID).Scan(&c.Author.Username)
+}
+
+func (db *DB) UpdateComment(ctx context.Context, c *model.Comment) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `UPDATE comments SET body = ?, edited_at = ? WHERE id = ? AND todo_id = ?`,
+		c.Body, formatTime(now), c.ID, c.TodoID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	c.EditedAt = &now
+	return nil
+}
+
+func (db *DB) DeleteComment(ctx context.Context, todoID, commentID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM comments WHERE id = ? AND todo_id = ?`, commentID, todoID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanComment(scanner rowScanner) (*model.Comment, error) {
+	var (
+		c         model.Comment
+		createdAt any
+		editedAt  any
+	)
+	if err := scanner.Scan(&c.ID, &c.TodoID, &c.Author.ID, &c.Author.Username, &c.Body, &createdAt, &editedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if parsed, ok := parseTime(createdAt); ok {
+		c.CreatedAt = parsed
+	}
+	if parsed, ok := parseTime(editedAt); ok {
+		c.EditedAt = &parsed
+	}
+	return &c, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -8,6 +9,7 @@ import (
 
 type DB struct {
 	*sql.DB
+	tx *sql.Tx
 }
 
 func NewDB(dataSourceName string) (*DB, error) {
@@ -18,5 +20,65 @@ func NewDB(dataSourceName string) (*DB, error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
-	return &DB{db}, nil
+	return &DB{DB: db}, nil
+}
+
+// WithTx runs fn against a copy of db bound to a single transaction. The
+// transaction is committed when fn returns nil and rolled back otherwise.
+// Calling WithTx on a DB that is already inside a transaction reuses it.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	sqlTx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&DB{DB: db.DB, tx: sqlTx}); err != nil {
+		_ = sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// WithSavepoint runs fn inside a savepoint of the current transaction, so
+// that when fn fails only its own changes are rolled back. Outside of a
+// transaction it behaves like WithTx.
+func (db *DB) WithSavepoint(ctx context.Context, fn func(tx *DB) error) error {
+	if db.tx == nil {
+		return db.WithTx(ctx, fn)
+	}
+	if _, err := db.tx.ExecContext(ctx, `SAVEPOINT sp`); err != nil {
+		return err
+	}
+	if err := fn(db); err != nil {
+		if _, rbErr := db.tx.ExecContext(ctx, `ROLLBACK TO sp`); rbErr != nil {
+			return rbErr
+		}
+		_, _ = db.tx.ExecContext(ctx, `RELEASE sp`)
+		return err
+	}
+	_, err := db.tx.ExecContext(ctx, `RELEASE sp`)
+	return err
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.ExecContext(ctx, query, args...)
+	}
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.QueryContext(ctx, query, args...)
+	}
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if db.tx != nil {
+		return db.tx.QueryRowContext(ctx, query, args...)
+	}
+	return db.DB.QueryRowContext(ctx, query, args...)
 }
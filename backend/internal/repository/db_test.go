@@ -0,0 +1,40 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/migrations"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// newTestDB opens a migrated database in a temporary directory.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB("file:" + t.TempDir() + "/test.db?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Up(db.DB); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestList creates a user named username and a list they own.
+func newTestList(t *testing.T, db *DB, username string) (userID, listID int64) {
+	t.Helper()
+	ctx := context.Background()
+	user := &model.User{Username: username, PasswordHash: "x"}
+	if err := db.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	list := &model.List{Name: username + "'s list", CreatedBy: user.ID}
+	if err := db.CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, list.ID
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// BeginIdempotentRequest reserves key for userID until lease passes, first
+// dropping expired keys, among them those of requests whose lease ran out
+// before they completed. When the key is already taken it returns what was
+// recorded for it instead.
+func (db *DB) BeginIdempotentRequest(ctx context.Context, userID int64, key, fingerprint string, lease time.Duration) (*model.IdempotentResponse, error) {
+	now := time.Now().UTC()
+	if _, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, formatTime(now)); err != nil {
+		return nil, err
+	}
+	res, err := db.ExecContext(ctx, `INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, key) DO NOTHING`,
+		userID, key, fingerprint, formatTime(now), formatTime(now.Add(lease)))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 1 {
+		return nil, err
+	}
+
+	var (
+		recorded model.IdempotentResponse
+		header   []byte
+	)
+	err = db.QueryRowContext(ctx, `SELECT fingerprint, status, header, body FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key).
+		Scan(&recorded.Fingerprint, &recorded.Status, &header, &recorded.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &recorded.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &recorded, nil
+}
+
+// RenewIdempotentRequest extends the reservation of key, which is still in
+// progress, until lease passes.
+func (db *DB) RenewIdempotentRequest(ctx context.Context, userID int64, key string, lease time.Duration) error {
+	_, err := db.ExecContext(ctx, `UPDATE idempotency_keys SET expires_at = ? WHERE user_id = ? AND key = ? AND status = 0`,
+		formatTime(time.Now().UTC().Add(lease)), userID, key)
+	return err
+}
+
+// CompleteIdempotentRequest records the response to replay for key, and
+// keeps it until ttl passes.
+func (db *DB) CompleteIdempotentRequest(ctx context.Context, userID int64, key string, resp *model.IdempotentResponse, ttl time.Duration) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `UPDATE idempotency_keys SET status = ?, header = ?, body = ?, expires_at = ? WHERE user_id = ? AND key = ?`,
+		resp.Status, string(header), resp.Body, formatTime(time.Now().UTC().Add(ttl)), userID, key)
+	return err
+}
+
+// ReleaseIdempotentRequest forgets key so that the request can be retried.
+func (db *DB) ReleaseIdempotentRequest(ctx context.Context, userID int64, key string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key)
+	return err
+}
@@ -0,0 +1,64 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestIdempotentRequestLease(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	const lease = 50 * time.Millisecond
+
+	begin := func(key string) *model.IdempotentResponse {
+		t.Helper()
+		recorded, err := db.BeginIdempotentRequest(ctx, 1, key, "fp", lease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return recorded
+	}
+
+	// A request that never completes frees its key once the lease is over.
+	if begin("crashed") != nil {
+		t.Fatal("new key was already taken")
+	}
+	if recorded := begin("crashed"); recorded == nil || recorded.Status != 0 {
+		t.Fatalf("key within the lease = %+v, want it in progress", recorded)
+	}
+	time.Sleep(2 * lease)
+	if recorded := begin("crashed"); recorded != nil {
+		t.Fatalf("key after the lease = %+v, want it free", recorded)
+	}
+
+	// Renewing keeps a running request's key.
+	begin("running")
+	for range 3 {
+		time.Sleep(lease / 2)
+		if err := db.RenewIdempotentRequest(ctx, 1, "running", lease); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recorded := begin("running"); recorded == nil {
+		t.Fatal("renewed key was freed")
+	}
+
+	// A completed request is kept for its ttl, well past the lease.
+	resp := &model.IdempotentResponse{Status: 201, Header: map[string][]string{"Location": {"/x"}}, Body: []byte("ok")}
+	if err := db.CompleteIdempotentRequest(ctx, 1, "running", resp, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RenewIdempotentRequest(ctx, 1, "running", lease); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * lease)
+	recorded := begin("running")
+	if recorded == nil || recorded.Status != 201 || string(recorded.Body) != "ok" || recorded.Header.Get("Location") != "/x" {
+		t.Errorf("completed key = %+v, want the recorded response", recorded)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const importJobColumns = `id, format, filename, list_id, dry_run, status, total, processed, created, updated, unchanged, failed, error, created_at, finished_at`
+
+// CreateImportJob stores job for userID.
+func (db *DB) CreateImportJob(ctx context.Context, userID int64, job *model.ImportJob) error {
+	items, err := json.Marshal(job.Items)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO import_jobs (user_id, format, filename, list_id, dry_run, status, total, items, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, job.Format, job.Filename, job.ListID, job.DryRun, job.Status, job.Total, string(items), formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	job.ID = id
+	job.CreatedAt = now
+	return nil
+}
+
+// UpdateImportJob saves the status, progress and items of job.
+func (db *DB) UpdateImportJob(ctx context.Context, job *model.ImportJob) error {
+	items, err := json.Marshal(job.Items)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `UPDATE import_jobs SET status = ?, processed = ?, created = ?, updated = ?, unchanged = ?, failed = ?, error = ?, items = ?, finished_at = ? WHERE id = ?`,
+		job.Status, job.Processed, job.Created, job.Updated, job.Unchanged, job.Failed, job.Error, string(items), nullTime(job.FinishedAt), job.ID)
+	return err
+}
+
+// GetImportJob returns one of userID's import jobs with its items.
+func (db *DB) GetImportJob(ctx context.Context, userID, id int64) (*model.ImportJob, error) {
+	var items string
+	job, err := scanImportJob(db.QueryRowContext(ctx, `SELECT `+importJobColumns+`, items FROM import_jobs WHERE id = ? AND user_id = ?`, id, userID), &items)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(items), &job.Items); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListImportJobs returns userID's import jobs, newest first, without their
+// items.
+func (db *DB) ListImportJobs(ctx context.Context, userID int64) ([]model.ImportJob, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+importJobColumns+` FROM import_jobs WHERE user_id = ? ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []model.ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// FailUnfinishedImportJobs marks the jobs that were pending or running when
+// the server stopped as failed with reason.
+func (db *DB) FailUnfinishedImportJobs(ctx context.Context, reason string) error {
+	_, err := db.ExecContext(ctx, `UPDATE import_jobs SET status = ?, error = ?, finished_at = ? WHERE status IN (?, ?)`,
+		model.ImportJobFailed, reason, formatTime(time.Now().UTC()), model.ImportJobPending, model.ImportJobRunning)
+	return err
+}
+
+func scanImportJob(scanner rowScanner, extra ...any) (*model.ImportJob, error) {
+	var (
+		job                   model.ImportJob
+		listID                sql.NullInt64
+		createdAt, finishedAt any
+	)
+	dest := append([]any{&job.ID, &job.Format, &job.Filename, &listID, &job.DryRun, &job.Status, &job.Total, &job.Processed,
+		&job.Created, &job.Updated, &job.Unchanged, &job.Failed, &job.Error, &createdAt, &finishedAt}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if listID.Valid {
+		job.ListID = &listID.Int64
+	}
+	job.CreatedAt, _ = parseTime(createdAt)
+	if t, ok := parseTime(finishedAt); ok {
+		job.FinishedAt = &t
+	}
+	return &job, nil
+}
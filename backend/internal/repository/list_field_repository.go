@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const listFieldColumns = `f.id, f.list_id, f.name, f.type, f.options, f.created_at`
+
+// todoFieldValues is an SQL expression building the JSON object of the
+// custom field values of the todo t, keyed by field ID.
+const todoFieldValues = `(SELECT json_group_object(fv.field_id, json(fv.value)) FROM todo_field_values fv WHERE fv.todo_id = t.id)`
+
+// ListFields returns the custom fields of listID, oldest first.
+func (db *DB) ListFields(ctx context.Context, listID int64) ([]model.ListField, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+listFieldColumns+` FROM list_fields f WHERE f.list_id = ? ORDER BY f.id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []model.ListField{}
+	for rows.Next() {
+		field, err := scanListField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, *field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// GetListField returns fieldID when userID is a member of its list.
+func (db *DB) GetListField(ctx context.Context, userID, fieldID int64) (*model.ListField, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+listFieldColumns+` FROM list_fields f
+		JOIN list_members m ON m.list_id = f.list_id AND m.user_id = ?
+		WHERE f.id = ?`, userID, fieldID)
+	return scanListField(row)
+}
+
+// CreateListField inserts field. A name already used in the list fails with
+// a UNIQUE constraint error.
+func (db *DB) CreateListField(ctx context.Context, field *model.ListField) error {
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO list_fields (list_id, name, type, options, created_at) VALUES (?, ?, ?, ?, ?)`,
+		field.ListID, field.Name, field.Type, string(options), formatTime(now))
+	if err != nil {
+		return err
+	}
+	if field.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	field.CreatedAt = now
+	return nil
+}
+
+// UpdateListField saves the name and options of field.
+func (db *DB) UpdateListField(ctx context.Context, field *model.ListField) error {
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, `UPDATE list_fields SET name = ?, options = ? WHERE id = ? AND list_id = ?`,
+		field.Name, string(options), field.ID, field.ListID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteListField deletes fieldID of listID along with its values.
+func (db *DB) DeleteListField(ctx context.Context, listID, fieldID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM list_fields WHERE id = ? AND list_id = ?`, fieldID, listID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountFieldValue counts the todos, including those in the trash, whose
+// value of fieldID is value.
+func (db *DB) CountFieldValue(ctx context.Context, fieldID int64, value any) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todo_field_values WHERE field_id = ? AND json_extract(value, '$') = ?`,
+		fieldID, value).Scan(&n)
+	return n, err
+}
+
+// saveTodoFields replaces the custom field values of a todo that was just
+// written with todo.Fields.
+func (db *DB) saveTodoFields(ctx context.Context, todo *model.Todo) error {
+	if todo.Fields == nil {
+		todo.Fields = map[int64]any{}
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM todo_field_values WHERE todo_id = ?`, todo.ID); err != nil {
+		return err
+	}
+	for fieldID, value := range todo.Fields {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `INSERT INTO todo_field_values (todo_id, field_id, value) VALUES (?, ?, ?)`,
+			todo.ID, fieldID, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanListField(scanner rowScanner) (*model.ListField, error) {
+	var (
+		f         model.ListField
+		options   string
+		createdAt any
+	)
+	if err := scanner.Scan(&f.ID, &f.ListID, &f.Name, &f.Type, &options, &createdAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(options), &f.Options); err != nil || f.Options == nil {
+		f.Options = []string{}
+	}
+	if parsed, ok := parseTime(createdAt); ok {
+		f.CreatedAt = parsed
+	}
+	return &f, nil
+}
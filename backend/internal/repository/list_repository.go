@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// roleAtLeast is an SQL predicate on a list_members alias m, keyed by the
+// minimum role it accepts.
+var roleAtLeast = map[string]string{
+	model.RoleViewer: `m.role IN ('viewer', 'editor', 'owner')`,
+	model.RoleEditor: `m.role IN ('editor', 'owner')`,
+	model.RoleOwner:  `m.role = 'owner'`,
+}
+
+// listColumns reads the requesting user's membership from a joined list_members m.
+const listColumns = `l.id, l.name, l.is_default AND l.created_by = m.user_id, l.created_by, m.role, l.created_at, l.enforce_dependencies`
+
+func (db *DB) ListListsByUser(ctx context.Context, userID int64) ([]model.List, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+listColumns+` FROM lists l
+		JOIN list_members m ON m.list_id = l.id AND m.user_id = ?
+		ORDER BY l.is_default DESC, l.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []model.List{}
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// GetList returns listID as seen by userID, or ErrNotFound when userID is not
+// a member.
+func (db *DB) GetList(ctx context.Context, userID, listID int64) (*model.List, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+listColumns+` FROM lists l
+		JOIN list_members m ON m.list_id = l.id AND m.user_id = ?
+		WHERE l.id = ?`, userID, listID)
+	return scanList(row)
+}
+
+// GetDefaultList returns the list new todos of userID go to by default.
+func (db *DB) GetDefaultList(ctx context.Context, userID int64) (*model.List, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+listColumns+` FROM lists l
+		JOIN list_members m ON m.list_id = l.id AND m.user_id = ?
+		WHERE l.created_by = m.user_id AND l.is_default = 1
+		ORDER BY l.id LIMIT 1`, userID)
+	return scanList(row)
+}
+
+// DefaultListOwner returns the user whose default list listID is, or zero
+// when it is an ordinary list.
+func (db *DB) DefaultListOwner(ctx context.Context, listID int64) (int64, error) {
+	var createdBy int64
+	var isDefault int
+	err := db.QueryRowContext(ctx, `SELECT created_by, is_default FROM lists WHERE id = ?`, listID).Scan(&createdBy, &isDefault)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil || isDefault == 0 {
+		return 0, err
+	}
+	return createdBy, nil
+}
+
+// CreateList inserts a list and makes its creator the owner.
+func (db *DB) CreateList(ctx context.Context, list *model.List) error {
+	return db.WithTx(ctx, func(tx *DB) error {
+		now := time.Now().UTC()
+		res, err := tx.ExecContext(ctx, `INSERT INTO lists (name, created_by, created_at) VALUES (?, ?, ?)`,
+			list.Name, list.CreatedBy, formatTime(now))
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO list_members (list_id, user_id, role, created_at) VALUES (?, ?, 'owner', ?)`,
+			id, list.CreatedBy, formatTime(now)); err != nil {
+			return err
+		}
+		list.ID = id
+		list.Role = model.RoleOwner
+		list.CreatedAt = now
+		return nil
+	})
+}
+
+// UpdateList saves the name and settings of list when userID owns it.
+func (db *DB) UpdateList(ctx context.Context, userID int64, list *model.List) error {
+	res, err := db.ExecContext(ctx, `UPDATE lists SET name = ?, enforce_dependencies = ? WHERE id = ? AND id IN (
+		SELECT m.list_id FROM list_members m WHERE m.user_id = ? AND `+roleAtLeast[model.RoleOwner]+`)`,
+		list.Name, boolToInt(list.EnforceDependencies), list.ID, userID)
+	if err != nil {
+		return err
+	}
+	return db.listAccessResult(ctx, res, userID, list.ID)
+}
+
+// DeleteList deletes listID, and with it its todos, when userID owns it.
+func (db *DB) DeleteList(ctx context.Context, userID, listID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM lists WHERE id = ? AND id IN (
+		SELECT m.list_id FROM list_members m WHERE m.user_id = ? AND `+roleAtLeast[model.RoleOwner]+`)`,
+		listID, userID)
+	if err != nil {
+		return err
+	}
+	return db.listAccessResult(ctx, res, userID, listID)
+}
+
+// ListRole returns userID's role on listID, or ErrNotFound when userID is not
+// a member.
+func (db *DB) ListRole(ctx context.Context, userID, listID int64) (string, error) {
+	var role string
+	err := db.QueryRowContext(ctx, `SELECT role FROM list_members WHERE list_id = ? AND user_id = ?`, listID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+func (db *DB) ListMembers(ctx context.Context, listID int64) ([]model.ListMember, error) {
+	rows, err := db.QueryContext(ctx, `SELECT m.list_id, u.id, u.username, m.role, m.created_at FROM list_members m
+		JOIN users u ON u.id = m.user_id WHERE m.list_id = ? ORDER BY m.created_at, u.id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.ListMember{}
+	for rows.Next() {
+		var (
+			member    model.ListMember
+			createdAt any
+		)
+		if err := rows.Scan(&member.ListID, &member.User.ID, &member.User.Username, &member.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		if parsed, ok := parseTime(createdAt); ok {
+			member.CreatedAt = parsed
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// ListMemberIDs returns the IDs of every member of listID.
+func (db *DB) ListMemberIDs(ctx context.Context, listID int64) ([]int64, error) {
+	return db.queryIDs(ctx, `SELECT user_id FROM list_members WHERE list_id = ?`, listID)
+}
+
+// SetListMember adds userID to listID with role, or changes the role of an
+// existing member.
+func (db *DB) SetListMember(ctx context.Context, listID, userID int64, role string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO list_members (list_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (list_id, user_id) DO UPDATE SET role = excluded.role`,
+		listID, userID, role, formatTime(time.Now()))
+	return err
+}
+
+func (db *DB) RemoveListMember(ctx context.Context, listID, userID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM list_members WHERE list_id = ? AND user_id = ?`, listID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (db *DB) CountListOwners(ctx context.Context, listID int64) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM list_members WHERE list_id = ? AND role = 'owner'`, listID).Scan(&n)
+	return n, err
+}
+
+// listAccessResult turns a write that matched no rows into ErrNotFound when
+// userID cannot see listID, or ErrForbidden when their role is too low.
+func (db *DB) listAccessResult(ctx context.Context, res sql.Result, userID, listID int64) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	if _, err := db.ListRole(ctx, userID, listID); err != nil {
+		return err
+	}
+	return ErrForbidden
+}
+
+func (db *DB) queryIDs(ctx context.Context, query string, args ...any) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func scanList(scanner rowScanner) (*model.List, error) {
+	var (
+		l         model.List
+		isDefault int
+		createdAt any
+		enforce   int
+	)
+	if err := scanner.Scan(&l.ID, &l.Name, &isDefault, &l.CreatedBy, &l.Role, &createdAt, &enforce); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	l.IsDefault = isDefault == 1
+	l.EnforceDependencies = enforce == 1
+	if parsed, ok := parseTime(createdAt); ok {
+		l.CreatedAt = parsed
+	}
+	return &l, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const listStatusColumns = `s.id, s.list_id, s.name, s.category, s.position, s.wip_limit`
+
+// ListStatuses returns the statuses of listID in board order.
+func (db *DB) ListStatuses(ctx context.Context, listID int64) ([]model.ListStatus, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+listStatusColumns+` FROM list_statuses s WHERE s.list_id = ? ORDER BY s.position, s.id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []model.ListStatus{}
+	for rows.Next() {
+		status, err := scanListStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// GetListStatus returns statusID when it belongs to listID.
+func (db *DB) GetListStatus(ctx context.Context, listID, statusID int64) (*model.ListStatus, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+listStatusColumns+` FROM list_statuses s WHERE s.list_id = ? AND s.id = ?`, listID, statusID)
+	return scanListStatus(row)
+}
+
+func (db *DB) CreateListStatus(ctx context.Context, status *model.ListStatus) error {
+	res, err := db.ExecContext(ctx, `INSERT INTO list_statuses (list_id, name, category, position, wip_limit) VALUES (?, ?, ?, ?, ?)`,
+		status.ListID, status.Name, status.Category, status.Position, status.WIPLimit)
+	if err != nil {
+		return err
+	}
+	status.ID, err = res.LastInsertId()
+	return err
+}
+
+func (db *DB) UpdateListStatus(ctx context.Context, status *model.ListStatus) error {
+	res, err := db.ExecContext(ctx, `UPDATE list_statuses SET name = ?, category = ?, position = ?, wip_limit = ? WHERE id = ? AND list_id = ?`,
+		status.Name, status.Category, status.Position, status.WIPLimit, status.ID, status.ListID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (db *DB) DeleteListStatus(ctx context.Context, listID, statusID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM list_statuses WHERE id = ? AND list_id = ?`, statusID, listID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveStatusTodos moves every todo in fromID, including those in the trash,
+// to toID.
+func (db *DB) MoveStatusTodos(ctx context.Context, fromID, toID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE todos SET status_id = ? WHERE status_id = ?`, toID, fromID)
+	return err
+}
+
+// CountTodosInStatus counts the todos outside the trash in statusID, leaving
+// out exceptTodoID.
+func (db *DB) CountTodosInStatus(ctx context.Context, statusID, exceptTodoID int64) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE status_id = ? AND id <> ? AND deleted_at IS NULL`,
+		statusID, exceptTodoID).Scan(&n)
+	return n, err
+}
+
+// CountTodosByStatus counts the todos outside the trash in each status of
+// listID. Statuses without todos are left out.
+func (db *DB) CountTodosByStatus(ctx context.Context, listID int64) (map[int64]int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT status_id, COUNT(*) FROM todos
+		WHERE list_id = ? AND status_id IS NOT NULL AND deleted_at IS NULL GROUP BY status_id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var (
+			statusID int64
+			n        int
+		)
+		if err := rows.Scan(&statusID, &n); err != nil {
+			return nil, err
+		}
+		counts[statusID] = n
+	}
+	return counts, rows.Err()
+}
+
+// ListTodosByStatus returns up to limit of the todos in statusID that userID
+// can see, soonest due first.
+func (db *DB) ListTodosByStatus(ctx context.Context, userID, statusID int64, limit int) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+` WHERE t.status_id = ?
+		ORDER BY t.due_at IS NULL, t.due_at, t.id LIMIT ?`, userID, statusID, limit)
+}
+
+func scanListStatus(scanner rowScanner) (*model.ListStatus, error) {
+	var (
+		s        model.ListStatus
+		wipLimit sql.NullInt64
+	)
+	if err := scanner.Scan(&s.ID, &s.ListID, &s.Name, &s.Category, &s.Position, &wipLimit); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if wipLimit.Valid {
+		limit := int(wipLimit.Int64)
+		s.WIPLimit = &limit
+	}
+	return &s, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const notificationColumns = `n.id, n.kind, n.todo_id, n.actor_id, u.username, n.created_at, n.read_at`
+
+// CreateNotification stores n for userID.
+func (db *DB) CreateNotification(ctx context.Context, userID int64, n *model.Notification) error {
+	now := time.Now().UTC()
+	var actorID *int64
+	if n.Actor != nil {
+		actorID = &n.Actor.ID
+	}
+	res, err := db.ExecContext(ctx, `INSERT INTO notifications (user_id, kind, todo_id, actor_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+		userID, n.Kind, n.TodoID, actorID, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	n.ID = id
+	n.CreatedAt = now
+	if n.Actor != nil {
+		return db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = ?`, n.Actor.ID).Scan(&n.Actor.Username)
+	}
+	return nil
+}
+
+// ListNotifications returns up to limit of userID's notifications newest
+// first, with IDs below beforeID unless it is zero.
+func (db *DB) ListNotifications(ctx context.Context, userID int64, unreadOnly bool, beforeID int64, limit int) ([]model.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications n LEFT JOIN users u ON u.id = n.actor_id WHERE n.user_id = ?`
+	args := []any{userID}
+	if unreadOnly {
+		query += ` AND n.read_at IS NULL`
+	}
+	if beforeID > 0 {
+		query += ` AND n.id < ?`
+		args = append(args, beforeID)
+	}
+	rows, err := db.QueryContext(ctx, query+` ORDER BY n.id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []model.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkNotificationRead marks one of userID's notifications as read.
+func (db *DB) MarkNotificationRead(ctx context.Context, userID, notificationID int64) error {
+	res, err := db.ExecContext(ctx, `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`,
+		formatTime(time.Now().UTC()), notificationID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of userID as read.
+func (db *DB) MarkAllNotificationsRead(ctx context.Context, userID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`,
+		formatTime(time.Now().UTC()), userID)
+	return err
+}
+
+func scanNotification(scanner rowScanner) (*model.Notification, error) {
+	var (
+		n             model.Notification
+		todoID        sql.NullInt64
+		actorID       sql.NullInt64
+		actorUsername sql.NullString
+		createdAt     any
+		readAt        any
+	)
+	if err := scanner.Scan(&n.ID, &n.Kind, &todoID, &actorID, &actorUsername, &createdAt, &readAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if todoID.Valid {
+		n.TodoID = &todoID.Int64
+	}
+	if actorID.Valid {
+		n.Actor = &model.UserSummary{ID: actorID.Int64, Username: actorUsername.String}
+	}
+	if parsed, ok := parseTime(createdAt); ok {
+		n.CreatedAt = parsed
+	}
+	if parsed, ok := parseTime(readAt); ok {
+		n.ReadAt = &parsed
+	}
+	return &n, nil
+}
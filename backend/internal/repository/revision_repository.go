@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const revisionColumns = `r.id, r.todo_id, r.user_id, u.username, r.action, r.changes, r.revert_of, r.created_at`
+
+func (db *DB) CreateTodoRevision(ctx context.Context, rev *model.TodoRevision) error {
+	changes, err := json.Marshal(rev.Changes)
+	if err != nil {
+		return err
+	}
+	var actorID *int64
+	if rev.Actor != nil {
+		actorID = &rev.Actor.ID
+	}
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO todo_revisions (todo_id, user_id, action, changes, revert_of, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		rev.TodoID, actorID, rev.Action, string(changes), rev.RevertOf, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rev.ID = id
+	rev.CreatedAt = now
+	return nil
+}
+
+// ListTodoRevisions returns the revisions of todoID, newest first.
+func (db *DB) ListTodoRevisions(ctx context.Context, todoID int64) ([]model.TodoRevision, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+revisionColumns+` FROM todo_revisions r LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.todo_id = ? ORDER BY r.id DESC`, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.TodoRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func scanRevision(scanner rowScanner) (*model.TodoRevision, error) {
+	var (
+		rev           model.TodoRevision
+		actorID       sql.NullInt64
+		actorUsername sql.NullString
+		changes       string
+		revertOf      sql.NullInt64
+		createdAt     any
+	)
+	if err := scanner.Scan(&rev.ID, &rev.TodoID, &actorID, &actorUsername, &rev.Action, &changes, &revertOf, &createdAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(changes), &rev.Changes); err != nil {
+		return nil, err
+	}
+	if actorID.Valid {
+		rev.Actor = &model.UserSummary{ID: actorID.Int64, Username: actorUsername.String}
+	}
+	if revertOf.Valid {
+		rev.RevertOf = &revertOf.Int64
+	}
+	if parsed, ok := parseTime(createdAt); ok {
+		rev.CreatedAt = parsed
+	}
+	return &rev, nil
+}
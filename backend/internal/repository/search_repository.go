@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// ErrEmptyQuery reports a search query with nothing left to search for.
+var ErrEmptyQuery = errors.New("empty search query")
+
+// matchStart and matchEnd are wrapped around matched terms by FTS5. They are
+// control characters that ordinary titles and notes never contain, so the
+// text can be HTML-escaped before they are replaced with <mark> tags.
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+var markMatches = strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>")
+
+// SearchTodos runs a full-text search over the titles and notes of the todos
+// userID can see, best matches first. query is free text as described by
+// matchQuery; it returns ErrEmptyQuery when nothing searchable remains.
+func (db *DB) SearchTodos(ctx context.Context, userID int64, query string, limit int) ([]model.SearchResult, error) {
+	match := matchQuery(query)
+	if match == "" {
+		return nil, ErrEmptyQuery
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+todoColumns+`,
+			bm25(todos_fts, 10.0, 1.0) AS rank,
+			highlight(todos_fts, 0, char(2), char(3)),
+			snippet(todos_fts, 1, char(2), char(3), '…', 16)
+		FROM todos_fts
+		JOIN todos t ON t.id = todos_fts.rowid
+		JOIN users c ON c.id = t.user_id
+		LEFT JOIN users a ON a.id = t.assignee_id
+		LEFT JOIN list_statuses st ON st.id = t.status_id`+todoAccess+`
+		WHERE todos_fts MATCH ?
+		ORDER BY rank, t.id
+		LIMIT ?`, userID, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []model.SearchResult{}
+	for rows.Next() {
+		var r model.SearchResult
+		todo, err := scanTodo(rows, &r.Rank, &r.TitleHighlight, &r.NotesSnippet)
+		if err != nil {
+			return nil, err
+		}
+		r.Todo = *todo
+		r.TitleHighlight = markMatches.Replace(html.EscapeString(r.TitleHighlight))
+		r.NotesSnippet = markMatches.Replace(html.EscapeString(r.NotesSnippet))
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// matchQuery turns free text into an FTS5 query in which every part must
+// match. Double-quoted text is searched as a phrase and a trailing * on a
+// word or phrase makes it a prefix query. Everything else is quoted, so FTS5
+// operators and column filters typed by users are searched as plain words.
+func matchQuery(text string) string {
+	var parts []string
+	rest := []rune(text)
+	for len(rest) > 0 {
+		if unicode.IsSpace(rest[0]) {
+			rest = rest[1:]
+			continue
+		}
+		var term []rune
+		if rest[0] == '"' {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				end++
+			}
+			term = rest[1:end]
+			if end < len(rest) {
+				end++ // closing quote
+			}
+			rest = rest[end:]
+		} else {
+			end := 0
+			for end < len(rest) && !unicode.IsSpace(rest[end]) && rest[end] != '"' {
+				end++
+			}
+			term = rest[:end]
+			rest = rest[end:]
+		}
+		prefix := false
+		for len(rest) > 0 && rest[0] == '*' {
+			prefix = true
+			rest = rest[1:]
+		}
+		word := string(term)
+		if trimmed := strings.TrimRight(word, "*"); trimmed != word {
+			word, prefix = trimmed, true
+		}
+		if strings.TrimSpace(word) == "" {
+			continue
+		}
+		part := `"` + word + `"`
+		if prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
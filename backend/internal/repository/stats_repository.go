@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// TimeRange is the half-open interval [Start, End).
+type TimeRange struct {
+	Start, End time.Time
+}
+
+// TodoCounts counts the todos created and completed in a TimeRange.
+type TodoCounts struct {
+	Created   int
+	Completed int
+}
+
+// statsScope limits a statistics query on todos t to the live todos userID
+// can see, in listID when it is not zero. It takes the arguments that
+// statsArgs returns.
+const statsScope = `t.deleted_at IS NULL AND t.list_id IN (SELECT m.list_id FROM list_members m WHERE m.user_id = ?) AND (? = 0 OR t.list_id = ?)`
+
+func statsArgs(userID, listID int64) []any {
+	return []any{userID, listID, listID}
+}
+
+// CountTodosPerRange counts, for each of ranges, the todos userID can see
+// that were created and completed in it.
+func (db *DB) CountTodosPerRange(ctx context.Context, userID, listID int64, ranges []TimeRange) ([]TodoCounts, error) {
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	values := make([]string, len(ranges))
+	args := make([]any, 0, 3*len(ranges)+6)
+	for i, r := range ranges {
+		values[i] = `(?, ?, ?)`
+		args = append(args, i, formatTime(r.Start), formatTime(r.End))
+	}
+	args = append(args, statsArgs(userID, listID)...)
+	args = append(args, statsArgs(userID, listID)...)
+	rows, err := db.QueryContext(ctx, `WITH ranges(i, start, end) AS (VALUES `+strings.Join(values, ", ")+`)
+		SELECT r.i,
+			(SELECT COUNT(*) FROM todos t WHERE `+sqlTime(`t.created_at`)+` >= r.start AND `+sqlTime(`t.created_at`)+` < r.end AND `+statsScope+`),
+			(SELECT COUNT(*) FROM todos t WHERE `+sqlTime(`t.completed_at`)+` >= r.start AND `+sqlTime(`t.completed_at`)+` < r.end AND `+statsScope+`)
+		FROM ranges r ORDER BY r.i`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]TodoCounts, len(ranges))
+	for rows.Next() {
+		var (
+			i int
+			c TodoCounts
+		)
+		if err := rows.Scan(&i, &c.Created, &c.Completed); err != nil {
+			return nil, err
+		}
+		counts[i] = c
+	}
+	return counts, rows.Err()
+}
+
+// CompletionTimes returns the mean seconds from creation to completion of
+// the todos userID can see that were completed in r, and how many of them
+// were completed after they were due. The mean is invalid when none were
+// completed.
+func (db *DB) CompletionTimes(ctx context.Context, userID, listID int64, r TimeRange) (sql.NullFloat64, int, error) {
+	var (
+		mean sql.NullFloat64
+		late int
+	)
+	err := db.QueryRowContext(ctx, `SELECT AVG((julianday(t.completed_at) - julianday(t.created_at)) * 86400),
+			COALESCE(SUM(t.due_at IS NOT NULL AND `+sqlTime(`t.completed_at`)+` > t.due_at), 0)
+		FROM todos t WHERE `+sqlTime(`t.completed_at`)+` >= ? AND `+sqlTime(`t.completed_at`)+` < ? AND `+statsScope,
+		append([]any{formatTime(r.Start), formatTime(r.End)}, statsArgs(userID, listID)...)...).Scan(&mean, &late)
+	return mean, late, err
+}
+
+// CountOverdueTodos counts the open todos userID can see that were due
+// before now.
+func (db *DB) CountOverdueTodos(ctx context.Context, userID, listID int64, now time.Time) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos t WHERE t.completed = 0 AND t.due_at < ? AND `+statsScope,
+		append([]any{formatTime(now)}, statsArgs(userID, listID)...)...).Scan(&n)
+	return n, err
+}
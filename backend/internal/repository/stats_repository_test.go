@@ -0,0 +1,48 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// TestCountTodosPerRangeSQLiteTimestamps checks that timestamps written by
+// CURRENT_TIMESTAMP, without milliseconds, fall in the range that starts at
+// the same second.
+func TestCountTodosPerRangeSQLiteTimestamps(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	userID, listID := newTestList(t, db, "alice")
+	todo := &model.Todo{UserID: userID, ListID: listID, Title: "a"}
+	if err := db.CreateTodo(ctx, todo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE todos SET completed = 1, created_at = '2026-10-19 10:00:00', completed_at = '2026-10-19 10:00:00' WHERE id = ?`, todo.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	counts, err := db.CountTodosPerRange(ctx, userID, listID, []TimeRange{
+		{start.Add(-time.Hour), start},
+		{start, start.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TodoCounts{{}, {Created: 1, Completed: 1}}
+	if len(counts) != 2 || counts[0] != want[0] || counts[1] != want[1] {
+		t.Errorf("counts = %+v, want %+v", counts, want)
+	}
+
+	mean, _, err := db.CompletionTimes(ctx, userID, listID, TimeRange{start, start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mean.Valid || mean.Float64 != 0 {
+		t.Errorf("mean completion time = %+v, want 0", mean)
+	}
+}
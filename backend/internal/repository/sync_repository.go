@@ -0,0 +1,44 @@
+package repository
+
+import "context"
+
+// TodoChange is a todo that changed in a list. Removed is set when it has
+// since left the list, by being deleted, purged or moved elsewhere.
+type TodoChange struct {
+	ID      int64
+	UID     string
+	Removed bool
+}
+
+// ListSyncToken returns the ID of the latest change to a todo in listID or
+// moved out of it, or zero when there is none. The todo_changes triggers
+// record every version bump and move, and their IDs are never reused, so the
+// token grows with every change to the list's contents and never goes back,
+// not even when the trash is purged.
+func (db *DB) ListSyncToken(ctx context.Context, listID int64) (int64, error) {
+	var token int64
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM todo_changes WHERE list_id = ?`, listID).Scan(&token)
+	return token, err
+}
+
+// ListTodoChanges returns the todos in listID, or moved out of it, that
+// changed after the sync token since.
+func (db *DB) ListTodoChanges(ctx context.Context, listID, since int64) ([]TodoChange, error) {
+	rows, err := db.QueryContext(ctx, `SELECT c.todo_id, c.uid, t.id IS NULL OR t.list_id <> c.list_id OR t.deleted_at IS NOT NULL
+		FROM todo_changes c LEFT JOIN todos t ON t.id = c.todo_id
+		WHERE c.list_id = ? AND c.id > ? ORDER BY c.id`, listID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []TodoChange
+	for rows.Next() {
+		var c TodoChange
+		if err := rows.Scan(&c.ID, &c.UID, &c.Removed); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
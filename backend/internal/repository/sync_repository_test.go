@@ -0,0 +1,82 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestListSyncToken(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner, listID := newTestList(t, db, "alice")
+	_, otherList := newTestList(t, db, "bob")
+	if err := db.SetListMember(ctx, otherList, owner, model.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(listID int64) int64 {
+		t.Helper()
+		token, err := db.ListSyncToken(ctx, listID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	// advanced runs step and checks that it moves the token of listID on
+	// and that todo is the only change since the old token.
+	advanced := func(name string, listID int64, step func() error, todo *model.Todo, removed bool) {
+		t.Helper()
+		before := token(listID)
+		if err := step(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		after := token(listID)
+		if after <= before {
+			t.Errorf("%s: token went from %d to %d", name, before, after)
+		}
+		changes, err := db.ListTodoChanges(ctx, listID, before)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := TodoChange{ID: todo.ID, UID: todo.UID, Removed: removed}
+		if len(changes) != 1 || changes[0] != want {
+			t.Errorf("%s: changes = %+v, want %+v", name, changes, want)
+		}
+	}
+
+	todo := &model.Todo{ListID: listID, UserID: owner, Title: "buy milk", AssigneeID: &owner}
+	advanced("create", listID, func() error { return db.CreateTodo(ctx, todo) }, todo, false)
+
+	// Losing list access clears assignments without recording a revision.
+	advanced("unassign", listID, func() error { return db.UnassignListMember(ctx, listID, owner) }, todo, false)
+
+	todo.Version++
+	todo.AssigneeID = nil
+	todo.ListID = otherList
+	advanced("move out", listID, func() error { return db.UpdateTodo(ctx, owner, todo) }, todo, true)
+	if changes, err := db.ListTodoChanges(ctx, otherList, 0); err != nil || len(changes) != 1 || changes[0].Removed {
+		t.Errorf("changes in the new list = %+v, %v", changes, err)
+	}
+
+	advanced("trash", otherList, func() error { return db.DeleteTodo(ctx, owner, todo.ID, 0) }, todo, true)
+
+	before := token(otherList)
+	if _, err := db.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if after := token(otherList); after != before {
+		t.Errorf("purge moved the token from %d to %d", before, after)
+	}
+	changes, err := db.ListTodoChanges(ctx, otherList, 0)
+	if err != nil || len(changes) != 1 || !changes[0].Removed || changes[0].UID != todo.UID {
+		t.Errorf("changes after purge = %+v, %v, want the purged todo removed", changes, err)
+	}
+
+	next := &model.Todo{ListID: otherList, UserID: owner, Title: "new"}
+	advanced("create after purge", otherList, func() error { return db.CreateTodo(ctx, next) }, next, false)
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const timeEntryColumns = `e.id, e.todo_id, t.title, t.list_id, e.user_id, u.username, e.started_at, e.ended_at, e.note, e.created_at`
+
+// timeEntryFrom joins the todo and user of each entry e.
+const timeEntryFrom = ` FROM time_entries e JOIN todos t ON t.id = e.todo_id JOIN users u ON u.id = e.user_id`
+
+// TimeEntryFilter selects time entries. Zero values leave a filter off; the
+// range keeps the entries that overlap it.
+type TimeEntryFilter struct {
+	TodoID int64
+	ListID int64
+	UserID int64
+	From   *time.Time // inclusive
+	To     *time.Time // exclusive
+}
+
+// where returns the conditions of f on entries e of todos t, and their
+// arguments.
+func (f TimeEntryFilter) where() (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.TodoID != 0 {
+		where = append(where, `e.todo_id = ?`)
+		args = append(args, f.TodoID)
+	}
+	if f.ListID != 0 {
+		where = append(where, `t.list_id = ?`)
+		args = append(args, f.ListID)
+	}
+	if f.UserID != 0 {
+		where = append(where, `e.user_id = ?`)
+		args = append(args, f.UserID)
+	}
+	if f.From != nil {
+		where = append(where, `(e.ended_at IS NULL OR e.ended_at > ?)`)
+		args = append(args, formatTime(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, `e.started_at < ?`)
+		args = append(args, formatTime(*f.To))
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(where, " AND "), args
+}
+
+// ListTimeEntries returns the time entries matching f on todos userID can
+// see, oldest first.
+func (db *DB) ListTimeEntries(ctx context.Context, userID int64, f TimeEntryFilter) ([]model.TimeEntry, error) {
+	where, args := f.where()
+	rows, err := db.QueryContext(ctx, `SELECT `+timeEntryColumns+timeEntryFrom+todoAccess+where+` ORDER BY e.started_at, e.id`,
+		append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.TimeEntry{}
+	for rows.Next() {
+		e, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// GetTimeEntry returns an entry on todoID when userID can see the todo.
+func (db *DB) GetTimeEntry(ctx context.Context, userID, todoID, entryID int64) (*model.TimeEntry, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+timeEntryFrom+todoAccess+` WHERE e.id = ? AND e.todo_id = ?`,
+		userID, entryID, todoID)
+	return scanTimeEntry(row)
+}
+
+// GetRunningTimeEntry returns userID's running timer. It is found even when
+// its todo has since gone to the trash, so that it can still be stopped.
+func (db *DB) GetRunningTimeEntry(ctx context.Context, userID int64) (*model.TimeEntry, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+timeEntryFrom+` WHERE e.user_id = ? AND e.ended_at IS NULL`, userID)
+	return scanTimeEntry(row)
+}
+
+func (db *DB) CreateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO time_entries (todo_id, user_id, started_at, ended_at, note, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.TodoID, e.User.ID, formatTime(e.StartedAt), nullTime(e.EndedAt), e.Note, formatTime(now))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	stored, err := scanTimeEntry(db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+timeEntryFrom+` WHERE e.id = ?`, id))
+	if err != nil {
+		return err
+	}
+	*e = *stored
+	return nil
+}
+
+func (db *DB) UpdateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	res, err := db.ExecContext(ctx, `UPDATE time_entries SET started_at = ?, ended_at = ?, note = ? WHERE id = ?`,
+		formatTime(e.StartedAt), nullTime(e.EndedAt), e.Note, e.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	e.Seconds = entrySeconds(e.StartedAt, e.EndedAt)
+	return nil
+}
+
+func (db *DB) DeleteTimeEntry(ctx context.Context, entryID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM time_entries WHERE id = ?`, entryID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TimeTotals sums the time of the entries matching f on todos userID can
+// see, per todo or, when byList is set, per list, largest first. Only the
+// part of each entry within f's range counts, and running timers count up
+// to now.
+func (db *DB) TimeTotals(ctx context.Context, userID int64, f TimeEntryFilter, byList bool, now time.Time) ([]model.TimeTotal, error) {
+	from, to := "0000", "9999"
+	if f.From != nil {
+		from = formatTime(*f.From)
+	}
+	if f.To != nil {
+		to = formatTime(*f.To)
+	}
+	// MIN and MAX compare the timestamps as text, which sorts them in time.
+	seconds := `CAST(ROUND(SUM(MAX(0, julianday(MIN(COALESCE(e.ended_at, ?), ?)) - julianday(MAX(e.started_at, ?))) * 86400)) AS INTEGER)`
+	query := `SELECT e.todo_id, t.list_id, t.title, ` + seconds + timeEntryFrom + todoAccess
+	group := ` GROUP BY e.todo_id`
+	if byList {
+		query = `SELECT 0, t.list_id, l.name, ` + seconds + timeEntryFrom + todoAccess + ` JOIN lists l ON l.id = t.list_id`
+		group = ` GROUP BY t.list_id`
+	}
+	where, args := f.where()
+	rows, err := db.QueryContext(ctx, query+where+group+` ORDER BY 4 DESC, 1, 2`,
+		append([]any{formatTime(now), to, from, userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []model.TimeTotal{}
+	for rows.Next() {
+		var t model.TimeTotal
+		if err := rows.Scan(&t.TodoID, &t.ListID, &t.Name, &t.Seconds); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func scanTimeEntry(scanner rowScanner) (*model.TimeEntry, error) {
+	var (
+		e                             model.TimeEntry
+		startedAt, endedAt, createdAt any
+	)
+	err := scanner.Scan(&e.ID, &e.TodoID, &e.TodoTitle, &e.ListID, &e.User.ID, &e.User.Username, &startedAt, &endedAt, &e.Note, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.StartedAt, _ = parseTime(startedAt)
+	if t, ok := parseTime(endedAt); ok {
+		e.EndedAt = &t
+	}
+	e.CreatedAt, _ = parseTime(createdAt)
+	e.Seconds = entrySeconds(e.StartedAt, e.EndedAt)
+	return &e, nil
+}
+
+// entrySeconds is the length of an entry, up to now while it is running.
+func entrySeconds(startedAt time.Time, endedAt *time.Time) int64 {
+	end := time.Now()
+	if endedAt != nil {
+		end = *endedAt
+	}
+	return int64(end.Sub(startedAt).Round(time.Second) / time.Second)
+}
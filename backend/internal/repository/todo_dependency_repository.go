@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// todoBlocked is an SQL expression telling whether the todo t has a blocker
+// that is neither completed nor in the trash.
+const todoBlocked = `EXISTS (SELECT 1 FROM todo_dependencies dep JOIN todos blk ON blk.id = dep.blocker_id
+	WHERE dep.todo_id = t.id AND blk.completed = 0 AND blk.deleted_at IS NULL)`
+
+// AddTodoDependency makes blockerID block todoID. Adding a dependency that
+// exists already does nothing.
+func (db *DB) AddTodoDependency(ctx context.Context, todoID, blockerID int64) error {
+	_, err := db.ExecContext(ctx, `INSERT OR IGNORE INTO todo_dependencies (todo_id, blocker_id, created_at) VALUES (?, ?, ?)`,
+		todoID, blockerID, formatTime(time.Now().UTC()))
+	return err
+}
+
+func (db *DB) DeleteTodoDependency(ctx context.Context, todoID, blockerID int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM todo_dependencies WHERE todo_id = ? AND blocker_id = ?`, todoID, blockerID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Blocks reports whether blockerID blocks todoID, directly or through other
+// todos, counting todos in the trash too.
+func (db *DB) Blocks(ctx context.Context, blockerID, todoID int64) (bool, error) {
+	var blocks bool
+	err := db.QueryRowContext(ctx, `WITH RECURSIVE blockers(id) AS (
+			SELECT blocker_id FROM todo_dependencies WHERE todo_id = ?
+			UNION
+			SELECT d.blocker_id FROM todo_dependencies d JOIN blockers b ON d.todo_id = b.id
+		)
+		SELECT EXISTS (SELECT 1 FROM blockers WHERE id = ?)`, todoID, blockerID).Scan(&blocks)
+	return blocks, err
+}
+
+// ListBlockers returns the todos blocking todoID that userID can see.
+func (db *DB) ListBlockers(ctx context.Context, userID, todoID int64) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+`
+		JOIN todo_dependencies d ON d.blocker_id = t.id WHERE d.todo_id = ? ORDER BY t.id`, userID, todoID)
+}
+
+// ListBlocked returns the todos blockerID blocks that userID can see.
+func (db *DB) ListBlocked(ctx context.Context, userID, blockerID int64) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+`
+		JOIN todo_dependencies d ON d.todo_id = t.id WHERE d.blocker_id = ? ORDER BY t.id`, userID, blockerID)
+}
+
+// ListDependentTodos returns every todo blockerID blocks, outside the trash,
+// whoever can see them.
+func (db *DB) ListDependentTodos(ctx context.Context, blockerID int64) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+`
+		JOIN todo_dependencies d ON d.todo_id = t.id WHERE d.blocker_id = ? AND t.deleted_at IS NULL ORDER BY t.id`, blockerID)
+}
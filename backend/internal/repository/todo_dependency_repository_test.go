@@ -0,0 +1,65 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestBlocks(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	userID, listID := newTestList(t, db, "alice")
+	ids := map[string]int64{}
+	for _, title := range []string{"a", "b", "c", "d"} {
+		todo := &model.Todo{UserID: userID, ListID: listID, Title: title}
+		if err := db.CreateTodo(ctx, todo); err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = todo.ID
+	}
+	// a ← b ← c: b blocks a and c blocks b; d stands apart.
+	for _, dep := range [][2]string{{"a", "b"}, {"b", "c"}} {
+		if err := db.AddTodoDependency(ctx, ids[dep[0]], ids[dep[1]]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Adding a dependency twice does nothing.
+	if err := db.AddTodoDependency(ctx, ids["a"], ids["b"]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		blocker, todo string
+		want          bool
+	}{
+		{"b", "a", true},
+		{"c", "b", true},
+		{"c", "a", true},
+		{"a", "b", false},
+		{"a", "c", false},
+		{"a", "a", false},
+		{"d", "a", false},
+		{"a", "d", false},
+	}
+	for _, tt := range tests {
+		got, err := db.Blocks(ctx, ids[tt.blocker], ids[tt.todo])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Blocks(%s, %s) = %v, want %v", tt.blocker, tt.todo, got, tt.want)
+		}
+	}
+
+	// Todos in the trash still count, so restoring one cannot close a cycle.
+	if err := db.DeleteTodo(ctx, userID, ids["b"], 0); err != nil {
+		t.Fatal(err)
+	}
+	if blocks, err := db.Blocks(ctx, ids["c"], ids["a"]); err != nil || !blocks {
+		t.Errorf("Blocks(c, a) through a trashed todo = %v, %v, want true", blocks, err)
+	}
+}
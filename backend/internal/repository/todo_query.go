@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/query"
+)
+
+// ErrInvalidQuery reports a filter query that does not parse, names an
+// unknown field or compares a field with a value it cannot take.
+var ErrInvalidQuery = errors.New("invalid filter query")
+
+// TodoQuery is a parsed filter query for ListTodos.
+type TodoQuery struct {
+	Expr query.Expr
+	// Now is the current time, in the timezone relative dates such as today
+	// are read in.
+	Now time.Time
+	// FieldValue converts the value of a term on custom field fieldID into
+	// the string, number or bool the field stores. Terms on custom fields are
+	// rejected when it is nil.
+	FieldValue func(fieldID int64, raw string) (any, error)
+}
+
+// compileTodoQuery turns q into an SQL condition on the todos t, statuses
+// st and memberships m that ListTodos joins, for userID, and its arguments.
+// Values are only ever bound as arguments. Every term compiles to a
+// condition that is never NULL, so negating it matches exactly the todos it
+// does not.
+func compileTodoQuery(q *TodoQuery, userID int64) (string, []any, error) {
+	c := &queryCompiler{q: q, userID: userID}
+	cond, err := c.expr(q.Expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return cond, c.args, nil
+}
+
+type queryCompiler struct {
+	q      *TodoQuery
+	userID int64
+	args   []any
+}
+
+func (c *queryCompiler) expr(expr query.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *query.And:
+		return c.binary(e.Left, " AND ", e.Right)
+	case *query.Or:
+		return c.binary(e.Left, " OR ", e.Right)
+	case *query.Not:
+		cond, err := c.expr(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return `NOT (` + cond + `)`, nil
+	case *query.Term:
+		return c.term(e)
+	}
+	return "", fmt.Errorf("%w: unexpected expression %T", ErrInvalidQuery, expr)
+}
+
+func (c *queryCompiler) binary(left query.Expr, op string, right query.Expr) (string, error) {
+	l, err := c.expr(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.expr(right)
+	if err != nil {
+		return "", err
+	}
+	return `(` + l + op + r + `)`, nil
+}
+
+// term compiles a single comparison. A != term is the negation of the
+// matching : term.
+func (c *queryCompiler) term(t *query.Term) (string, error) {
+	if t.Op == query.Ne {
+		eq := *t
+		eq.Op = query.Eq
+		cond, err := c.term(&eq)
+		if err != nil {
+			return "", err
+		}
+		return `NOT (` + cond + `)`, nil
+	}
+	if t.Field == "" {
+		if t.Value == "" {
+			return "", fmt.Errorf("%w: empty search term at position %d", ErrInvalidQuery, t.Pos)
+		}
+		pattern := "%" + escapeLike(t.Value) + "%"
+		return c.bind(`(t.title LIKE ? ESCAPE '\' OR t.notes LIKE ? ESCAPE '\')`, pattern, pattern), nil
+	}
+	if raw, ok := strings.CutPrefix(t.Field, fieldSortPrefix); ok {
+		return c.customField(t, raw)
+	}
+	ordered := t.Field == "priority" || t.Field == "due" || t.Field == "created" || t.Field == "updated"
+	if !ordered && t.Op != query.Eq {
+		return "", fmt.Errorf("%w: %s only takes : and !=", ErrInvalidQuery, t.Field)
+	}
+	value := strings.ToLower(t.Value)
+	switch t.Field {
+	case "title":
+		return c.bind(`t.title LIKE ? ESCAPE '\'`, "%"+escapeLike(t.Value)+"%"), nil
+	case "notes":
+		return c.bind(`t.notes LIKE ? ESCAPE '\'`, "%"+escapeLike(t.Value)+"%"), nil
+	case "is":
+		switch value {
+		case "open":
+			return `t.completed = 0`, nil
+		case "done", "completed":
+			return `t.completed = 1`, nil
+		case "blocked":
+			return todoBlocked, nil
+		case "overdue":
+			return c.bind(`(t.completed = 0 AND t.due_at IS NOT NULL AND t.due_at < ?)`, formatTime(c.q.Now)), nil
+		case "recurring":
+			return `t.rrule <> ''`, nil
+		}
+	case "has":
+		switch value {
+		case "due":
+			return `t.due_at IS NOT NULL`, nil
+		case "assignee":
+			return `t.assignee_id IS NOT NULL`, nil
+		case "priority":
+			return `t.priority <> ''`, nil
+		case "tags":
+			return `t.tags <> '[]'`, nil
+		case "notes":
+			return `t.notes <> ''`, nil
+		}
+	case "completed":
+		if completed, err := strconv.ParseBool(value); err == nil {
+			return c.bind(`t.completed = ?`, boolToInt(completed)), nil
+		}
+	case "tag":
+		return c.bind(`EXISTS (SELECT 1 FROM json_each(t.tags) tg WHERE tg.value = ?)`, value), nil
+	case "priority":
+		if value == "none" && t.Op == query.Eq {
+			return `t.priority = ''`, nil
+		}
+		if len(value) == 1 && 'a' <= value[0] && value[0] <= 'z' {
+			return c.bind(`(t.priority <> '' AND t.priority `+t.Op+` ?)`, strings.ToUpper(value)), nil
+		}
+	case "due", "created", "updated":
+		return c.date(t, value)
+	case "assignee", "creator":
+		column := `t.assignee_id`
+		if t.Field == "creator" {
+			column = `t.user_id`
+		}
+		if value == "none" && t.Field == "assignee" {
+			return `t.assignee_id IS NULL`, nil
+		}
+		if value == "me" {
+			return c.bind(column+` IS ?`, c.userID), nil
+		}
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return c.bind(column+` IS ?`, id), nil
+		}
+		return c.bind(`EXISTS (SELECT 1 FROM users u WHERE u.id = `+column+` AND u.username = ?)`, t.Value), nil
+	case "list":
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return c.bind(`t.list_id = ?`, id), nil
+		}
+		return c.bind(`EXISTS (SELECT 1 FROM lists l WHERE l.id = t.list_id AND l.name = ? COLLATE NOCASE)`, t.Value), nil
+	case "status":
+		return c.bind(`COALESCE(st.name, '') = ? COLLATE NOCASE`, t.Value), nil
+	case "category":
+		switch value {
+		case model.StatusTodo, model.StatusInProgress, model.StatusDone:
+			return c.bind(`COALESCE(st.category, '') = ?`, value), nil
+		}
+	default:
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, t.Field)
+	}
+	return "", invalidQueryValue(t)
+}
+
+// date compiles a comparison of a timestamp column with a day or a range
+// of days: due:today matches todos due some time today, due<today those due
+// before it and due<=today those due by its end.
+func (c *queryCompiler) date(t *query.Term, value string) (string, error) {
+	column := map[string]string{"due": `t.due_at`, "created": `t.created_at`, "updated": `t.updated_at`}[t.Field]
+	if value == "none" && t.Op == query.Eq {
+		return column + ` IS NULL`, nil
+	}
+	cond := column + ` IS NOT NULL AND `
+	if t.Field != "due" {
+		// Only due dates are always written by the repository.
+		column = sqlTime(column)
+	}
+	start, end, ok := queryDateRange(value, c.q.Now)
+	if !ok {
+		return "", invalidQueryValue(t)
+	}
+	switch t.Op {
+	case query.Eq:
+		return c.bind(`(`+cond+column+` >= ? AND `+column+` < ?)`, formatTime(start), formatTime(end)), nil
+	case query.Lt:
+		return c.bind(`(`+cond+column+` < ?)`, formatTime(start)), nil
+	case query.Le:
+		return c.bind(`(`+cond+column+` < ?)`, formatTime(end)), nil
+	case query.Gt:
+		return c.bind(`(`+cond+column+` >= ?)`, formatTime(end)), nil
+	default:
+		return c.bind(`(`+cond+column+` >= ?)`, formatTime(start)), nil
+	}
+}
+
+// customField compiles a comparison of the custom field with the ID raw.
+// Todos without a value for it never match.
+func (c *queryCompiler) customField(t *query.Term, raw string) (string, error) {
+	fieldID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || fieldID <= 0 || strconv.FormatInt(fieldID, 10) != raw {
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, t.Field)
+	}
+	if c.q.FieldValue == nil {
+		return "", fmt.Errorf("%w: custom fields cannot be used here", ErrInvalidQuery)
+	}
+	value, err := c.q.FieldValue(fieldID, t.Value)
+	if err != nil {
+		return "", err
+	}
+	return c.bind(`EXISTS (SELECT 1 FROM todo_field_values fv WHERE fv.todo_id = t.id AND fv.field_id = ? AND json_extract(fv.value, '$') `+t.Op+` ?)`, fieldID, value), nil
+}
+
+func (c *queryCompiler) bind(cond string, args ...any) string {
+	c.args = append(c.args, args...)
+	return cond
+}
+
+func invalidQueryValue(t *query.Term) error {
+	return fmt.Errorf("%w: invalid value %q for %s", ErrInvalidQuery, t.Value, t.Field)
+}
+
+// queryDateRange reads value as a span of time [start, end) relative to now:
+// now itself, today, tomorrow, yesterday, this-week, next-week, last-week
+// (weeks start on Monday), this-month, next-month, last-month, a day a
+// number of days or weeks from today such as +3d or -2w, or a YYYY-MM-DD
+// date. Days are those of the location of now.
+func queryDateRange(value string, now time.Time) (start, end time.Time, ok bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := func(days int) (time.Time, time.Time, bool) {
+		start := today.AddDate(0, 0, days)
+		return start, start.AddDate(0, 0, 1), true
+	}
+	week := func(weeks int) (time.Time, time.Time, bool) {
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7+7*weeks)
+		return monday, monday.AddDate(0, 0, 7), true
+	}
+	month := func(months int) (time.Time, time.Time, bool) {
+		first := time.Date(today.Year(), today.Month()+time.Month(months), 1, 0, 0, 0, 0, today.Location())
+		return first, first.AddDate(0, 1, 0), true
+	}
+	switch value {
+	case "now":
+		return now, now, true
+	case "today":
+		return day(0)
+	case "tomorrow":
+		return day(1)
+	case "yesterday":
+		return day(-1)
+	case "this-week":
+		return week(0)
+	case "next-week":
+		return week(1)
+	case "last-week":
+		return week(-1)
+	case "this-month":
+		return month(0)
+	case "next-month":
+		return month(1)
+	case "last-month":
+		return month(-1)
+	}
+	if len(value) > 2 && (value[0] == '+' || value[0] == '-') && '0' <= value[1] && value[1] <= '9' {
+		n, err := strconv.ParseInt(value[1:len(value)-1], 10, 16)
+		if err != nil {
+			return start, end, false
+		}
+		if value[0] == '-' {
+			n = -n
+		}
+		switch value[len(value)-1] {
+		case 'd':
+			return day(int(n))
+		case 'w':
+			return day(7 * int(n))
+		}
+		return start, end, false
+	}
+	d, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+	if err != nil {
+		return start, end, false
+	}
+	start = d
+	return start, start.AddDate(0, 0, 1), true
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/query"
+)
+
+func compileTestQuery(t *testing.T, text string, fieldValue func(int64, string) (any, error)) (string, []any, error) {
+	t.Helper()
+	expr, err := query.Parse(text)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", text, err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &TodoQuery{Expr: expr, Now: time.Date(2026, 10, 21, 15, 0, 0, 0, berlin), FieldValue: fieldValue}
+	return compileTodoQuery(q, 42)
+}
+
+func rawFieldValue(fieldID int64, raw string) (any, error) {
+	return raw, nil
+}
+
+// TestCompileTodoQueryBindsValues checks that the values of a query only
+// ever reach the database as arguments, however they are quoted.
+func TestCompileTodoQueryBindsValues(t *testing.T) {
+	tests := []struct {
+		query string
+		// values must not appear in the SQL text.
+		values []string
+		args   []any
+	}{
+		{`title:"x' OR '1'='1"`, []string{"x' OR '1'='1"}, []any{"%x' OR '1'='1%"}},
+		{`notes:"a\"b"`, []string{`a"b`}, []any{`%a"b%`}},
+		{`"50%_off\\"`, []string{`50%_off\`}, []any{`%50\%\_off\\%`, `%50\%\_off\\%`}},
+		{`tag:"'; DROP TABLE todos; --"`, []string{"DROP TABLE"}, []any{"'; drop table todos; --"}},
+		{`list:"Evil') OR 1=1 --"`, []string{"Evil')"}, []any{"Evil') OR 1=1 --"}},
+		{`list:7`, nil, []any{int64(7)}},
+		{`assignee:me`, nil, []any{int64(42)}},
+		{`creator:"rob'ert"`, []string{"rob'ert"}, []any{"rob'ert"}},
+		{`assignee:17`, nil, []any{int64(17)}},
+		{`status:"Do'ing"`, []string{"Do'ing"}, []any{"Do'ing"}},
+		{`category:in_progress`, []string{"in_progress"}, []any{"in_progress"}},
+		{`priority>=b`, nil, []any{"B"}},
+		{`completed:true`, nil, []any{1}},
+		{`due<=tomorrow`, []string{"tomorrow"}, []any{"2026-10-22 22:00:00.000"}},
+		{`is:overdue`, nil, []any{"2026-10-21 13:00:00.000"}},
+		{`field.3:"x' --"`, []string{"x' --"}, []any{int64(3), "x' --"}},
+		{`field.3>=10 -tag:"a'b" OR title:"c;d"`, []string{"a'b", "c;d"}, []any{int64(3), "10", "a'b", "%c;d%"}},
+	}
+	for _, tt := range tests {
+		sql, args, err := compileTestQuery(t, tt.query, rawFieldValue)
+		if err != nil {
+			t.Errorf("compile %q: %v", tt.query, err)
+			continue
+		}
+		for _, v := range tt.values {
+			if strings.Contains(strings.ToLower(sql), strings.ToLower(v)) {
+				t.Errorf("compile %q: value %q is in the SQL %s", tt.query, v, sql)
+			}
+		}
+		if n := strings.Count(sql, "?"); n != len(args) {
+			t.Errorf("compile %q: %d placeholders for %d args in %s", tt.query, n, len(args), sql)
+		}
+		if fmt.Sprint(args) != fmt.Sprint(tt.args) {
+			t.Errorf("compile %q: args = %#v, want %#v", tt.query, args, tt.args)
+		}
+	}
+}
+
+func TestCompileTodoQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`is:open`, `t.completed = 0`},
+		{`is:DONE has:due`, `(t.completed = 1 AND t.due_at IS NOT NULL)`},
+		{`is:recurring OR has:tags`, `(t.rrule <> '' OR t.tags <> '[]')`},
+		{`-(is:open has:assignee)`, `NOT ((t.completed = 0 AND t.assignee_id IS NOT NULL))`},
+		{`assignee:none`, `t.assignee_id IS NULL`},
+		{`priority:none`, `t.priority = ''`},
+		{`priority!=none`, `NOT (t.priority = '')`},
+		{`due:none`, `t.due_at IS NULL`},
+		{`tag!=x`, `NOT (EXISTS (SELECT 1 FROM json_each(t.tags) tg WHERE tg.value = ?))`},
+		{`due:today`, `(t.due_at IS NOT NULL AND t.due_at >= ? AND t.due_at < ?)`},
+		{`created>today`, `(t.created_at IS NOT NULL AND strftime('%Y-%m-%d %H:%M:%f', t.created_at) >= ?)`},
+	}
+	for _, tt := range tests {
+		sql, _, err := compileTestQuery(t, tt.query, nil)
+		if err != nil {
+			t.Errorf("compile %q: %v", tt.query, err)
+			continue
+		}
+		if sql != tt.want {
+			t.Errorf("compile %q = %s, want %s", tt.query, sql, tt.want)
+		}
+	}
+}
+
+func TestCompileTodoQueryErrors(t *testing.T) {
+	errField := errors.New("not a number")
+	fieldValue := func(fieldID int64, raw string) (any, error) {
+		if fieldID == 9 {
+			return nil, errField
+		}
+		return raw, nil
+	}
+	tests := []struct {
+		query string
+		want  error
+	}{
+		{`bogus:x`, ErrInvalidQuery},
+		{`is:weird`, ErrInvalidQuery},
+		{`has:everything`, ErrInvalidQuery},
+		{`title>x`, ErrInvalidQuery},
+		{`tag<=x`, ErrInvalidQuery},
+		{`priority:zz`, ErrInvalidQuery},
+		{`completed:maybe`, ErrInvalidQuery},
+		{`category:someday`, ErrInvalidQuery},
+		{`due:someday`, ErrInvalidQuery},
+		{`due<none`, ErrInvalidQuery},
+		{`field.03:x`, ErrInvalidQuery},
+		{`field.0:x`, ErrInvalidQuery},
+		{`field.x:x`, ErrInvalidQuery},
+		{`is:open OR field.9:x`, errField},
+	}
+	for _, tt := range tests {
+		if _, _, err := compileTestQuery(t, tt.query, fieldValue); !errors.Is(err, tt.want) {
+			t.Errorf("compile %q = %v, want %v", tt.query, err, tt.want)
+		}
+	}
+	if _, _, err := compileTestQuery(t, `field.3:x`, nil); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("custom field without FieldValue = %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestQueryDateRange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wednesday 21 October 2026; Berlin leaves daylight saving time on
+	// Sunday 25 October.
+	now := time.Date(2026, 10, 21, 15, 0, 0, 0, berlin)
+	const layout = "Mon 2006-01-02 15:04 -0700"
+	tests := []struct {
+		value      string
+		start, end string
+	}{
+		{"today", "Wed 2026-10-21 00:00 +0200", "Thu 2026-10-22 00:00 +0200"},
+		{"tomorrow", "Thu 2026-10-22 00:00 +0200", "Fri 2026-10-23 00:00 +0200"},
+		{"yesterday", "Tue 2026-10-20 00:00 +0200", "Wed 2026-10-21 00:00 +0200"},
+		{"this-week", "Mon 2026-10-19 00:00 +0200", "Mon 2026-10-26 00:00 +0100"},
+		{"next-week", "Mon 2026-10-26 00:00 +0100", "Mon 2026-11-02 00:00 +0100"},
+		{"last-week", "Mon 2026-10-12 00:00 +0200", "Mon 2026-10-19 00:00 +0200"},
+		{"this-month", "Thu 2026-10-01 00:00 +0200", "Sun 2026-11-01 00:00 +0100"},
+		{"next-month", "Sun 2026-11-01 00:00 +0100", "Tue 2026-12-01 00:00 +0100"},
+		{"last-month", "Tue 2026-09-01 00:00 +0200", "Thu 2026-10-01 00:00 +0200"},
+		{"+3d", "Sat 2026-10-24 00:00 +0200", "Sun 2026-10-25 00:00 +0200"},
+		{"+4d", "Sun 2026-10-25 00:00 +0200", "Mon 2026-10-26 00:00 +0100"},
+		{"-2w", "Wed 2026-10-07 00:00 +0200", "Thu 2026-10-08 00:00 +0200"},
+		{"2026-12-24", "Thu 2026-12-24 00:00 +0100", "Fri 2026-12-25 00:00 +0100"},
+		{"now", "Wed 2026-10-21 15:00 +0200", "Wed 2026-10-21 15:00 +0200"},
+	}
+	for _, tt := range tests {
+		start, end, ok := queryDateRange(tt.value, now)
+		if !ok {
+			t.Errorf("queryDateRange(%q) failed", tt.value)
+			continue
+		}
+		if got := start.Format(layout); got != tt.start {
+			t.Errorf("queryDateRange(%q) start = %s, want %s", tt.value, got, tt.start)
+		}
+		if got := end.Format(layout); got != tt.end {
+			t.Errorf("queryDateRange(%q) end = %s, want %s", tt.value, got, tt.end)
+		}
+	}
+	for _, value := range []string{"soon", "+3x", "+d", "-w", "+99999d", "2026-02-30", "26-10-21"} {
+		if _, _, ok := queryDateRange(value, now); ok {
+			t.Errorf("queryDateRange(%q) succeeded", value)
+		}
+	}
+}
@@ -1,21 +1,398 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
 )
 
-func (db *DB) ListTodosByUser(ctx context.Context, userID int64) ([]model.Todo, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, user_id, title, completed, created_at FROM todos WHERE user_id = ? ORDER BY id`, userID)
+const todoColumns = `t.id, t.list_id, t.user_id, c.username, t.assignee_id, a.username, t.title, t.notes, t.completed, t.created_at, t.due_at, t.rrule, t.timezone, t.repeat_mode, t.deleted_at, t.version, t.updated_at, t.uid, t.priority, t.tags, t.completed_at, t.status_id, st.name, st.category, ` + todoBlocked + `, ` + todoFieldValues
+
+// todoFrom joins the creator, assignee and status todoColumns selects.
+const todoFrom = ` FROM todos t JOIN users c ON c.id = t.user_id LEFT JOIN users a ON a.id = t.assignee_id LEFT JOIN list_statuses st ON st.id = t.status_id`
+
+// todoAccess joins the list membership of the user bound to its single
+// placeholder, so only todos in lists that user belongs to are visible. Todos
+// in the trash are hidden; trashAccess shows only those.
+const (
+	todoAccess  = ` JOIN list_members m ON m.list_id = t.list_id AND m.user_id = ? AND t.deleted_at IS NULL`
+	trashAccess = ` JOIN list_members m ON m.list_id = t.list_id AND m.user_id = ? AND t.deleted_at IS NOT NULL`
+)
+
+// ErrVersionConflict reports a write based on an outdated version of a todo.
+var ErrVersionConflict = errors.New("todo was modified concurrently")
+
+// ErrInvalidCursor reports a pagination cursor that is malformed or was
+// issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TodoFilter selects and orders a page of todos for ListTodos. Zero values
+// leave a filter off.
+type TodoFilter struct {
+	ListID        int64
+	StatusID      int64
+	Completed     *bool
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	TitleContains string
+	Sort          []TodoSort // defaults to id ascending
+	Cursor        string     // from a previous page, empty for the first
+	Limit         int
+	// Fields filters on custom field values; all must match.
+	Fields []FieldFilter
+	// Query is a filter query todos must match as well, or nil.
+	Query *TodoQuery
+}
+
+// FieldFilter compares the value of a custom field with Value, a string,
+// number or bool as the field stores it. Todos without a value never match.
+type FieldFilter struct {
+	FieldID int64
+	Op      string // "=", ">=" or "<="
+	Value   any
+}
+
+var fieldFilterOps = []string{"=", ">=", "<="}
+
+// TodoSort orders todos by one of the keys of todoSortKeys, or by a custom
+// field with a key such as "field.12".
+type TodoSort struct {
+	Key  string
+	Desc bool
+}
+
+// sortKey is an expression todos sort by. Cursor values of numeric keys are
+// integers and those of mixed keys numbers or strings; the others are
+// strings.
+type sortKey struct {
+	expr    string
+	numeric bool
+	mixed   bool
+}
+
+// todoSortKeys maps sort keys to the expressions they order by. Every
+// expression is non-NULL, so keyset comparisons work, and matches an index
+// in 202410190008_todo_list_indexes.sql. Todos without a due date sort after
+// those with one.
+var todoSortKeys = map[string]sortKey{
+	"id":        {expr: `t.id`, numeric: true},
+	"createdAt": {expr: `COALESCE(t.created_at, '')`},
+	"dueAt":     {expr: `COALESCE(t.due_at, '9999-12-31')`},
+	"title":     {expr: `t.title COLLATE NOCASE`},
+	"completed": {expr: `t.completed`, numeric: true},
+}
+
+// ParseTodoSort parses a comma-separated list of sort keys, each optionally
+// prefixed with - for descending order, such as "-createdAt,title".
+func ParseTodoSort(spec string) ([]TodoSort, error) {
+	var sorts []TodoSort
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		sort := TodoSort{Key: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := todoSortKey(sort.Key); !ok || strings.HasSuffix(sort.Key, fieldMissingSuffix) {
+			return nil, fmt.Errorf("unknown sort key %q", sort.Key)
+		}
+		if seen[sort.Key] {
+			return nil, fmt.Errorf("duplicate sort key %q", sort.Key)
+		}
+		seen[sort.Key] = true
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
+const (
+	// fieldSortPrefix starts the keys sorting by a custom field.
+	fieldSortPrefix = "field."
+	// fieldMissingSuffix makes the internal key ListTodos sorts by ahead of
+	// a custom field, so todos without a value come last either way.
+	fieldMissingSuffix = ".missing"
+)
+
+// todoSortKey looks up key among todoSortKeys and the custom field keys.
+func todoSortKey(key string) (sortKey, bool) {
+	if k, ok := todoSortKeys[key]; ok {
+		return k, true
+	}
+	name, missing := strings.CutSuffix(key, fieldMissingSuffix)
+	raw, ok := strings.CutPrefix(name, fieldSortPrefix)
+	if !ok {
+		return sortKey{}, false
+	}
+	fieldID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || fieldID <= 0 || strconv.FormatInt(fieldID, 10) != raw {
+		return sortKey{}, false
+	}
+	value := fmt.Sprintf(`(SELECT json_extract(fv.value, '$') FROM todo_field_values fv WHERE fv.todo_id = t.id AND fv.field_id = %d)`, fieldID)
+	if missing {
+		return sortKey{expr: `(` + value + ` IS NULL)`, numeric: true}, true
+	}
+	return sortKey{expr: `COALESCE(` + value + `, 0)`, mixed: true}, true
+}
+
+// expandFieldSorts puts the missing-value key ahead of every custom field
+// key in sorts.
+func expandFieldSorts(sorts []TodoSort) []TodoSort {
+	var expanded []TodoSort
+	for _, s := range sorts {
+		if strings.HasPrefix(s.Key, fieldSortPrefix) {
+			expanded = append(expanded, TodoSort{Key: s.Key + fieldMissingSuffix})
+		}
+		expanded = append(expanded, s)
+	}
+	return expanded
+}
+
+// todoCursor is the decoded form of the opaque cursor handed to clients: the
+// sort it was issued for and the sort values of the last todo on the page.
+type todoCursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// ListTodos returns a page of the todos userID can see and the cursor of the
+// next page, which is empty on the last one.
+func (db *DB) ListTodos(ctx context.Context, userID int64, f TodoFilter) ([]model.Todo, string, error) {
+	sorts := expandFieldSorts(f.Sort)
+	if !slices.ContainsFunc(sorts, func(s TodoSort) bool { return s.Key == "id" }) {
+		// id breaks ties so every todo has a distinct position.
+		sorts = append(sorts, TodoSort{Key: "id"})
+	}
+	spec := sortSpec(sorts)
+
+	var (
+		where []string
+		args  = []any{userID}
+	)
+	if f.ListID != 0 {
+		where = append(where, `t.list_id = ?`)
+		args = append(args, f.ListID)
+	}
+	if f.StatusID != 0 {
+		where = append(where, `t.status_id = ?`)
+		args = append(args, f.StatusID)
+	}
+	if f.Completed != nil {
+		where = append(where, `t.completed = ?`)
+		args = append(args, boolToInt(*f.Completed))
+	}
+	if f.CreatedAfter != nil {
+		where = append(where, todoSortKeys["createdAt"].expr+` >= ?`)
+		args = append(args, formatTime(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		where = append(where, todoSortKeys["createdAt"].expr+` < ?`)
+		args = append(args, formatTime(*f.CreatedBefore))
+	}
+	if f.TitleContains != "" {
+		where = append(where, `t.title LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(f.TitleContains)+"%")
+	}
+	for _, ff := range f.Fields {
+		if !slices.Contains(fieldFilterOps, ff.Op) {
+			return nil, "", fmt.Errorf("invalid field filter operator %q", ff.Op)
+		}
+		where = append(where, `EXISTS (SELECT 1 FROM todo_field_values fv WHERE fv.todo_id = t.id AND fv.field_id = ? AND json_extract(fv.value, '$') `+ff.Op+` ?)`)
+		args = append(args, ff.FieldID, ff.Value)
+	}
+	if f.Query != nil && f.Query.Expr != nil {
+		cond, queryArgs, err := compileTodoQuery(f.Query, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		where = append(where, cond)
+		args = append(args, queryArgs...)
+	}
+	if f.Cursor != "" {
+		values, err := decodeTodoCursor(f.Cursor, spec, sorts)
+		if err != nil {
+			return nil, "", err
+		}
+		predicate, predicateArgs := keysetAfter(sorts, values)
+		where = append(where, predicate)
+		args = append(args, predicateArgs...)
+	}
+
+	var (
+		keyColumns []string
+		orderBy    []string
+	)
+	for _, s := range sorts {
+		key, _ := todoSortKey(s.Key)
+		expr := key.expr
+		keyColumns = append(keyColumns, expr)
+		if s.Desc {
+			expr += ` DESC`
+		}
+		orderBy = append(orderBy, expr)
+	}
+	query := `SELECT ` + todoColumns + `, ` + strings.Join(keyColumns, ", ") + todoFrom + todoAccess
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY ` + strings.Join(orderBy, ", ") + ` LIMIT ?`
+	// One extra row tells whether there is a next page.
+	args = append(args, f.Limit+1)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	todos := []model.Todo{}
+	var last []any
+	for rows.Next() {
+		values := make([]any, len(sorts))
+		dest := make([]any, len(sorts))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		todo, err := scanTodo(rows, dest...)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(todos) == f.Limit {
+			return todos, encodeTodoCursor(spec, last), rows.Err()
+		}
+		todos = append(todos, *todo)
+		last = values
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	return todos, "", nil
+}
+
+// keysetAfter builds the predicate selecting the rows that sort after the
+// row with the given sort values.
+func keysetAfter(sorts []TodoSort, values []any) (string, []any) {
+	var (
+		clauses []string
+		args    []any
+	)
+	for i, s := range sorts {
+		var terms []string
+		for j := 0; j < i; j++ {
+			key, _ := todoSortKey(sorts[j].Key)
+			terms = append(terms, key.expr+` = ?`)
+			args = append(args, values[j])
+		}
+		op := ` > ?`
+		if s.Desc {
+			op = ` < ?`
+		}
+		key, _ := todoSortKey(s.Key)
+		terms = append(terms, key.expr+op)
+		args = append(args, values[i])
+		clauses = append(clauses, `(`+strings.Join(terms, " AND ")+`)`)
+	}
+	return `(` + strings.Join(clauses, " OR ") + `)`, args
+}
+
+func sortSpec(sorts []TodoSort) string {
+	parts := make([]string, len(sorts))
+	for i, s := range sorts {
+		parts[i] = s.Key
+		if s.Desc {
+			parts[i] = "-" + s.Key
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeTodoCursor(spec string, values []any) string {
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	data, _ := json.Marshal(todoCursor{Sort: spec, Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTodoCursor(cursor, spec string, sorts []TodoSort) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var c todoCursor
+	if err := dec.Decode(&c); err != nil || c.Sort != spec || len(c.Values) != len(sorts) {
+		return nil, ErrInvalidCursor
+	}
+	for i, s := range sorts {
+		key, _ := todoSortKey(s.Key)
+		switch v := c.Values[i].(type) {
+		case json.Number:
+			if !key.numeric && !key.mixed {
+				return nil, ErrInvalidCursor
+			}
+			n, err := v.Int64()
+			if err == nil {
+				c.Values[i] = n
+				break
+			}
+			f, err := v.Float64()
+			if err != nil || key.numeric {
+				return nil, ErrInvalidCursor
+			}
+			c.Values[i] = f
+		case string:
+			if key.numeric {
+				return nil, ErrInvalidCursor
+			}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return c.Values, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ListAssignedTodos returns the todos assigned to userID across every list
+// they belong to, soonest due first.
+func (db *DB) ListAssignedTodos(ctx context.Context, userID int64) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+` WHERE t.assignee_id = ?
+		ORDER BY t.due_at IS NULL, t.due_at, t.id`, userID, userID)
+}
+
+// ListTodosByCompletion returns every todo in listID with the given
+// completed state, when userID is a member of the list.
+func (db *DB) ListTodosByCompletion(ctx context.Context, userID, listID int64, completed bool) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+` WHERE t.list_id = ? AND t.completed = ? ORDER BY t.id`,
+		userID, listID, boolToInt(completed))
+}
+
+func (db *DB) queryTodos(ctx context.Context, query string, args ...any) ([]model.Todo, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var todos []model.Todo
+	todos := []model.Todo{}
 	for rows.Next() {
 		todo, err := scanTodo(rows)
 		if err != nil {
@@ -29,9 +406,22 @@ func (db *DB) ListTodosByUser(ctx context.Context, userID int64) ([]model.Todo,
 	return todos, nil
 }
 
+// CreateTodo inserts todo into its list. Callers check that the creator may
+// edit that list.
 func (db *DB) CreateTodo(ctx context.Context, todo *model.Todo) error {
 	now := time.Now().UTC()
-	res, err := db.ExecContext(ctx, `INSERT INTO todos (user_id, title, completed, created_at) VALUES (?, ?, ?, ?)`, todo.UserID, todo.Title, boolToInt(todo.Completed), now)
+	if todo.UID == "" {
+		todo.UID = uuid.NewString()
+	}
+	if todo.Tags == nil {
+		todo.Tags = []string{}
+	}
+	tags, err := json.Marshal(todo.Tags)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, `INSERT INTO todos (list_id, user_id, assignee_id, uid, title, notes, completed, completed_at, created_at, updated_at, due_at, rrule, timezone, repeat_mode, priority, tags, status_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		todo.ListID, todo.UserID, todo.AssigneeID, todo.UID, todo.Title, todo.Notes, boolToInt(todo.Completed), nullTime(todo.CompletedAt), formatTime(now), formatTime(now), nullTime(todo.DueAt), todo.RRule, todo.Timezone, todo.RepeatMode, todo.Priority, string(tags), nullID(todo.StatusID))
 	if err != nil {
 		return err
 	}
@@ -41,68 +431,318 @@ func (db *DB) CreateTodo(ctx context.Context, todo *model.Todo) error {
 	}
 	todo.ID = id
 	todo.CreatedAt = now
-	return nil
+	todo.UpdatedAt = now
+	todo.Version = 1
+	if err := db.saveTodoFields(ctx, todo); err != nil {
+		return err
+	}
+	return db.loadTodoSummaries(ctx, todo)
+}
+
+// UpdateTodo saves todo when userID is an editor or owner of the list it is
+// currently in and todo.Version is still the stored version, which it then
+// advances. A stale version reports ErrVersionConflict.
+func (db *DB) UpdateTodo(ctx context.Context, userID int64, todo *model.Todo) error {
+	now := time.Now().UTC()
+	if todo.Tags == nil {
+		todo.Tags = []string{}
+	}
+	tags, err := json.Marshal(todo.Tags)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, `UPDATE todos SET list_id = ?, assignee_id = ?, title = ?, notes = ?, completed = ?, completed_at = ?, due_at = ?, rrule = ?, timezone = ?, repeat_mode = ?,
+			priority = ?, tags = ?, status_id = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND version = ? AND deleted_at IS NULL AND list_id IN (SELECT m.list_id FROM list_members m WHERE m.user_id = ? AND `+roleAtLeast[model.RoleEditor]+`)`,
+		todo.ListID, todo.AssigneeID, todo.Title, todo.Notes, boolToInt(todo.Completed), nullTime(todo.CompletedAt), nullTime(todo.DueAt), todo.RRule, todo.Timezone, todo.RepeatMode,
+		todo.Priority, string(tags), nullID(todo.StatusID), formatTime(now), todo.ID, todo.Version, userID)
+	if err != nil {
+		return err
+	}
+	if err := db.versionedAccessResult(ctx, res, todoAccess, userID, todo.ID, todo.Version); err != nil {
+		return err
+	}
+	todo.Version++
+	todo.UpdatedAt = now
+	if err := db.saveTodoFields(ctx, todo); err != nil {
+		return err
+	}
+	return db.loadTodoSummaries(ctx, todo)
 }
 
-func (db *DB) UpdateTodo(ctx context.Context, todo *model.Todo) error {
-	_, err := db.ExecContext(ctx, `UPDATE todos SET title = ?, completed = ? WHERE id = ? AND user_id = ?`, todo.Title, boolToInt(todo.Completed), todo.ID, todo.UserID)
+// UnassignListMember clears the assignments userID holds in listID, for when
+// they lose access to it.
+func (db *DB) UnassignListMember(ctx context.Context, listID, userID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE todos SET assignee_id = NULL, version = version + 1, updated_at = ? WHERE list_id = ? AND assignee_id = ?`,
+		formatTime(time.Now().UTC()), listID, userID)
 	return err
 }
 
-func (db *DB) DeleteTodo(ctx context.Context, userID, todoID int64) error {
-	res, err := db.ExecContext(ctx, `DELETE FROM todos WHERE id = ? AND user_id = ?`, todoID, userID)
+// loadTodoSummaries fills the creator, assignee and status summaries of a
+// todo that was just written.
+func (db *DB) loadTodoSummaries(ctx context.Context, todo *model.Todo) error {
+	todo.Creator = model.UserSummary{ID: todo.UserID}
+	if err := db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = ?`, todo.UserID).Scan(&todo.Creator.Username); err != nil {
+		return err
+	}
+	todo.Assignee = nil
+	if todo.AssigneeID != nil {
+		assignee := model.UserSummary{ID: *todo.AssigneeID}
+		if err := db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = ?`, assignee.ID).Scan(&assignee.Username); err != nil {
+			return err
+		}
+		todo.Assignee = &assignee
+	}
+	todo.Status = nil
+	if todo.StatusID != 0 {
+		status := model.StatusSummary{ID: todo.StatusID}
+		if err := db.QueryRowContext(ctx, `SELECT name, category FROM list_statuses WHERE id = ?`, status.ID).Scan(&status.Name, &status.Category); err != nil {
+			return err
+		}
+		todo.Status = &status
+	}
+	return nil
+}
+
+// DeleteTodo moves todoID to the trash when userID is an editor or owner of
+// its list. Unless ifVersion is zero, the todo must still be at that version
+// or ErrVersionConflict is returned.
+func (db *DB) DeleteTodo(ctx context.Context, userID, todoID, ifVersion int64) error {
+	now := formatTime(time.Now().UTC())
+	res, err := db.ExecContext(ctx, `UPDATE todos SET deleted_at = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND (? = 0 OR version = ?) AND deleted_at IS NULL AND list_id IN (SELECT m.list_id FROM list_members m WHERE m.user_id = ? AND `+roleAtLeast[model.RoleEditor]+`)`,
+		now, now, todoID, ifVersion, ifVersion, userID)
 	if err != nil {
 		return err
 	}
-	affected, err := res.RowsAffected()
+	return db.versionedAccessResult(ctx, res, todoAccess, userID, todoID, ifVersion)
+}
+
+// RestoreTodo takes todoID out of the trash when userID is an editor or owner
+// of its list.
+func (db *DB) RestoreTodo(ctx context.Context, userID, todoID int64) error {
+	res, err := db.ExecContext(ctx, `UPDATE todos SET deleted_at = NULL, version = version + 1, updated_at = ?
+		WHERE id = ? AND deleted_at IS NOT NULL AND list_id IN (SELECT m.list_id FROM list_members m WHERE m.user_id = ? AND `+roleAtLeast[model.RoleEditor]+`)`,
+		formatTime(time.Now().UTC()), todoID, userID)
 	if err != nil {
 		return err
 	}
-	if affected == 0 {
-		return ErrNotFound
+	return db.todoAccessResult(ctx, res, trashAccess, userID, todoID)
+}
+
+// ListTrash returns the deleted todos in the lists userID belongs to, most
+// recently deleted first.
+func (db *DB) ListTrash(ctx context.Context, userID int64) ([]model.Todo, error) {
+	return db.queryTodos(ctx, `SELECT `+todoColumns+todoFrom+trashAccess+` ORDER BY t.deleted_at DESC, t.id DESC`, userID)
+}
+
+// PurgeTrash permanently deletes the todos that were moved to the trash
+// before cutoff, along with their attachments and comments.
+func (db *DB) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM todos WHERE deleted_at IS NOT NULL AND deleted_at < ?`, formatTime(cutoff))
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return res.RowsAffected()
 }
 
+// GetTodo returns todoID when userID is a member of its list.
 func (db *DB) GetTodo(ctx context.Context, userID, todoID int64) (*model.Todo, error) {
-	row := db.QueryRowContext(ctx, `SELECT id, user_id, title, completed, created_at FROM todos WHERE id = ? AND user_id = ?`, todoID, userID)
+	row := db.QueryRowContext(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+` WHERE t.id = ?`, userID, todoID)
+	return scanTodo(row)
+}
+
+// GetTodoByUID returns the oldest todo with uid that userID can see, in
+// listID unless it is zero.
+func (db *DB) GetTodoByUID(ctx context.Context, userID, listID int64, uid string) (*model.Todo, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+todoColumns+todoFrom+todoAccess+` WHERE t.uid = ? AND (? = 0 OR t.list_id = ?) ORDER BY t.id LIMIT 1`,
+		userID, uid, listID, listID)
 	return scanTodo(row)
 }
 
-type todoScanner interface {
+// TodoRole returns userID's role on the list holding todoID, or ErrNotFound
+// when they cannot see it.
+func (db *DB) TodoRole(ctx context.Context, userID, todoID int64) (string, error) {
+	return db.todoRole(ctx, todoAccess, userID, todoID)
+}
+
+// todoRole looks up userID's role on todoID through access, which is either
+// todoAccess or trashAccess.
+func (db *DB) todoRole(ctx context.Context, access string, userID, todoID int64) (string, error) {
+	var role string
+	err := db.QueryRowContext(ctx, `SELECT m.role FROM todos t`+access+` WHERE t.id = ?`, userID, todoID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+// TodoAudience returns the IDs of the users who can see todoID.
+func (db *DB) TodoAudience(ctx context.Context, todoID int64) ([]int64, error) {
+	return db.queryIDs(ctx, `SELECT m.user_id FROM todos t JOIN list_members m ON m.list_id = t.list_id WHERE t.id = ?`, todoID)
+}
+
+// todoAccessResult turns a write that matched no rows into ErrNotFound when
+// userID cannot see todoID through access, or ErrForbidden when their role is
+// too low.
+func (db *DB) todoAccessResult(ctx context.Context, res sql.Result, access string, userID, todoID int64) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	if _, err := db.todoRole(ctx, access, userID, todoID); err != nil {
+		return err
+	}
+	return ErrForbidden
+}
+
+// versionedAccessResult is todoAccessResult for writes conditional on the
+// todo being at version, where zero means any version. An editor whose write
+// missed because the version moved on gets ErrVersionConflict.
+func (db *DB) versionedAccessResult(ctx context.Context, res sql.Result, access string, userID, todoID, version int64) error {
+	err := db.todoAccessResult(ctx, res, access, userID, todoID)
+	if err != ErrForbidden || version == 0 {
+		return err
+	}
+	var current int64
+	err = db.QueryRowContext(ctx, `SELECT t.version FROM todos t`+access+` WHERE t.id = ? AND `+roleAtLeast[model.RoleEditor],
+		userID, todoID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if current != version {
+		return ErrVersionConflict
+	}
+	return ErrForbidden
+}
+
+type rowScanner interface {
 	Scan(dest ...any) error
 }
 
-func scanTodo(scanner todoScanner) (*model.Todo, error) {
+// scanTodo scans the todoColumns of a row, followed by any extra columns
+// into extra.
+func scanTodo(scanner rowScanner, extra ...any) (*model.Todo, error) {
 	var (
-		t         model.Todo
-		completed int
-		createdAt any
+		t                model.Todo
+		completed        int
+		createdAt        any
+		dueAt            any
+		deletedAt        any
+		updatedAt        any
+		assigneeID       sql.NullInt64
+		assigneeUsername sql.NullString
+		tags             string
+		completedAt      any
+		statusID         sql.NullInt64
+		statusName       sql.NullString
+		statusCategory   sql.NullString
+		blocked          int
+		fields           string
 	)
-	if err := scanner.Scan(&t.ID, &t.UserID, &t.Title, &completed, &createdAt); err != nil {
+	dest := []any{&t.ID, &t.ListID, &t.UserID, &t.Creator.Username, &assigneeID, &assigneeUsername, &t.Title, &t.Notes, &completed, &createdAt, &dueAt, &t.RRule, &t.Timezone, &t.RepeatMode, &deletedAt, &t.Version, &updatedAt, &t.UID, &t.Priority, &tags, &completedAt, &statusID, &statusName, &statusCategory, &blocked, &fields}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	t.Creator.ID = t.UserID
+	if assigneeID.Valid {
+		t.AssigneeID = &assigneeID.Int64
+		t.Assignee = &model.UserSummary{ID: assigneeID.Int64, Username: assigneeUsername.String}
+	}
+	if statusID.Valid {
+		t.StatusID = statusID.Int64
+		t.Status = &model.StatusSummary{ID: statusID.Int64, Name: statusName.String, Category: statusCategory.String}
+	}
 	t.Completed = completed == 1
-	switch v := createdAt.(type) {
+	t.Blocked = blocked == 1
+	if parsed, ok := parseTime(createdAt); ok {
+		t.CreatedAt = parsed
+	}
+	if parsed, ok := parseTime(completedAt); ok {
+		t.CompletedAt = &parsed
+	}
+	if parsed, ok := parseTime(dueAt); ok {
+		t.DueAt = &parsed
+	}
+	if parsed, ok := parseTime(deletedAt); ok {
+		t.DeletedAt = &parsed
+	}
+	if parsed, ok := parseTime(updatedAt); ok {
+		t.UpdatedAt = parsed
+	}
+	if err := json.Unmarshal([]byte(tags), &t.Tags); err != nil || t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if err := json.Unmarshal([]byte(fields), &t.Fields); err != nil || t.Fields == nil {
+		t.Fields = map[int64]any{}
+	}
+	return &t, nil
+}
+
+// timeLayout is the layout used for every timestamp the repository writes.
+// It sorts lexically. CURRENT_TIMESTAMP, which the schema's created_at
+// columns default to and which older rows were backfilled from, writes
+// "2006-01-02 15:04:05" without milliseconds, so columns that may hold such
+// values are compared through sqlTime.
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// sqlTime is the SQL expression normalising the timestamp in column to
+// timeLayout.
+func sqlTime(column string) string {
+	return `strftime('%Y-%m-%d %H:%M:%f', ` + column + `)`
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// parseTime converts a scanned DATETIME value into a time.Time. SQLite hands
+// back either a parsed time, a string or raw bytes depending on how the row
+// was written; NULL reports false.
+func parseTime(v any) (time.Time, bool) {
+	switch v := v.(type) {
 	case time.Time:
-		t.CreatedAt = v
+		return v, true
 	case string:
-		if parsed, err := time.Parse("2006-01-02 15:04:05", v); err == nil {
-			t.CreatedAt = parsed
-		} else if parsed, err := time.Parse(time.RFC3339Nano, v); err == nil {
-			t.CreatedAt = parsed
-		}
+		return parseTimeString(v)
 	case []byte:
-		if parsed, err := time.Parse("2006-01-02 15:04:05", string(v)); err == nil {
-			t.CreatedAt = parsed
-		}
+		return parseTimeString(string(v))
 	default:
-		// leave zero value
+		return time.Time{}, false
 	}
-	return &t, nil
+}
+
+func parseTimeString(s string) (time.Time, bool) {
+	for _, layout := range []string{timeLayout, "2006-01-02 15:04:05", time.RFC3339Nano} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nullID stores a zero ID as NULL.
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
 }
 
 func boolToInt(b bool) int {
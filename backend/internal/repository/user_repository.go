@@ -29,8 +29,8 @@ func (db *DB) CreateUser(ctx context.Context, user *model.User) error {
 
 func (db *DB) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := db.QueryRowContext(ctx, `SELECT id, username, password_hash, created_at FROM users WHERE username = ?`, username).
-		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	err := db.QueryRowContext(ctx, `SELECT id, username, password_hash, timezone, created_at FROM users WHERE username = ?`, username).
+		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Timezone, &user.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
@@ -42,8 +42,8 @@ func (db *DB) GetUserByUsername(ctx context.Context, username string) (*model.Us
 
 func (db *DB) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	var user model.User
-	err := db.QueryRowContext(ctx, `SELECT id, username, password_hash, created_at FROM users WHERE id = ?`, id).
-		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	err := db.QueryRowContext(ctx, `SELECT id, username, password_hash, timezone, created_at FROM users WHERE id = ?`, id).
+		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Timezone, &user.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
@@ -53,8 +53,23 @@ func (db *DB) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	return &user, nil
 }
 
+func (db *DB) SetUserTimezone(ctx context.Context, id int64, timezone string) error {
+	res, err := db.ExecContext(ctx, `UPDATE users SET timezone = ? WHERE id = ?`, timezone, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (db *DB) ListUsers(ctx context.Context) ([]model.User, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, username, password_hash, created_at FROM users ORDER BY id`)
+	rows, err := db.QueryContext(ctx, `SELECT id, username, password_hash, timezone, created_at FROM users ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +78,7 @@ func (db *DB) ListUsers(ctx context.Context) ([]model.User, error) {
 	var users []model.User
 	for rows.Next() {
 		var user model.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Timezone, &user.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+const viewColumns = `v.id, v.name, v.query, v.sort, v.created_at, v.updated_at`
+
+// ListViews returns userID's saved views by name.
+func (db *DB) ListViews(ctx context.Context, userID int64) ([]model.View, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+viewColumns+` FROM views v WHERE v.user_id = ? ORDER BY v.name COLLATE NOCASE, v.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []model.View{}
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// GetView returns one of userID's views.
+func (db *DB) GetView(ctx context.Context, userID, id int64) (*model.View, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+viewColumns+` FROM views v WHERE v.id = ? AND v.user_id = ?`, id, userID)
+	return scanView(row)
+}
+
+// CreateView stores view for userID. A name userID already uses fails with
+// a UNIQUE constraint error.
+func (db *DB) CreateView(ctx context.Context, userID int64, view *model.View) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO views (user_id, name, query, sort, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, view.Name, view.Query, view.Sort, formatTime(now), formatTime(now))
+	if err != nil {
+		return err
+	}
+	if view.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	view.CreatedAt = now
+	view.UpdatedAt = now
+	return nil
+}
+
+// UpdateView saves the name, query and sort of one of userID's views.
+func (db *DB) UpdateView(ctx context.Context, userID int64, view *model.View) error {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `UPDATE views SET name = ?, query = ?, sort = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		view.Name, view.Query, view.Sort, formatTime(now), view.ID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	view.UpdatedAt = now
+	return nil
+}
+
+// DeleteView deletes one of userID's views.
+func (db *DB) DeleteView(ctx context.Context, userID, id int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM views WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanView(scanner rowScanner) (*model.View, error) {
+	var (
+		v                    model.View
+		createdAt, updatedAt any
+	)
+	if err := scanner.Scan(&v.ID, &v.Name, &v.Query, &v.Sort, &createdAt, &updatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	v.CreatedAt, _ = parseTime(createdAt)
+	v.UpdatedAt, _ = parseTime(updatedAt)
+	return &v, nil
+}
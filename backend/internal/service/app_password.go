@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var ErrAppPasswordName = errors.New("name is required")
+
+// appPasswordBytes is the entropy of a generated app password. Being random,
+// it is safe to store as a plain SHA-256 hash, which is cheap enough to check
+// on every request.
+const appPasswordBytes = 20
+
+// CreateAppPassword generates a new app password for userID. The returned
+// password is the only time it is available in plain text.
+func (s *UserService) CreateAppPassword(ctx context.Context, userID int64, name string) (*model.AppPassword, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrAppPasswordName
+	}
+	secret := make([]byte, appPasswordBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	password := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+	p := &model.AppPassword{Name: name}
+	if err := s.db.CreateAppPassword(ctx, userID, p, hashAppPassword(password)); err != nil {
+		return nil, err
+	}
+	p.Password = password
+	return p, nil
+}
+
+func (s *UserService) AppPasswords(ctx context.Context, userID int64) ([]model.AppPassword, error) {
+	return s.db.ListAppPasswords(ctx, userID)
+}
+
+func (s *UserService) DeleteAppPassword(ctx context.Context, userID, id int64) error {
+	return s.db.DeleteAppPassword(ctx, userID, id)
+}
+
+// AuthenticateAppPassword returns the ID of the user called username when
+// password is one of their app passwords.
+func (s *UserService) AuthenticateAppPassword(ctx context.Context, username, password string) (int64, error) {
+	userID, err := s.db.UseAppPassword(ctx, username, hashAppPassword(password))
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrInvalidCredentials
+	}
+	return userID, err
+}
+
+func hashAppPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/blob"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+func NewAttachmentService(db *repository.DB, store blob.BlobStore, maxSize, quota int64) *AttachmentService {
+	return &AttachmentService{db: db, store: store, maxSize: maxSize, quota: quota}
+}
+
+// AttachmentService stores attachment metadata in SQLite and contents in a
+// BlobStore. maxSize caps a single file and quota caps the total bytes a user
+// may upload.
+type AttachmentService struct {
+	db      *repository.DB
+	store   blob.BlobStore
+	maxSize int64
+	quota   int64
+}
+
+func (s *AttachmentService) List(ctx context.Context, userID, todoID int64) ([]model.Attachment, error) {
+	if _, err := s.db.GetTodo(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	return s.db.ListAttachments(ctx, todoID)
+}
+
+// Upload streams r into the blob store as a new attachment of todoID. The
+// content type is sniffed from the data rather than trusted from the client.
+func (s *AttachmentService) Upload(ctx context.Context, userID, todoID int64, filename string, r io.Reader) (*model.Attachment, error) {
+	if err := s.requireEditor(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	used, err := s.db.AttachmentBytesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	limit, limitErr := s.maxSize, ErrAttachmentTooLarge
+	if remaining := s.quota - used; remaining < limit {
+		limit, limitErr = remaining, ErrQuotaExceeded
+	}
+	if limit <= 0 {
+		return nil, ErrQuotaExceeded
+	}
+
+	// Spool to disk first: the size must be known before storing, and some
+	// blob stores need it up front.
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, limitErr
+	}
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	key, err := newStorageKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	a := &model.Attachment{
+		TodoID:      todoID,
+		UserID:      userID,
+		Filename:    cleanFilename(filename),
+		ContentType: http.DetectContentType(head[:n]),
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := s.store.Put(ctx, key, tmp, size, a.ContentType); err != nil {
+		return nil, fmt.Errorf("store attachment: %w", err)
+	}
+	err = s.db.WithTx(ctx, func(tx *repository.DB) error {
+		// Re-check inside the transaction so concurrent uploads cannot
+		// overshoot the quota together.
+		used, err := tx.AttachmentBytesByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if used+size > s.quota {
+			return ErrQuotaExceeded
+		}
+		return tx.CreateAttachment(ctx, a)
+	})
+	if err != nil {
+		_ = s.store.Delete(ctx, key)
+		return nil, err
+	}
+	return a, nil
+}
+
+// Open returns the metadata and contents of an attachment. The caller must
+// close the returned reader.
+func (s *AttachmentService) Open(ctx context.Context, userID, todoID, attachmentID int64) (*model.Attachment, io.ReadCloser, error) {
+	if _, err := s.db.GetTodo(ctx, userID, todoID); err != nil {
+		return nil, nil, err
+	}
+	a, err := s.db.GetAttachment(ctx, todoID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := s.store.Get(ctx, a.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, body, nil
+}
+
+func (s *AttachmentService) Delete(ctx context.Context, userID, todoID, attachmentID int64) error {
+	if err := s.requireEditor(ctx, userID, todoID); err != nil {
+		return err
+	}
+	if err := s.db.DeleteAttachment(ctx, todoID, attachmentID); err != nil {
+		return err
+	}
+	// The row is gone either way; a failed blob delete is retried by the sweeper.
+	if err := s.SweepOrphans(ctx); err != nil {
+		log.Printf("attachment sweeper: %v", err)
+	}
+	return nil
+}
+
+func (s *AttachmentService) requireEditor(ctx context.Context, userID, todoID int64) error {
+	role, err := s.db.TodoRole(ctx, userID, todoID)
+	if err != nil {
+		return err
+	}
+	if !roleAtLeast(role, model.RoleEditor) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// SweepOrphans deletes blobs whose attachment rows are gone, whether they were
+// removed directly or by cascading deletes of their todo or user.
+func (s *AttachmentService) SweepOrphans(ctx context.Context) error {
+	for {
+		keys, err := s.db.ListOrphanedBlobs(ctx, 100)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		for _, key := range keys {
+			if err := s.store.Delete(ctx, key); err != nil {
+				return fmt.Errorf("delete blob %s: %w", key, err)
+			}
+			if err := s.db.DeleteOrphanedBlob(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// RunSweeper calls SweepOrphans every interval until ctx is cancelled.
+func (s *AttachmentService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.SweepOrphans(ctx); err != nil {
+				log.Printf("attachment sweeper: %v", err)
+			}
+		}
+	}
+}
+
+func newStorageKey(userID int64) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%s", userID, hex.EncodeToString(b[:])), nil
+}
+
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// Batch operation kinds.
+const (
+	BatchCreate          = "create"
+	BatchUpdate          = "update"
+	BatchDelete          = "delete"
+	BatchCompleteAll     = "completeAll"
+	BatchDeleteCompleted = "deleteCompleted"
+)
+
+var ErrInvalidBatchOp = errors.New("invalid batch operation")
+
+// BatchOp is one operation of a batch. Which fields are used depends on Op:
+// create and update use Apply, update and delete use TodoID, and completeAll
+// and deleteCompleted use ListID.
+type BatchOp struct {
+	Op     string
+	TodoID int64
+	ListID int64
+	// IfVersion, when non-zero, makes update and delete fail with
+	// repository.ErrVersionConflict unless the todo is at that version.
+	IfVersion int64
+	// Apply fills in a new todo for create, or applies the requested
+	// changes to the current state of the todo for update.
+	Apply func(todo *model.Todo) error
+}
+
+// BatchResult is the outcome of one BatchOp. Todo is the created or updated
+// todo and Affected lists the todos changed by completeAll and
+// deleteCompleted.
+type BatchResult struct {
+	Todo     *model.Todo
+	Affected []int64
+	Err      error
+}
+
+// BatchError reports the operation that aborted an atomic batch.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch applies ops on behalf of userID in a single transaction. When atomic
+// is set, the first failing operation rolls back the whole batch and Batch
+// returns a *BatchError; otherwise each operation runs in its own savepoint
+// and failures are only reported in its result. Members of the affected lists
+// receive the committed changes as one EventTodoBatch each.
+func (s *TodoService) Batch(ctx context.Context, userID int64, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		for i, op := range ops {
+			var opOut outbox
+			run := func(tx *repository.DB) error {
+				var err error
+				results[i], err = s.applyBatchOp(ctx, tx, userID, op, &opOut)
+				return err
+			}
+			if atomic {
+				if err := run(tx); err != nil {
+					results[i] = BatchResult{Err: err}
+					return &BatchError{Index: i, Err: err}
+				}
+			} else if err := tx.WithSavepoint(ctx, run); err != nil {
+				results[i] = BatchResult{Err: err}
+				continue
+			}
+			out = append(out, opOut...)
+		}
+		return nil
+	})
+	if err != nil {
+		return results, err
+	}
+	s.flushBatch(out)
+	return results, nil
+}
+
+func (s *TodoService) applyBatchOp(ctx context.Context, tx *repository.DB, userID int64, op BatchOp, out *outbox) (BatchResult, error) {
+	switch op.Op {
+	case BatchCreate:
+		if op.Apply == nil {
+			return BatchResult{}, fmt.Errorf("%w: create needs a todo", ErrInvalidBatchOp)
+		}
+		todo := &model.Todo{}
+		if err := op.Apply(todo); err != nil {
+			return BatchResult{}, err
+		}
+		todo.UserID = userID
+		if err := s.create(ctx, tx, todo, out); err != nil {
+			return BatchResult{}, err
+		}
+		return BatchResult{Todo: todo}, nil
+	case BatchUpdate:
+		todo, err := tx.GetTodo(ctx, userID, op.TodoID)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		if op.IfVersion != 0 && todo.Version != op.IfVersion {
+			return BatchResult{}, repository.ErrVersionConflict
+		}
+		if op.Apply != nil {
+			if err := op.Apply(todo); err != nil {
+				return BatchResult{}, err
+			}
+		}
+		if _, err := s.updateTx(ctx, tx, userID, todo, model.RevisionUpdated, nil, out); err != nil {
+			return BatchResult{}, err
+		}
+		return BatchResult{Todo: todo}, nil
+	case BatchDelete:
+		if err := s.delete(ctx, tx, userID, op.TodoID, op.IfVersion, out); err != nil {
+			return BatchResult{}, err
+		}
+		return BatchResult{Affected: []int64{op.TodoID}}, nil
+	case BatchCompleteAll, BatchDeleteCompleted:
+		if err := requireListRole(ctx, tx, userID, op.ListID, model.RoleEditor); err != nil {
+			return BatchResult{}, err
+		}
+		completeAll := op.Op == BatchCompleteAll
+		todos, err := tx.ListTodosByCompletion(ctx, userID, op.ListID, !completeAll)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		affected := []int64{}
+		for i := range todos {
+			todo := &todos[i]
+			if completeAll {
+				todo.Completed = true
+				_, err = s.updateTx(ctx, tx, userID, todo, model.RevisionUpdated, nil, out)
+			} else {
+				err = s.delete(ctx, tx, userID, todo.ID, todo.Version, out)
+			}
+			if err != nil {
+				return BatchResult{}, err
+			}
+			affected = append(affected, todo.ID)
+		}
+		return BatchResult{Affected: affected}, nil
+	default:
+		return BatchResult{}, fmt.Errorf("%w: unknown op %q", ErrInvalidBatchOp, op.Op)
+	}
+}
+
+// flushBatch publishes the events of a committed batch. Todo changes are
+// combined into one EventTodoBatch per recipient holding what they can see;
+// other events are published as usual.
+func (s *TodoService) flushBatch(out outbox) {
+	batches := make(map[int64]*TodoBatch)
+	var order []int64
+	for _, ev := range out {
+		for _, userID := range ev.audience {
+			b, ok := batches[userID]
+			if !ok {
+				b = &TodoBatch{}
+				batches[userID] = b
+				order = append(order, userID)
+			}
+			switch payload := ev.payload.(type) {
+			case *model.Todo:
+				if ev.name == EventTodoCreated {
+					b.Created = append(b.Created, payload)
+				} else {
+					b.Updated = append(b.Updated, payload)
+				}
+			case TodoDeleted:
+				b.Deleted = append(b.Deleted, payload.ID)
+			case TodoAssigned:
+				b.Assigned = append(b.Assigned, payload)
+			default:
+				s.publish(ev.name, ev.payload, []int64{userID})
+			}
+		}
+	}
+	for _, userID := range order {
+		if b := batches[userID]; !b.empty() {
+			s.publish(EventTodoBatch, b, []int64{userID})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// ErrForbidden reports that the caller can see a resource but their role does
+// not allow the requested change.
+var ErrForbidden = repository.ErrForbidden
+
+func NewCommentService(db *repository.DB) *CommentService {
+	return &CommentService{db: db}
+}
+
+type CommentService struct {
+	db     *repository.DB
+	events Publisher
+}
+
+// SetPublisher makes the service announce comment changes to everyone who
+// can see the todo.
+func (s *CommentService) SetPublisher(events Publisher) {
+	s.events = events
+}
+
+func (s *CommentService) publish(ctx context.Context, event string, todoID int64, payload any) {
+	if s.events == nil {
+		return
+	}
+	audience, err := s.db.TodoAudience(ctx, todoID)
+	if err == nil && len(audience) > 0 {
+		s.events.Publish(event, payload, audience)
+	}
+}
+
+// List returns up to limit comments on todoID posted after the comment with
+// ID afterID, oldest first.
+func (s *CommentService) List(ctx context.Context, userID, todoID, afterID int64, limit int) ([]model.Comment, error) {
+	if _, err := s.db.GetTodo(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	return s.db.ListComments(ctx, todoID, afterID, limit)
+}
+
+func (s *CommentService) Create(ctx context.Context, userID, todoID int64, body string) (*model.Comment, error) {
+	if _, err := s.db.GetTodo(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	comment := &model.Comment{TodoID: todoID, Author: model.UserSummary{ID: userID}, Body: body}
+	if err := s.db.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	s.publish(ctx, EventCommentCreated, todoID, comment)
+	return comment, nil
+}
+
+// Update replaces the body of a comment. Only its author, the creator of the
+// todo or an owner of its list may edit it.
+func (s *CommentService) Update(ctx context.Context, userID, todoID, commentID int64, body string) (*model.Comment, error) {
+	comment, err := s.authorize(ctx, userID, todoID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	comment.Body = body
+	if err := s.db.UpdateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	s.publish(ctx, EventCommentUpdated, todoID, comment)
+	return comment, nil
+}
+
+// Delete removes a comment. Only its author, the creator of the todo or an
+// owner of its list may delete it.
+func (s *CommentService) Delete(ctx context.Context, userID, todoID, commentID int64) error {
+	if _, err := s.authorize(ctx, userID, todoID, commentID); err != nil {
+		return err
+	}
+	if err := s.db.DeleteComment(ctx, todoID, commentID); err != nil {
+		return err
+	}
+	s.publish(ctx, EventCommentDeleted, todoID, CommentDeleted{ID: commentID, TodoID: todoID})
+	return nil
+}
+
+func (s *CommentService) authorize(ctx context.Context, userID, todoID, commentID int64) (*model.Comment, error) {
+	todo, err := s.db.GetTodo(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.db.GetComment(ctx, todoID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Author.ID == userID || todo.UserID == userID {
+		return comment, nil
+	}
+	role, err := s.db.TodoRole(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if role != model.RoleOwner {
+		return nil, ErrForbidden
+	}
+	return comment, nil
+}
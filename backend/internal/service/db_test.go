@@ -0,0 +1,41 @@
+//go:build sqlite_fts5
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/migrations"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// newTestDB opens a migrated database in a temporary directory.
+func newTestDB(t *testing.T) *repository.DB {
+	t.Helper()
+	db, err := repository.NewDB("file:" + t.TempDir() + "/test.db?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Up(db.DB); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestList creates a user named username and a list they own.
+func newTestList(t *testing.T, db *repository.DB, username string) (userID, listID int64) {
+	t.Helper()
+	ctx := context.Background()
+	user := &model.User{Username: username, PasswordHash: "x"}
+	if err := db.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	list := &model.List{Name: username + "'s list", CreatedBy: user.ID}
+	if err := db.CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, list.ID
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var (
+	ErrDependencyCycle = errors.New("a todo cannot be blocked by itself, directly or through other todos")
+	ErrTodoBlocked     = errors.New("todo is blocked by open todos")
+)
+
+// Dependencies returns the todos blocking todoID and those it blocks, among
+// the todos userID can see.
+func (s *TodoService) Dependencies(ctx context.Context, userID, todoID int64) (*model.Dependencies, error) {
+	todo, err := s.db.GetTodo(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	deps := &model.Dependencies{Blocked: todo.Blocked}
+	if deps.BlockedBy, err = s.db.ListBlockers(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	if deps.Blocking, err = s.db.ListBlocked(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// AddDependency makes blockerID block todoID. userID must be able to edit the
+// list of todoID and see blockerID. A dependency closing a cycle fails with
+// ErrDependencyCycle.
+func (s *TodoService) AddDependency(ctx context.Context, userID, todoID, blockerID int64) (*model.Dependencies, error) {
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		todo, err := tx.GetTodo(ctx, userID, todoID)
+		if err != nil {
+			return err
+		}
+		if err := requireListRole(ctx, tx, userID, todo.ListID, model.RoleEditor); err != nil {
+			return err
+		}
+		if _, err := tx.GetTodo(ctx, userID, blockerID); err != nil {
+			return err
+		}
+		if todoID == blockerID {
+			return ErrDependencyCycle
+		}
+		cycle, err := tx.Blocks(ctx, todoID, blockerID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+		if err := tx.AddTodoDependency(ctx, todoID, blockerID); err != nil {
+			return err
+		}
+		return addBlockedChange(ctx, tx, userID, todo, &out)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flush(out)
+	return s.Dependencies(ctx, userID, todoID)
+}
+
+// RemoveDependency stops blockerID from blocking todoID. userID must be able
+// to edit the list of todoID.
+func (s *TodoService) RemoveDependency(ctx context.Context, userID, todoID, blockerID int64) error {
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		todo, err := tx.GetTodo(ctx, userID, todoID)
+		if err != nil {
+			return err
+		}
+		if err := requireListRole(ctx, tx, userID, todo.ListID, model.RoleEditor); err != nil {
+			return err
+		}
+		if err := tx.DeleteTodoDependency(ctx, todoID, blockerID); err != nil {
+			return err
+		}
+		return addBlockedChange(ctx, tx, userID, todo, &out)
+	})
+	if err != nil {
+		return err
+	}
+	s.flush(out)
+	return nil
+}
+
+// addBlockedChange announces todo, as it was before its blockers changed, as
+// updated when it has become blocked or unblocked.
+func addBlockedChange(ctx context.Context, tx *repository.DB, userID int64, prev *model.Todo, out *outbox) error {
+	todo, err := tx.GetTodo(ctx, userID, prev.ID)
+	if err != nil || todo.Blocked == prev.Blocked {
+		return err
+	}
+	audience, err := tx.ListMemberIDs(ctx, todo.ListID)
+	if err != nil {
+		return err
+	}
+	out.add(EventTodoUpdated, todo, audience)
+	return nil
+}
+
+// addDependents announces the todos blockerID blocks as updated, after the
+// blocker was completed, reopened, deleted or restored.
+func addDependents(ctx context.Context, tx *repository.DB, blockerID int64, out *outbox) error {
+	dependents, err := tx.ListDependentTodos(ctx, blockerID)
+	if err != nil {
+		return err
+	}
+	for i := range dependents {
+		audience, err := tx.ListMemberIDs(ctx, dependents[i].ListID)
+		if err != nil {
+			return err
+		}
+		out.add(EventTodoUpdated, &dependents[i], audience)
+	}
+	return nil
+}
+
+// requireUnblocked refuses to complete a blocked todo in a list that
+// enforces dependencies. prev is the todo before the change.
+func requireUnblocked(ctx context.Context, tx *repository.DB, userID int64, todo, prev *model.Todo) error {
+	if !todo.Completed || prev.Completed || !prev.Blocked {
+		return nil
+	}
+	list, err := tx.GetList(ctx, userID, todo.ListID)
+	if err != nil {
+		return err
+	}
+	if list.EnforceDependencies {
+		return ErrTodoBlocked
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+//go:build sqlite_fts5
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+// newTestTodos creates a todo in listID for each title.
+func newTestTodos(t *testing.T, s *TodoService, userID, listID int64, titles ...string) map[string]int64 {
+	t.Helper()
+	ids := map[string]int64{}
+	for _, title := range titles {
+		todo := &model.Todo{UserID: userID, ListID: listID, Title: title}
+		if err := s.Create(context.Background(), todo); err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = todo.ID
+	}
+	return ids
+}
+
+func TestAddDependencyCycles(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	userID, listID := newTestList(t, db, "alice")
+	s := NewTodoService(db)
+	ids := newTestTodos(t, s, userID, listID, "a", "b", "c", "d")
+	add := func(todo, blocker string) error {
+		_, err := s.AddDependency(ctx, userID, ids[todo], ids[blocker])
+		return err
+	}
+
+	if err := add("a", "a"); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("a blocking itself = %v, want ErrDependencyCycle", err)
+	}
+	// b blocks a, so a cannot block b.
+	if err := add("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := add("b", "a"); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("direct cycle = %v, want ErrDependencyCycle", err)
+	}
+	// c blocks b, which blocks a, so a cannot block c.
+	if err := add("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := add("c", "a"); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("transitive cycle = %v, want ErrDependencyCycle", err)
+	}
+	// Diamonds are not cycles.
+	if err := add("a", "c"); err != nil {
+		t.Errorf("second path from c to a = %v", err)
+	}
+	if err := add("d", "a"); err != nil {
+		t.Errorf("d after a = %v", err)
+	}
+
+	deps, err := s.Dependencies(ctx, userID, ids["a"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps.BlockedBy) != 2 || !deps.Blocked {
+		t.Errorf("a's dependencies = %+v, want blocked by b and c", deps)
+	}
+}
+
+func TestCompleteBlockedTodo(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	userID, listID := newTestList(t, db, "alice")
+	s := NewTodoService(db)
+	ids := newTestTodos(t, s, userID, listID, "paint", "buy paint", "sand")
+	if _, err := s.AddDependency(ctx, userID, ids["paint"], ids["buy paint"]); err != nil {
+		t.Fatal(err)
+	}
+	complete := func(title string) error {
+		todo, err := s.Get(ctx, userID, ids[title])
+		if err != nil {
+			t.Fatal(err)
+		}
+		todo.Completed = true
+		_, err = s.Update(ctx, userID, todo)
+		return err
+	}
+
+	enforce := true
+	if _, err := NewListService(db).Update(ctx, userID, listID, ListChanges{EnforceDependencies: &enforce}); err != nil {
+		t.Fatal(err)
+	}
+	if err := complete("paint"); !errors.Is(err, ErrTodoBlocked) {
+		t.Fatalf("completing a blocked todo = %v, want ErrTodoBlocked", err)
+	}
+	if todo, _ := s.Get(ctx, userID, ids["paint"]); todo.Completed {
+		t.Error("the blocked todo was completed")
+	}
+	if err := complete("sand"); err != nil {
+		t.Errorf("completing an unblocked todo = %v", err)
+	}
+	if err := complete("buy paint"); err != nil {
+		t.Fatal(err)
+	}
+	if err := complete("paint"); err != nil {
+		t.Errorf("completing a todo whose blocker is done = %v", err)
+	}
+
+	// Without enforcement a blocked todo can be completed.
+	ids = newTestTodos(t, s, userID, listID, "frame", "buy wood")
+	if _, err := s.AddDependency(ctx, userID, ids["frame"], ids["buy wood"]); err != nil {
+		t.Fatal(err)
+	}
+	enforce = false
+	if _, err := NewListService(db).Update(ctx, userID, listID, ListChanges{EnforceDependencies: &enforce}); err != nil {
+		t.Fatal(err)
+	}
+	if err := complete("frame"); err != nil {
+		t.Errorf("completing a blocked todo without enforcement = %v", err)
+	}
+}
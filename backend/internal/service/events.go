@@ -0,0 +1,101 @@
+package service
+
+import "github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+
+// Real-time event names. Each is served by its own WebSocket endpoint.
+const (
+	EventTodoCreated    = "todo:created"
+	EventTodoUpdated    = "todo:updated"
+	EventTodoDeleted    = "todo:deleted"
+	EventTodoAssigned   = "todo:assigned"
+	EventTodoRestored   = "todo:restored"
+	EventTodoBatch      = "todo:batch"
+	EventCommentCreated = "comment:created"
+	EventCommentUpdated = "comment:updated"
+	EventCommentDeleted = "comment:deleted"
+	EventTimerStarted   = "timer:started"
+	EventTimerStopped   = "timer:stopped"
+
+	EventNotificationCreated = "notification:created"
+)
+
+// Publisher delivers a real-time event to the connections of the given users.
+type Publisher interface {
+	Publish(event string, payload any, userIDs []int64)
+}
+
+// TodoDeleted is the payload of EventTodoDeleted.
+type TodoDeleted struct {
+	ID int64 `json:"id"`
+}
+
+// TodoAssigned is the payload of EventTodoAssigned. Assignee is nil when the
+// todo was unassigned.
+type TodoAssigned struct {
+	TodoID             int64              `json:"todoId"`
+	ListID             int64              `json:"listId"`
+	Assignee           *model.UserSummary `json:"assignee"`
+	PreviousAssigneeID *int64             `json:"previousAssigneeId"`
+	AssignedBy         int64              `json:"assignedBy"`
+}
+
+// TodoBatch is the payload of EventTodoBatch: the changes made by one batch
+// request that the recipient can see.
+type TodoBatch struct {
+	Created  []*model.Todo  `json:"created,omitempty"`
+	Updated  []*model.Todo  `json:"updated,omitempty"`
+	Deleted  []int64        `json:"deleted,omitempty"`
+	Assigned []TodoAssigned `json:"assigned,omitempty"`
+}
+
+func (b *TodoBatch) empty() bool {
+	return len(b.Created) == 0 && len(b.Updated) == 0 && len(b.Deleted) == 0 && len(b.Assigned) == 0
+}
+
+// CommentDeleted is the payload of EventCommentDeleted.
+type CommentDeleted struct {
+	ID     int64 `json:"id"`
+	TodoID int64 `json:"todoId"`
+}
+
+// mergeAudiences returns the union of the given user ID sets.
+func mergeAudiences(sets ...[]int64) []int64 {
+	seen := make(map[int64]bool)
+	var out []int64
+	for _, set := range sets {
+		for _, id := range set {
+			if !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// pendingEvent is an event waiting for the transaction that raised it to
+// commit.
+type pendingEvent struct {
+	name     string
+	payload  any
+	audience []int64
+}
+
+// outbox collects the events raised inside a transaction so they are only
+// published once it commits.
+type outbox []pendingEvent
+
+func (o *outbox) add(name string, payload any, audience []int64) {
+	*o = append(*o, pendingEvent{name: name, payload: payload, audience: audience})
+}
+
+// addAssignment queues the events of a change of assignee, if there was one.
+func (o *outbox) addAssignment(a *assignment, audience []int64) {
+	if a == nil {
+		return
+	}
+	o.add(EventTodoAssigned, a.event, audience)
+	if a.notification != nil {
+		o.add(EventNotificationCreated, a.notification, []int64{a.event.Assignee.ID})
+	}
+}
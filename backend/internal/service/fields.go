@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var (
+	ErrInvalidFieldName    = errors.New("field name is required")
+	ErrInvalidFieldType    = errors.New("type must be text, number, date, select, checkbox or user")
+	ErrInvalidFieldOptions = errors.New("a select field needs distinct, non-empty options")
+	ErrFieldTypeChange     = errors.New("the type of a field cannot be changed")
+	ErrDuplicateField      = errors.New("the list already has a field with that name")
+	ErrFieldOptionInUse    = errors.New("an option that todos still use cannot be removed")
+	ErrInvalidField        = errors.New("field is not defined on the todo's list")
+	ErrInvalidFieldValue   = errors.New("invalid field value")
+)
+
+// maxFieldTextLength bounds the value of a text field, in bytes.
+const maxFieldTextLength = 1000
+
+var fieldTypes = []string{model.FieldText, model.FieldNumber, model.FieldDate, model.FieldSelect, model.FieldCheckbox, model.FieldUser}
+
+// FieldChanges are the parts of a custom field to set; nil fields are left
+// alone.
+type FieldChanges struct {
+	Name    *string
+	Type    *string
+	Options *[]string
+}
+
+// Fields returns the custom fields of listID, which userID must belong to.
+func (s *ListService) Fields(ctx context.Context, userID, listID int64) ([]model.ListField, error) {
+	if _, err := s.db.ListRole(ctx, userID, listID); err != nil {
+		return nil, err
+	}
+	return s.db.ListFields(ctx, listID)
+}
+
+// CreateField adds a custom field to listID, which userID must own.
+func (s *ListService) CreateField(ctx context.Context, userID, listID int64, changes FieldChanges) (*model.ListField, error) {
+	if changes.Type == nil || !slices.Contains(fieldTypes, *changes.Type) {
+		return nil, ErrInvalidFieldType
+	}
+	field := &model.ListField{ListID: listID, Type: *changes.Type, Options: []string{}}
+	if changes.Name == nil {
+		return nil, ErrInvalidFieldName
+	}
+	if err := applyFieldChanges(field, changes); err != nil {
+		return nil, err
+	}
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		return tx.CreateListField(ctx, field)
+	})
+	if err != nil {
+		return nil, fieldWriteError(err)
+	}
+	return field, nil
+}
+
+// UpdateField renames fieldID of listID, which userID must own, or changes
+// its options. Options that todos still use cannot be removed.
+func (s *ListService) UpdateField(ctx context.Context, userID, listID, fieldID int64, changes FieldChanges) (*model.ListField, error) {
+	var field *model.ListField
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		var err error
+		if field, err = tx.GetListField(ctx, userID, fieldID); err != nil {
+			return err
+		}
+		if field.ListID != listID {
+			return repository.ErrNotFound
+		}
+		if changes.Type != nil && *changes.Type != field.Type {
+			return ErrFieldTypeChange
+		}
+		previous := field.Options
+		if err := applyFieldChanges(field, changes); err != nil {
+			return err
+		}
+		for _, option := range previous {
+			if slices.Contains(field.Options, option) {
+				continue
+			}
+			n, err := tx.CountFieldValue(ctx, fieldID, option)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrFieldOptionInUse
+			}
+		}
+		return tx.UpdateListField(ctx, field)
+	})
+	if err != nil {
+		return nil, fieldWriteError(err)
+	}
+	return field, nil
+}
+
+// DeleteField removes fieldID and its values from listID, which userID must
+// own.
+func (s *ListService) DeleteField(ctx context.Context, userID, listID, fieldID int64) error {
+	return s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		return tx.DeleteListField(ctx, listID, fieldID)
+	})
+}
+
+func applyFieldChanges(field *model.ListField, changes FieldChanges) error {
+	if changes.Name != nil {
+		field.Name = strings.TrimSpace(*changes.Name)
+		if field.Name == "" {
+			return ErrInvalidFieldName
+		}
+	}
+	if changes.Options != nil && field.Type == model.FieldSelect {
+		options := make([]string, 0, len(*changes.Options))
+		for _, option := range *changes.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || slices.Contains(options, option) {
+				return ErrInvalidFieldOptions
+			}
+			options = append(options, option)
+		}
+		field.Options = options
+	}
+	if field.Type == model.FieldSelect && len(field.Options) == 0 {
+		return ErrInvalidFieldOptions
+	}
+	return nil
+}
+
+func fieldWriteError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrDuplicateField
+	}
+	return err
+}
+
+// normalizeFields checks the custom field values of todo against the fields
+// of its list and brings them into the form they are stored in. A nil value
+// removes the field. prev is nil for a new todo; when todo moves to another
+// list, the values of the fields of the old list are dropped.
+func normalizeFields(ctx context.Context, tx *repository.DB, todo, prev *model.Todo) error {
+	values := todo.Fields
+	todo.Fields = map[int64]any{}
+	if len(values) == 0 {
+		return nil
+	}
+	fields, err := tx.ListFields(ctx, todo.ListID)
+	if err != nil {
+		return err
+	}
+	for fieldID, value := range values {
+		i := slices.IndexFunc(fields, func(f model.ListField) bool { return f.ID == fieldID })
+		if i < 0 {
+			if prev != nil && prev.ListID != todo.ListID {
+				if _, ok := prev.Fields[fieldID]; ok {
+					continue
+				}
+			}
+			return fmt.Errorf("%w: %d", ErrInvalidField, fieldID)
+		}
+		if value == nil {
+			continue
+		}
+		if todo.Fields[fieldID], err = fieldValue(ctx, tx, &fields[i], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldValue checks that value, as decoded from JSON, suits field.
+func fieldValue(ctx context.Context, tx *repository.DB, field *model.ListField, value any) (any, error) {
+	invalid := func(want string) error {
+		return fmt.Errorf("%w: %s must be %s", ErrInvalidFieldValue, field.Name, want)
+	}
+	switch field.Type {
+	case model.FieldText:
+		s, ok := value.(string)
+		if !ok || len(s) > maxFieldTextLength {
+			return nil, invalid(fmt.Sprintf("a string of at most %d bytes", maxFieldTextLength))
+		}
+		return s, nil
+	case model.FieldNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, invalid("a number")
+		}
+		return n, nil
+	case model.FieldDate:
+		s, ok := value.(string)
+		if _, err := time.Parse(time.DateOnly, s); !ok || err != nil {
+			return nil, invalid("a YYYY-MM-DD date")
+		}
+		return s, nil
+	case model.FieldSelect:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(field.Options, s) {
+			return nil, invalid("one of " + strings.Join(field.Options, ", "))
+		}
+		return s, nil
+	case model.FieldCheckbox:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, invalid("true or false")
+		}
+		return b, nil
+	case model.FieldUser:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, invalid("a user ID")
+		}
+		userID := int64(n)
+		if _, err := tx.ListRole(ctx, userID, field.ListID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalid("a member of the list")
+			}
+			return nil, err
+		}
+		return userID, nil
+	}
+	return nil, ErrInvalidFieldType
+}
+
+// fieldFilterValue parses raw, as given in a query string, into the value a
+// filter on fieldID compares with. userID must be able to see the field.
+func (s *TodoService) fieldFilterValue(ctx context.Context, userID, fieldID int64, raw string) (any, error) {
+	field, err := s.db.GetListField(ctx, userID, fieldID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidField, fieldID)
+		}
+		return nil, err
+	}
+	invalid := fmt.Errorf("%w: %q for %s", ErrInvalidFieldValue, raw, field.Name)
+	switch field.Type {
+	case model.FieldNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return n, nil
+	case model.FieldCheckbox:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalid
+		}
+		return b, nil
+	case model.FieldUser:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return n, nil
+	case model.FieldDate:
+		if _, err := time.Parse(time.DateOnly, raw); err != nil {
+			return nil, invalid
+		}
+	}
+	return raw, nil
+}
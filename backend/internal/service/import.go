@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// Import outcomes.
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportFailed    = "failed"
+)
+
+// ImportResult is the outcome of importing one todo.
+type ImportResult struct {
+	Outcome string
+	Todo    *model.Todo
+	Err     error
+}
+
+// Import brings todos from an external source into the lists of userID. A
+// todo whose UID matches one userID can already see updates it in place;
+// the others are created in listID, or the user's default list when it is
+// zero. Each todo succeeds or fails on its own, and members of the affected
+// lists are told about the changes in a single todo:batch event.
+func (s *TodoService) Import(ctx context.Context, userID, listID int64, todos []*model.Todo) ([]ImportResult, error) {
+	results := make([]ImportResult, len(todos))
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		for i, todo := range todos {
+			var itemOut outbox
+			err := tx.WithSavepoint(ctx, func(tx *repository.DB) error {
+				var err error
+				results[i], err = s.importTodo(ctx, tx, userID, listID, todo, &itemOut)
+				return err
+			})
+			if err != nil {
+				results[i] = ImportResult{Outcome: ImportFailed, Err: err}
+				continue
+			}
+			out = append(out, itemOut...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flushBatch(out)
+	return results, nil
+}
+
+// SaveByUID creates or updates the todo with todo.UID in listID, the way a
+// CalDAV client stores a task. A non-zero ifVersion makes updating fail with
+// repository.ErrVersionConflict unless the todo is still at that version.
+func (s *TodoService) SaveByUID(ctx context.Context, userID, listID int64, todo *model.Todo, ifVersion int64) (ImportResult, error) {
+	var (
+		result ImportResult
+		out    outbox
+	)
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		existing, err := tx.GetTodoByUID(ctx, userID, listID, todo.UID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if existing != nil && ifVersion != 0 && existing.Version != ifVersion {
+			return repository.ErrVersionConflict
+		}
+		result, err = s.upsert(ctx, tx, userID, listID, existing, todo, &out)
+		return err
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	s.flush(out)
+	return result, nil
+}
+
+// GetByUID returns the todo with uid in listID, or in any list userID can
+// see when listID is zero.
+func (s *TodoService) GetByUID(ctx context.Context, userID, listID int64, uid string) (*model.Todo, error) {
+	return s.db.GetTodoByUID(ctx, userID, listID, uid)
+}
+
+func (s *TodoService) importTodo(ctx context.Context, tx *repository.DB, userID, listID int64, todo *model.Todo, out *outbox) (ImportResult, error) {
+	existing, err := tx.GetTodoByUID(ctx, userID, 0, todo.UID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return ImportResult{}, err
+	}
+	return s.upsert(ctx, tx, userID, listID, existing, todo, out)
+}
+
+// upsert creates todo in listID when existing is nil, and otherwise copies
+// the fields an external source knows about onto existing.
+func (s *TodoService) upsert(ctx context.Context, tx *repository.DB, userID, listID int64, existing, todo *model.Todo, out *outbox) (ImportResult, error) {
+	if existing == nil {
+		todo.UserID = userID
+		todo.ListID = listID
+		if err := s.create(ctx, tx, todo, out); err != nil {
+			return ImportResult{}, err
+		}
+		return ImportResult{Outcome: ImportCreated, Todo: todo}, nil
+	}
+
+	next := *existing
+	next.Title = todo.Title
+	next.Notes = todo.Notes
+	next.Completed = todo.Completed
+	next.DueAt = todo.DueAt
+	if todo.RRule != next.RRule {
+		next.RRule = todo.RRule
+		next.RepeatMode = todo.RepeatMode
+	}
+	if todo.Timezone != "" {
+		next.Timezone = todo.Timezone
+	}
+	changes, err := diffTodos(existing, &next)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if len(changes) == 0 {
+		return ImportResult{Outcome: ImportUnchanged, Todo: existing}, nil
+	}
+	if _, err := s.updateTx(ctx, tx, userID, &next, model.RevisionUpdated, nil, out); err != nil {
+		return ImportResult{}, err
+	}
+	return ImportResult{Outcome: ImportUpdated, Todo: &next}, nil
+}
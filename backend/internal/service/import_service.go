@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+const (
+	// maxImportTasks bounds the tasks a single import job may contain.
+	maxImportTasks = 5000
+	// importChunkSize is how many todos are imported in one transaction
+	// before the job's progress is saved.
+	importChunkSize = 100
+)
+
+var ErrImportTooLarge = fmt.Errorf("at most %d tasks can be imported at once", maxImportTasks)
+
+// ImportTask is a task of an export file, mapped onto a todo. Tasks with
+// Err set could not be mapped and are reported as failed; their Todo only
+// carries the title.
+type ImportTask struct {
+	Project  string
+	Todo     *model.Todo
+	Err      error
+	Warnings []string
+}
+
+func NewImportService(db *repository.DB, todos *TodoService, lists *ListService) *ImportService {
+	return &ImportService{db: db, todos: todos, lists: lists}
+}
+
+// ImportService runs import jobs in the background. Each task goes to
+// job.ListID when set, and otherwise to the list named after its project,
+// which is created when the user can edit none by that name; tasks without a
+// project go to the user's Inbox. Tasks imported before, recognised by their
+// UID, are updated in place.
+type ImportService struct {
+	db    *repository.DB
+	todos *TodoService
+	lists *ListService
+}
+
+// Start records job and imports tasks in the background. The returned copy
+// of job reports it as pending; its progress is read with Get.
+func (s *ImportService) Start(ctx context.Context, userID int64, job *model.ImportJob, tasks []ImportTask) (*model.ImportJob, error) {
+	if len(tasks) > maxImportTasks {
+		return nil, ErrImportTooLarge
+	}
+	if job.ListID != nil {
+		if err := requireListRole(ctx, s.db, userID, *job.ListID, model.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+	job.Status = model.ImportJobPending
+	job.Total = len(tasks)
+	job.Items = make([]model.ImportJobItem, len(tasks))
+	for i, task := range tasks {
+		job.Items[i] = model.ImportJobItem{Index: i, Title: task.Todo.Title, Warnings: task.Warnings}
+	}
+	if err := s.db.CreateImportJob(ctx, userID, job); err != nil {
+		return nil, err
+	}
+	started := *job
+	started.Items = nil
+	go s.run(userID, job, tasks)
+	return &started, nil
+}
+
+func (s *ImportService) Get(ctx context.Context, userID, id int64) (*model.ImportJob, error) {
+	return s.db.GetImportJob(ctx, userID, id)
+}
+
+func (s *ImportService) List(ctx context.Context, userID int64) ([]model.ImportJob, error) {
+	return s.db.ListImportJobs(ctx, userID)
+}
+
+// FailUnfinished marks the jobs a previous run of the server left unfinished
+// as failed. It is called at startup, before any job starts.
+func (s *ImportService) FailUnfinished(ctx context.Context) error {
+	return s.db.FailUnfinishedImportJobs(ctx, "interrupted by a server restart")
+}
+
+// run imports tasks for job, saving its progress as it goes. It outlives the
+// request that started it.
+func (s *ImportService) run(userID int64, job *model.ImportJob, tasks []ImportTask) {
+	ctx := context.Background()
+	job.Status = model.ImportJobRunning
+	err := s.db.UpdateImportJob(ctx, job)
+	if err == nil {
+		err = s.importTasks(ctx, userID, job, tasks)
+	}
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.Status = model.ImportJobSucceeded
+	if err != nil {
+		job.Status = model.ImportJobFailed
+		job.Error = err.Error()
+	}
+	if err := s.db.UpdateImportJob(ctx, job); err != nil {
+		log.Printf("import job %d: %v", job.ID, err)
+	}
+}
+
+func (s *ImportService) importTasks(ctx context.Context, userID int64, job *model.ImportJob, tasks []ImportTask) error {
+	lists, err := s.targetLists(ctx, userID, job, tasks)
+	if err != nil {
+		return err
+	}
+	// Tasks are imported list by list, in the order each list first
+	// appears in the file.
+	var (
+		order  []int64
+		byList = make(map[int64][]int)
+	)
+	for i, task := range tasks {
+		item := &job.Items[i]
+		target := lists[strings.ToLower(task.Project)]
+		item.List = target.name
+		item.NewList = target.isNew
+		if task.Err != nil {
+			s.finish(job, item, ImportResult{Outcome: ImportFailed, Err: task.Err})
+			continue
+		}
+		if _, ok := byList[target.id]; !ok {
+			order = append(order, target.id)
+		}
+		byList[target.id] = append(byList[target.id], i)
+	}
+
+	for _, listID := range order {
+		indexes := byList[listID]
+		for len(indexes) > 0 {
+			chunk := indexes[:min(importChunkSize, len(indexes))]
+			indexes = indexes[len(chunk):]
+			results, err := s.importChunk(ctx, userID, listID, job.DryRun, tasks, chunk)
+			if err != nil {
+				return err
+			}
+			for j, i := range chunk {
+				s.finish(job, &job.Items[i], results[j])
+			}
+			if err := s.db.UpdateImportJob(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// importChunk imports the tasks at indexes into listID, which is zero for a
+// list a dry run would create. A dry run only looks up the todos that would
+// be updated.
+func (s *ImportService) importChunk(ctx context.Context, userID, listID int64, dryRun bool, tasks []ImportTask, indexes []int) ([]ImportResult, error) {
+	if !dryRun {
+		todos := make([]*model.Todo, len(indexes))
+		for j, i := range indexes {
+			todos[j] = tasks[i].Todo
+		}
+		return s.todos.Import(ctx, userID, listID, todos)
+	}
+	results := make([]ImportResult, len(indexes))
+	for j, i := range indexes {
+		results[j] = ImportResult{Outcome: ImportCreated}
+		uid := tasks[i].Todo.UID
+		if uid == "" {
+			continue
+		}
+		existing, err := s.todos.GetByUID(ctx, userID, 0, uid)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[j] = ImportResult{Outcome: ImportUpdated, Todo: existing}
+	}
+	return results, nil
+}
+
+// finish records the outcome of one task in job.
+func (s *ImportService) finish(job *model.ImportJob, item *model.ImportJobItem, result ImportResult) {
+	job.Processed++
+	item.Result = result.Outcome
+	if result.Todo != nil {
+		item.TodoID = result.Todo.ID
+	}
+	if result.Err != nil {
+		item.Error = result.Err.Error()
+	}
+	switch result.Outcome {
+	case ImportCreated:
+		job.Created++
+	case ImportUpdated:
+		job.Updated++
+	case ImportUnchanged:
+		job.Unchanged++
+	case ImportFailed:
+		job.Failed++
+	}
+}
+
+type importTarget struct {
+	id    int64
+	name  string
+	isNew bool
+}
+
+// targetLists resolves the list each project of tasks goes to, keyed by the
+// lowercase project name. Only lists userID can edit are considered; lists
+// that do not exist yet are created, except in a dry run.
+func (s *ImportService) targetLists(ctx context.Context, userID int64, job *model.ImportJob, tasks []ImportTask) (map[string]importTarget, error) {
+	lists, err := s.lists.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var fixed *importTarget
+	if job.ListID != nil {
+		for _, l := range lists {
+			if l.ID == *job.ListID && roleAtLeast(l.Role, model.RoleEditor) {
+				fixed = &importTarget{id: l.ID, name: l.Name}
+			}
+		}
+		if fixed == nil {
+			return nil, ErrForbidden
+		}
+	}
+	var inbox importTarget
+	existing := make(map[string]importTarget, len(lists))
+	for _, l := range lists {
+		if !roleAtLeast(l.Role, model.RoleEditor) {
+			continue
+		}
+		if l.IsDefault {
+			inbox = importTarget{id: l.ID, name: l.Name}
+		}
+		key := strings.ToLower(l.Name)
+		if _, ok := existing[key]; !ok {
+			existing[key] = importTarget{id: l.ID, name: l.Name}
+		}
+	}
+
+	targets := make(map[string]importTarget)
+	for _, task := range tasks {
+		key := strings.ToLower(task.Project)
+		if _, ok := targets[key]; ok {
+			continue
+		}
+		switch target, ok := existing[key]; {
+		case fixed != nil:
+			targets[key] = *fixed
+		case task.Project == "":
+			targets[key] = inbox
+		case ok:
+			targets[key] = target
+		case job.DryRun:
+			targets[key] = importTarget{name: task.Project, isNew: true}
+		default:
+			list, err := s.lists.Create(ctx, userID, task.Project)
+			if err != nil {
+				return nil, err
+			}
+			targets[key] = importTarget{id: list.ID, name: list.Name, isNew: true}
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,67 @@
+//go:build sqlite_fts5
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// TestImportTargetLists checks that an import only goes to lists the user
+// can edit.
+func TestImportTargetLists(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	aliceID, _ := newTestList(t, db, "alice")
+	bobID, _ := newTestList(t, db, "bob")
+	lists := NewListService(db)
+	s := NewImportService(db, NewTodoService(db), lists)
+	shared := func(name, role string) int64 {
+		t.Helper()
+		list, err := lists.Create(ctx, bobID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetListMember(ctx, list.ID, aliceID, role); err != nil {
+			t.Fatal(err)
+		}
+		return list.ID
+	}
+	viewed := shared("Groceries", model.RoleViewer)
+	edited := shared("Garden", model.RoleEditor)
+	private, err := lists.Create(ctx, bobID, "Secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := []ImportTask{{Project: "groceries"}, {Project: "GARDEN"}}
+	targets, err := s.targetLists(ctx, aliceID, &model.ImportJob{DryRun: true}, tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := targets["groceries"]; got.id == viewed || !got.isNew {
+		t.Errorf("a project named like a list alice only views goes to %+v, want a new list", got)
+	}
+	if got := targets["garden"]; got.id != edited || got.isNew {
+		t.Errorf("a project named like a list alice edits goes to %+v, want list %d", got, edited)
+	}
+
+	for _, tt := range []struct {
+		listID int64
+		want   error
+	}{
+		{viewed, ErrForbidden},
+		{private.ID, repository.ErrNotFound},
+		{9999, repository.ErrNotFound},
+	} {
+		listID := tt.listID
+		job := &model.ImportJob{ListID: &listID, DryRun: true}
+		if _, err := s.Start(ctx, aliceID, job, tasks); !errors.Is(err, tt.want) {
+			t.Errorf("importing into list %d = %v, want %v", listID, err, tt.want)
+		}
+	}
+}
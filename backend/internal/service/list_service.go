@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var ErrInvalidRole = errors.New("role must be viewer, editor or owner")
+var ErrLastOwner = errors.New("a list must keep at least one owner")
+var ErrDefaultList = errors.New("the default list cannot be deleted")
+var ErrInvalidListName = errors.New("list name is required")
+var ErrDefaultListOwner = errors.New("a user must remain owner of their default list")
+
+func NewListService(db *repository.DB) *ListService {
+	return &ListService{db: db}
+}
+
+type ListService struct {
+	db *repository.DB
+}
+
+func (s *ListService) ListByUser(ctx context.Context, userID int64) ([]model.List, error) {
+	return s.db.ListListsByUser(ctx, userID)
+}
+
+func (s *ListService) Get(ctx context.Context, userID, listID int64) (*model.List, error) {
+	return s.db.GetList(ctx, userID, listID)
+}
+
+func (s *ListService) Create(ctx context.Context, userID int64, name string) (*model.List, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidListName
+	}
+	list := &model.List{Name: name, CreatedBy: userID}
+	if err := s.db.CreateList(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListChanges are the fields of a list to change; nil fields are left
+// alone.
+type ListChanges struct {
+	Name                *string
+	EnforceDependencies *bool
+}
+
+// Update changes listID, which userID must own.
+func (s *ListService) Update(ctx context.Context, userID, listID int64, changes ListChanges) (*model.List, error) {
+	list, err := s.db.GetList(ctx, userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Name != nil {
+		list.Name = strings.TrimSpace(*changes.Name)
+		if list.Name == "" {
+			return nil, ErrInvalidListName
+		}
+	}
+	if changes.EnforceDependencies != nil {
+		list.EnforceDependencies = *changes.EnforceDependencies
+	}
+	if err := s.db.UpdateList(ctx, userID, list); err != nil {
+		return nil, err
+	}
+	return s.db.GetList(ctx, userID, listID)
+}
+
+// Delete removes a list and all of its todos. Only owners may delete a list,
+// and a user's default list cannot be deleted.
+func (s *ListService) Delete(ctx context.Context, userID, listID int64) error {
+	return s.db.WithTx(ctx, func(tx *repository.DB) error {
+		list, err := tx.GetList(ctx, userID, listID)
+		if err != nil {
+			return err
+		}
+		if list.Role != model.RoleOwner {
+			return ErrForbidden
+		}
+		if list.IsDefault {
+			return ErrDefaultList
+		}
+		return tx.DeleteList(ctx, userID, listID)
+	})
+}
+
+// Members returns the members of listID, which userID must belong to.
+func (s *ListService) Members(ctx context.Context, userID, listID int64) ([]model.ListMember, error) {
+	if _, err := s.db.ListRole(ctx, userID, listID); err != nil {
+		return nil, err
+	}
+	return s.db.ListMembers(ctx, listID)
+}
+
+// MemberIDs returns the IDs of every member of listID.
+func (s *ListService) MemberIDs(ctx context.Context, listID int64) ([]int64, error) {
+	return s.db.ListMemberIDs(ctx, listID)
+}
+
+// SetMember adds memberID to listID with role, or changes their role. Only
+// owners may manage members, and the last owner cannot be demoted.
+func (s *ListService) SetMember(ctx context.Context, userID, listID, memberID int64, role string) (*model.ListMember, error) {
+	if _, ok := roleRank[role]; !ok {
+		return nil, ErrInvalidRole
+	}
+	var member *model.ListMember
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		if _, err := tx.GetUserByID(ctx, memberID); err != nil {
+			return err
+		}
+		current, err := tx.ListRole(ctx, memberID, listID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if current == model.RoleOwner && role != model.RoleOwner {
+			if err := requireAnotherOwner(ctx, tx, listID); err != nil {
+				return err
+			}
+			if err := protectDefaultListOwner(ctx, tx, listID, memberID); err != nil {
+				return err
+			}
+		}
+		if err := tx.SetListMember(ctx, listID, memberID, role); err != nil {
+			return err
+		}
+		members, err := tx.ListMembers(ctx, listID)
+		if err != nil {
+			return err
+		}
+		for i := range members {
+			if members[i].User.ID == memberID {
+				member = &members[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes memberID from listID. Owners may remove anyone and any
+// member may leave; the last owner cannot leave.
+func (s *ListService) RemoveMember(ctx context.Context, userID, listID, memberID int64) error {
+	return s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if userID != memberID {
+			if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+				return err
+			}
+		}
+		role, err := tx.ListRole(ctx, memberID, listID)
+		if err != nil {
+			return err
+		}
+		if role == model.RoleOwner {
+			if err := requireAnotherOwner(ctx, tx, listID); err != nil {
+				return err
+			}
+		}
+		if err := protectDefaultListOwner(ctx, tx, listID, memberID); err != nil {
+			return err
+		}
+		if err := tx.RemoveListMember(ctx, listID, memberID); err != nil {
+			return err
+		}
+		return tx.UnassignListMember(ctx, listID, memberID)
+	})
+}
+
+func requireAnotherOwner(ctx context.Context, tx *repository.DB, listID int64) error {
+	owners, err := tx.CountListOwners(ctx, listID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func protectDefaultListOwner(ctx context.Context, tx *repository.DB, listID, memberID int64) error {
+	owner, err := tx.DefaultListOwner(ctx, listID)
+	if err != nil {
+		return err
+	}
+	if owner == memberID {
+		return ErrDefaultListOwner
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+func NewNotificationService(db *repository.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+type NotificationService struct {
+	db *repository.DB
+}
+
+// List returns up to limit of userID's notifications newest first, starting
+// below the notification with ID beforeID unless it is zero.
+func (s *NotificationService) List(ctx context.Context, userID int64, unreadOnly bool, beforeID int64, limit int) ([]model.Notification, error) {
+	return s.db.ListNotifications(ctx, userID, unreadOnly, beforeID, limit)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID int64) error {
+	return s.db.MarkNotificationRead(ctx, userID, notificationID)
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int64) error {
+	return s.db.MarkAllNotificationsRead(ctx, userID)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// todoFields is the part of a todo that revisions track, keyed by the same
+// JSON names as model.Todo.
+type todoFields struct {
+	ListID     int64      `json:"listId"`
+	AssigneeID *int64     `json:"assigneeId"`
+	Title      string     `json:"title"`
+	Notes      string     `json:"notes"`
+	Completed  bool       `json:"completed"`
+	DueAt      *time.Time `json:"dueAt"`
+	RRule      string     `json:"rrule"`
+	Timezone   string     `json:"timezone"`
+	RepeatMode string     `json:"repeatMode"`
+	Priority   string     `json:"priority"`
+	Tags       []string   `json:"tags"`
+	StatusID   int64      `json:"statusId"`
+	// Fields are the custom field values, keyed by field ID.
+	Fields map[int64]any `json:"fields"`
+}
+
+// trackedFields returns the tracked fields of todo as JSON values, or nil
+// values when todo is nil.
+func trackedFields(todo *model.Todo) (map[string]json.RawMessage, error) {
+	var fields todoFields
+	if todo != nil {
+		fields = todoFields{
+			ListID:     todo.ListID,
+			AssigneeID: todo.AssigneeID,
+			Title:      todo.Title,
+			Notes:      todo.Notes,
+			Completed:  todo.Completed,
+			RRule:      todo.RRule,
+			Timezone:   todo.Timezone,
+			RepeatMode: todo.RepeatMode,
+			Priority:   todo.Priority,
+			Tags:       todo.Tags,
+			StatusID:   todo.StatusID,
+			Fields:     todo.Fields,
+		}
+		if fields.Tags == nil {
+			fields.Tags = []string{}
+		}
+		if fields.Fields == nil {
+			fields.Fields = map[int64]any{}
+		}
+		if todo.DueAt != nil {
+			// Stored timestamps are UTC with millisecond precision.
+			due := todo.DueAt.UTC().Truncate(time.Millisecond)
+			fields.DueAt = &due
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if todo == nil {
+		for name := range out {
+			out[name] = json.RawMessage("null")
+		}
+	}
+	return out, nil
+}
+
+// applyFields sets the tracked fields of todo from their JSON values.
+func applyFields(todo *model.Todo, values map[string]json.RawMessage) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	var fields todoFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	todo.ListID = fields.ListID
+	todo.AssigneeID = fields.AssigneeID
+	todo.Title = fields.Title
+	todo.Notes = fields.Notes
+	todo.Completed = fields.Completed
+	todo.DueAt = fields.DueAt
+	todo.RRule = fields.RRule
+	todo.Timezone = fields.Timezone
+	todo.RepeatMode = fields.RepeatMode
+	todo.Priority = fields.Priority
+	todo.Tags = fields.Tags
+	todo.StatusID = fields.StatusID
+	todo.Fields = fields.Fields
+	return nil
+}
+
+// diffTodos returns the tracked fields that differ between before and after.
+// A nil before stands for a todo that did not exist yet.
+func diffTodos(before, after *model.Todo) (map[string]model.FieldChange, error) {
+	from, err := trackedFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := trackedFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]model.FieldChange)
+	for name, value := range to {
+		if !bytes.Equal(from[name], value) {
+			changes[name] = model.FieldChange{From: from[name], To: value}
+		}
+	}
+	return changes, nil
+}
+
+// recordRevision stores the change actorID made from before to after. Plain
+// updates that changed nothing are not recorded.
+func recordRevision(ctx context.Context, tx *repository.DB, actorID int64, action string, before, after *model.Todo, revertOf *int64) error {
+	changes, err := diffTodos(before, after)
+	if err != nil {
+		return err
+	}
+	if action == model.RevisionUpdated && len(changes) == 0 {
+		return nil
+	}
+	return tx.CreateTodoRevision(ctx, &model.TodoRevision{
+		TodoID:   after.ID,
+		Actor:    &model.UserSummary{ID: actorID},
+		Action:   action,
+		Changes:  changes,
+		RevertOf: revertOf,
+	})
+}
+
+// recordTrashRevision records actorID moving todoID to or out of the trash,
+// which leaves the tracked fields unchanged.
+func recordTrashRevision(ctx context.Context, tx *repository.DB, actorID, todoID int64, action string) error {
+	return tx.CreateTodoRevision(ctx, &model.TodoRevision{
+		TodoID:  todoID,
+		Actor:   &model.UserSummary{ID: actorID},
+		Action:  action,
+		Changes: map[string]model.FieldChange{},
+	})
+}
+
+// History returns the revisions of todoID, newest first.
+func (s *TodoService) History(ctx context.Context, userID, todoID int64) ([]model.TodoRevision, error) {
+	if _, err := s.db.TodoRole(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	return s.db.ListTodoRevisions(ctx, todoID)
+}
+
+// Revert puts todoID back into the state it had right after revisionID by
+// undoing every later revision, and records that as a revision of its own.
+func (s *TodoService) Revert(ctx context.Context, userID, todoID, revisionID int64) (*model.Todo, error) {
+	todo, err := s.db.GetTodo(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := s.db.ListTodoRevisions(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := trackedFields(todo)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, rev := range revisions {
+		if rev.ID == revisionID {
+			found = true
+			break
+		}
+		for name, change := range rev.Changes {
+			if _, ok := fields[name]; ok {
+				fields[name] = change.From
+			}
+		}
+	}
+	if !found {
+		return nil, repository.ErrNotFound
+	}
+	if err := applyFields(todo, fields); err != nil {
+		return nil, err
+	}
+	if _, err := s.update(ctx, userID, todo, model.RevisionReverted, &revisionID); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// Stats intervals.
+const (
+	StatsDaily  = "day"
+	StatsWeekly = "week"
+)
+
+const (
+	// maxStatsDays bounds the range of a statistics query.
+	maxStatsDays = 366
+	// streakWindowDays is how many days the current streak is looked up at
+	// a time, going back until a day without completions.
+	streakWindowDays = 90
+)
+
+var (
+	ErrInvalidStatsRange    = fmt.Errorf("from must not be after to, and the range at most %d days", maxStatsDays)
+	ErrInvalidStatsInterval = errors.New("interval must be day or week")
+)
+
+// StatsQuery selects the todos and range Stats summarises. From and To are
+// the first and last day, at midnight in their location, which is the
+// timezone days and weeks are counted in. Weeks start on Monday.
+type StatsQuery struct {
+	ListID   int64
+	From, To time.Time
+	Interval string
+}
+
+// Stats summarises the todos userID can see, or those of q.ListID, over the
+// days of q.
+func (s *TodoService) Stats(ctx context.Context, userID int64, q StatsQuery) (*model.Stats, error) {
+	if q.Interval != StatsDaily && q.Interval != StatsWeekly {
+		return nil, ErrInvalidStatsInterval
+	}
+	loc := q.From.Location()
+	end := q.To.AddDate(0, 0, 1)
+	if q.To.Before(q.From) || end.After(q.From.AddDate(0, 0, maxStatsDays)) {
+		return nil, ErrInvalidStatsRange
+	}
+	if q.ListID != 0 {
+		if _, err := s.db.GetList(ctx, userID, q.ListID); err != nil {
+			return nil, err
+		}
+	}
+
+	days := dayRanges(q.From, end)
+	dayCounts, err := s.db.CountTodosPerRange(ctx, userID, q.ListID, days)
+	if err != nil {
+		return nil, err
+	}
+	stats := &model.Stats{
+		From:     q.From.Format(time.DateOnly),
+		To:       q.To.Format(time.DateOnly),
+		Timezone: loc.String(),
+		Interval: q.Interval,
+		Buckets:  []model.StatsBucket{},
+	}
+	run := 0
+	for i, c := range dayCounts {
+		stats.Created += c.Created
+		stats.Completed += c.Completed
+		if c.Completed == 0 {
+			run = 0
+		} else {
+			run++
+			stats.Streak.Longest = max(stats.Streak.Longest, run)
+		}
+		// A bucket starts on the first day and on every Monday.
+		if q.Interval == StatsDaily || i == 0 || days[i].Start.Weekday() == time.Monday {
+			stats.Buckets = append(stats.Buckets, model.StatsBucket{Start: days[i].Start.Format(time.DateOnly)})
+		}
+		bucket := &stats.Buckets[len(stats.Buckets)-1]
+		bucket.Created += c.Created
+		bucket.Completed += c.Completed
+	}
+
+	mean, late, err := s.db.CompletionTimes(ctx, userID, q.ListID, repository.TimeRange{Start: q.From, End: end})
+	if err != nil {
+		return nil, err
+	}
+	if mean.Valid {
+		stats.AverageCompletionSeconds = &mean.Float64
+	}
+	stats.CompletedLate = late
+	now := time.Now()
+	if stats.Overdue, err = s.db.CountOverdueTodos(ctx, userID, q.ListID, now); err != nil {
+		return nil, err
+	}
+	if stats.Streak.Current, err = s.currentStreak(ctx, userID, q.ListID, now.In(loc)); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// currentStreak counts the days up to today with completions, starting from
+// yesterday when nothing has been completed today yet.
+func (s *TodoService) currentStreak(ctx context.Context, userID, listID int64, now time.Time) (int, error) {
+	end := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	streak := 0
+	for {
+		days := dayRanges(end.AddDate(0, 0, -streakWindowDays), end)
+		counts, err := s.db.CountTodosPerRange(ctx, userID, listID, days)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(counts) - 1; i >= 0; i-- {
+			if counts[i].Completed > 0 {
+				streak++
+				continue
+			}
+			// Today does not break the streak until it is over.
+			if streak == 0 && days[i].End.After(now) {
+				continue
+			}
+			return streak, nil
+		}
+		end = days[0].Start
+	}
+}
+
+// dayRanges splits [from, end), both midnights, into days. Days are not
+// always 24 hours long, so each is found with AddDate.
+func dayRanges(from, end time.Time) []repository.TimeRange {
+	var days []repository.TimeRange
+	for day := from; day.Before(end); {
+		next := day.AddDate(0, 0, 1)
+		days = append(days, repository.TimeRange{Start: day, End: next})
+		day = next
+	}
+	return days
+}
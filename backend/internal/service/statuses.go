@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var (
+	ErrInvalidStatus         = errors.New("status does not belong to the todo's list")
+	ErrInvalidStatusName     = errors.New("status name is required")
+	ErrInvalidStatusCategory = errors.New("category must be todo, in_progress or done")
+	ErrInvalidWIPLimit       = errors.New("WIP limit cannot be negative")
+	ErrWIPLimit              = errors.New("status has reached its WIP limit")
+	ErrLastStatus            = errors.New("a list must keep at least one open and one done status")
+	ErrStatusInUse           = errors.New("status still holds todos")
+)
+
+var statusCategories = []string{model.StatusTodo, model.StatusInProgress, model.StatusDone}
+
+// StatusChanges are the fields of a status to set; nil fields are left
+// alone, or take their defaults on a new status. A WIP limit of zero removes
+// the limit.
+type StatusChanges struct {
+	Name     *string
+	Category *string
+	Position *int
+	WIPLimit *int
+}
+
+// Statuses returns the statuses of listID, which userID must belong to.
+func (s *ListService) Statuses(ctx context.Context, userID, listID int64) ([]model.ListStatus, error) {
+	if _, err := s.db.ListRole(ctx, userID, listID); err != nil {
+		return nil, err
+	}
+	return s.db.ListStatuses(ctx, listID)
+}
+
+// CreateStatus adds a status to listID, which userID must own. It goes last
+// and is an open status unless changes say otherwise.
+func (s *ListService) CreateStatus(ctx context.Context, userID, listID int64, changes StatusChanges) (*model.ListStatus, error) {
+	status := &model.ListStatus{ListID: listID, Category: model.StatusTodo}
+	if changes.Name == nil {
+		return nil, ErrInvalidStatusName
+	}
+	if err := applyStatusChanges(status, changes); err != nil {
+		return nil, err
+	}
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		statuses, err := tx.ListStatuses(ctx, listID)
+		if err != nil {
+			return err
+		}
+		status.Position = len(statuses)
+		if err := tx.CreateListStatus(ctx, status); err != nil {
+			return err
+		}
+		position := status.Position
+		if changes.Position != nil {
+			position = *changes.Position
+		}
+		return placeStatus(ctx, tx, append(statuses, *status), status.ID, position)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetListStatus(ctx, listID, status.ID)
+}
+
+// UpdateStatus changes statusID of listID, which userID must own. A status
+// holding todos cannot switch between done and not done, since that would
+// complete or reopen them.
+func (s *ListService) UpdateStatus(ctx context.Context, userID, listID, statusID int64, changes StatusChanges) (*model.ListStatus, error) {
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		statuses, err := tx.ListStatuses(ctx, listID)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(statuses, func(st model.ListStatus) bool { return st.ID == statusID })
+		if i < 0 {
+			return repository.ErrNotFound
+		}
+		status := statuses[i]
+		if err := applyStatusChanges(&status, changes); err != nil {
+			return err
+		}
+		wasDone := statuses[i].Category == model.StatusDone
+		statuses[i] = status
+		if (status.Category == model.StatusDone) != wasDone {
+			if err := requireOpenAndDone(statuses); err != nil {
+				return err
+			}
+			n, err := tx.CountTodosInStatus(ctx, statusID, 0)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrStatusInUse
+			}
+		}
+		if err := tx.UpdateListStatus(ctx, &status); err != nil {
+			return err
+		}
+		if changes.Position == nil {
+			return nil
+		}
+		return placeStatus(ctx, tx, statuses, statusID, *changes.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetListStatus(ctx, listID, statusID)
+}
+
+// DeleteStatus removes statusID from listID, which userID must own. Only a
+// status without todos can go; todos in the trash move to another status of
+// the same kind.
+func (s *ListService) DeleteStatus(ctx context.Context, userID, listID, statusID int64) error {
+	return s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleOwner); err != nil {
+			return err
+		}
+		statuses, err := tx.ListStatuses(ctx, listID)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(statuses, func(st model.ListStatus) bool { return st.ID == statusID })
+		if i < 0 {
+			return repository.ErrNotFound
+		}
+		status := statuses[i]
+		rest := slices.Delete(slices.Clone(statuses), i, i+1)
+		if err := requireOpenAndDone(rest); err != nil {
+			return err
+		}
+		n, err := tx.CountTodosInStatus(ctx, statusID, 0)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrStatusInUse
+		}
+		target := pickStatus(rest, status.Category == model.StatusDone, status.Category)
+		if err := tx.MoveStatusTodos(ctx, statusID, target.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteListStatus(ctx, listID, statusID); err != nil {
+			return err
+		}
+		return placeStatus(ctx, tx, rest, 0, 0)
+	})
+}
+
+func applyStatusChanges(status *model.ListStatus, changes StatusChanges) error {
+	if changes.Name != nil {
+		status.Name = strings.TrimSpace(*changes.Name)
+		if status.Name == "" {
+			return ErrInvalidStatusName
+		}
+	}
+	if changes.Category != nil {
+		if !slices.Contains(statusCategories, *changes.Category) {
+			return ErrInvalidStatusCategory
+		}
+		status.Category = *changes.Category
+	}
+	if changes.WIPLimit != nil {
+		switch limit := *changes.WIPLimit; {
+		case limit < 0:
+			return ErrInvalidWIPLimit
+		case limit == 0:
+			status.WIPLimit = nil
+		default:
+			status.WIPLimit = &limit
+		}
+	}
+	return nil
+}
+
+// placeStatus moves statusID among statuses to position, or keeps the order
+// when statusID is zero, and numbers the positions from zero.
+func placeStatus(ctx context.Context, tx *repository.DB, statuses []model.ListStatus, statusID int64, position int) error {
+	ordered := slices.Clone(statuses)
+	if i := slices.IndexFunc(ordered, func(st model.ListStatus) bool { return st.ID == statusID }); i >= 0 {
+		moved := ordered[i]
+		ordered = slices.Delete(ordered, i, i+1)
+		position = min(max(position, 0), len(ordered))
+		ordered = slices.Insert(ordered, position, moved)
+	}
+	for i := range ordered {
+		if ordered[i].Position == i && ordered[i].ID != statusID {
+			continue
+		}
+		ordered[i].Position = i
+		if err := tx.UpdateListStatus(ctx, &ordered[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requireOpenAndDone(statuses []model.ListStatus) error {
+	if pickStatus(statuses, false, model.StatusTodo) == nil || pickStatus(statuses, true, model.StatusDone) == nil {
+		return ErrLastStatus
+	}
+	return nil
+}
+
+// pickStatus returns the first of statuses that is done or not as asked,
+// preferring one in category, or nil when there is none.
+func pickStatus(statuses []model.ListStatus, done bool, category string) *model.ListStatus {
+	var picked *model.ListStatus
+	for i := range statuses {
+		if (statuses[i].Category == model.StatusDone) != done {
+			continue
+		}
+		if statuses[i].Category == category {
+			return &statuses[i]
+		}
+		if picked == nil {
+			picked = &statuses[i]
+		}
+	}
+	return picked
+}
+
+// resolveStatus keeps the status and completion of todo in step; prev is nil
+// for a new todo. Moving todo to another status sets Completed from its
+// category. Otherwise the status follows Completed, keeping to the category
+// the todo was in where it can, as when the todo moves to another list.
+// Entering a status checks its WIP limit.
+func resolveStatus(ctx context.Context, tx *repository.DB, todo, prev *model.Todo) error {
+	statuses, err := tx.ListStatuses(ctx, todo.ListID)
+	if err != nil {
+		return err
+	}
+	var prevStatusID int64
+	category := model.StatusTodo
+	if prev != nil {
+		prevStatusID = prev.StatusID
+		if prev.Status != nil {
+			category = prev.Status.Category
+		}
+	}
+	i := slices.IndexFunc(statuses, func(st model.ListStatus) bool { return st.ID == todo.StatusID })
+	var status *model.ListStatus
+	switch {
+	case todo.StatusID != 0 && todo.StatusID != prevStatusID:
+		if i < 0 {
+			return ErrInvalidStatus
+		}
+		status = &statuses[i]
+		todo.Completed = status.Category == model.StatusDone
+	case i >= 0 && (statuses[i].Category == model.StatusDone) == todo.Completed:
+		status = &statuses[i]
+	default:
+		status = pickStatus(statuses, todo.Completed, category)
+	}
+	if status == nil {
+		return ErrLastStatus
+	}
+	todo.StatusID = status.ID
+	if status.ID == prevStatusID || status.WIPLimit == nil {
+		return nil
+	}
+	n, err := tx.CountTodosInStatus(ctx, status.ID, todo.ID)
+	if err != nil {
+		return err
+	}
+	if n >= *status.WIPLimit {
+		return ErrWIPLimit
+	}
+	return nil
+}
+
+// Board returns the todos of listID that userID can see grouped by status,
+// up to limit per status.
+func (s *TodoService) Board(ctx context.Context, userID, listID int64, limit int) (*model.Board, error) {
+	list, err := s.db.GetList(ctx, userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.db.ListStatuses(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.db.CountTodosByStatus(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	board := &model.Board{List: *list, Columns: make([]model.BoardColumn, len(statuses))}
+	for i, status := range statuses {
+		todos, err := s.db.ListTodosByStatus(ctx, userID, status.ID, limit)
+		if err != nil {
+			return nil, err
+		}
+		board.Columns[i] = model.BoardColumn{Status: status, Count: counts[status.ID], Todos: todos}
+	}
+	return board, nil
+}
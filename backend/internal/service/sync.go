@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+// SyncToken returns a token that grows whenever the todos in listID change,
+// for clients that keep a copy of the list in sync.
+func (s *TodoService) SyncToken(ctx context.Context, userID, listID int64) (int64, error) {
+	if err := requireListRole(ctx, s.db, userID, listID, model.RoleViewer); err != nil {
+		return 0, err
+	}
+	return s.db.ListSyncToken(ctx, listID)
+}
+
+// Changes returns the todos of listID that changed after the sync token
+// since, along with the token to pass next time.
+func (s *TodoService) Changes(ctx context.Context, userID, listID, since int64) ([]repository.TodoChange, int64, error) {
+	var (
+		changes []repository.TodoChange
+		token   int64
+	)
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := requireListRole(ctx, tx, userID, listID, model.RoleViewer); err != nil {
+			return err
+		}
+		var err error
+		if token, err = tx.ListSyncToken(ctx, listID); err != nil {
+			return err
+		}
+		changes, err = tx.ListTodoChanges(ctx, listID, since)
+		return err
+	})
+	return changes, token, err
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var (
+	ErrNoRunningTimer   = errors.New("no timer is running on this todo")
+	ErrInvalidTimeEntry = errors.New("endedAt must be after startedAt, and neither in the future")
+)
+
+// TimerRunningError reports that the user already has a running timer, on
+// the todo of Entry.
+type TimerRunningError struct {
+	Entry *model.TimeEntry
+}
+
+func (e *TimerRunningError) Error() string {
+	return fmt.Sprintf("a timer is already running on todo %d", e.Entry.TodoID)
+}
+
+func NewTimeEntryService(db *repository.DB) *TimeEntryService {
+	return &TimeEntryService{db: db}
+}
+
+// TimeEntryService records the time users spend on todos, either with a
+// timer or as entries they enter by hand. Everyone who can see a todo can
+// track time on it and see its entries; entries can be changed by their
+// user and by owners of the todo's list.
+type TimeEntryService struct {
+	db     *repository.DB
+	events Publisher
+}
+
+// SetPublisher makes the service announce timers starting and stopping to
+// the user's own connections.
+func (s *TimeEntryService) SetPublisher(events Publisher) {
+	s.events = events
+}
+
+func (s *TimeEntryService) publish(event string, entry *model.TimeEntry) {
+	if s.events != nil {
+		s.events.Publish(event, entry, []int64{entry.User.ID})
+	}
+}
+
+// Running returns userID's running timer, or repository.ErrNotFound.
+func (s *TimeEntryService) Running(ctx context.Context, userID int64) (*model.TimeEntry, error) {
+	return s.db.GetRunningTimeEntry(ctx, userID)
+}
+
+// Start starts a timer for userID on todoID. A user has at most one running
+// timer; starting another fails with a *TimerRunningError.
+func (s *TimeEntryService) Start(ctx context.Context, userID, todoID int64, note string) (*model.TimeEntry, error) {
+	entry := &model.TimeEntry{TodoID: todoID, User: model.UserSummary{ID: userID}, StartedAt: time.Now().UTC(), Note: note}
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if _, err := tx.GetTodo(ctx, userID, todoID); err != nil {
+			return err
+		}
+		if err := runningTimerError(ctx, tx, userID); err != nil {
+			return err
+		}
+		if err := tx.CreateTimeEntry(ctx, entry); err != nil {
+			// Another request started a timer in the meantime.
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return runningTimerError(ctx, tx, userID)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(EventTimerStarted, entry)
+	return entry, nil
+}
+
+// Stop stops userID's timer on todoID.
+func (s *TimeEntryService) Stop(ctx context.Context, userID, todoID int64) (*model.TimeEntry, error) {
+	entry, err := s.db.GetRunningTimeEntry(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && entry.TodoID != todoID) {
+		return nil, ErrNoRunningTimer
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	entry.EndedAt = &now
+	if err := s.db.UpdateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	s.publish(EventTimerStopped, entry)
+	return entry, nil
+}
+
+// List returns the entries matching filter on todos userID can see, oldest
+// first.
+func (s *TimeEntryService) List(ctx context.Context, userID int64, filter repository.TimeEntryFilter) ([]model.TimeEntry, error) {
+	if filter.TodoID != 0 {
+		if _, err := s.db.GetTodo(ctx, userID, filter.TodoID); err != nil {
+			return nil, err
+		}
+	}
+	if filter.ListID != 0 {
+		if _, err := s.db.GetList(ctx, userID, filter.ListID); err != nil {
+			return nil, err
+		}
+	}
+	return s.db.ListTimeEntries(ctx, userID, filter)
+}
+
+// Totals sums the time of the entries matching filter, per todo or, when
+// byList is set, per list. Entries crossing the ends of filter's range only
+// count with the time inside it.
+func (s *TimeEntryService) Totals(ctx context.Context, userID int64, filter repository.TimeEntryFilter, byList bool) ([]model.TimeTotal, error) {
+	if filter.ListID != 0 {
+		if _, err := s.db.GetList(ctx, userID, filter.ListID); err != nil {
+			return nil, err
+		}
+	}
+	return s.db.TimeTotals(ctx, userID, filter, byList, time.Now().UTC())
+}
+
+// Create records time userID spent on todoID.
+func (s *TimeEntryService) Create(ctx context.Context, userID, todoID int64, startedAt, endedAt time.Time, note string) (*model.TimeEntry, error) {
+	endedAt = endedAt.UTC()
+	if err := validTimeEntry(startedAt, &endedAt); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.GetTodo(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	entry := &model.TimeEntry{TodoID: todoID, User: model.UserSummary{ID: userID}, StartedAt: startedAt.UTC(), EndedAt: &endedAt, Note: note}
+	if err := s.db.CreateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// TimeEntryChanges are the fields of a time entry to change; nil fields are
+// left alone. Setting EndedAt on a running timer stops it.
+type TimeEntryChanges struct {
+	StartedAt *time.Time
+	EndedAt   *time.Time
+	Note      *string
+}
+
+func (s *TimeEntryService) Update(ctx context.Context, userID, todoID, entryID int64, changes TimeEntryChanges) (*model.TimeEntry, error) {
+	entry, err := s.authorize(ctx, userID, todoID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	running := entry.EndedAt == nil
+	if changes.StartedAt != nil {
+		entry.StartedAt = changes.StartedAt.UTC()
+	}
+	if changes.EndedAt != nil {
+		ended := changes.EndedAt.UTC()
+		entry.EndedAt = &ended
+	}
+	if changes.Note != nil {
+		entry.Note = *changes.Note
+	}
+	if err := validTimeEntry(entry.StartedAt, entry.EndedAt); err != nil {
+		return nil, err
+	}
+	if err := s.db.UpdateTimeEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	if running && entry.EndedAt != nil {
+		s.publish(EventTimerStopped, entry)
+	}
+	return entry, nil
+}
+
+// Delete removes an entry. Deleting a running timer stops it.
+func (s *TimeEntryService) Delete(ctx context.Context, userID, todoID, entryID int64) error {
+	entry, err := s.authorize(ctx, userID, todoID, entryID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.DeleteTimeEntry(ctx, entryID); err != nil {
+		return err
+	}
+	if entry.EndedAt == nil {
+		s.publish(EventTimerStopped, entry)
+	}
+	return nil
+}
+
+func (s *TimeEntryService) authorize(ctx context.Context, userID, todoID, entryID int64) (*model.TimeEntry, error) {
+	entry, err := s.db.GetTimeEntry(ctx, userID, todoID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.User.ID == userID {
+		return entry, nil
+	}
+	role, err := s.db.TodoRole(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if role != model.RoleOwner {
+		return nil, ErrForbidden
+	}
+	return entry, nil
+}
+
+// runningTimerError returns a *TimerRunningError when userID has a running
+// timer.
+func runningTimerError(ctx context.Context, tx *repository.DB, userID int64) error {
+	running, err := tx.GetRunningTimeEntry(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &TimerRunningError{Entry: running}
+}
+
+// validTimeEntry checks that an entry ends after it starts, and that neither
+// lies in the future, allowing for clocks that are a little ahead. endedAt
+// is nil for a running timer.
+func validTimeEntry(startedAt time.Time, endedAt *time.Time) error {
+	now := time.Now().Add(time.Minute)
+	if startedAt.After(now) || (endedAt != nil && (!endedAt.After(startedAt) || endedAt.After(now))) {
+		return ErrInvalidTimeEntry
+	}
+	return nil
+}
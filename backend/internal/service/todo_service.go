@@ -2,35 +2,588 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
 )
 
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+	ErrInvalidAssignee   = errors.New("assignee cannot access the todo")
+	ErrInvalidPriority   = errors.New("priority must be a letter from A to Z")
+	ErrInvalidTag        = errors.New("invalid tag")
+)
+
+const (
+	// maxTags bounds the tags of a single todo.
+	maxTags = 20
+	// maxTagLength bounds the length of a tag, in bytes.
+	maxTagLength = 50
+)
+
 func NewTodoService(db *repository.DB) *TodoService {
 	return &TodoService{db: db}
 }
 
 type TodoService struct {
-	db *repository.DB
+	db     *repository.DB
+	events Publisher
+}
+
+// SetPublisher makes the service announce todo changes to every member of
+// the affected lists.
+func (s *TodoService) SetPublisher(events Publisher) {
+	s.events = events
+}
+
+func (s *TodoService) publish(event string, payload any, audience []int64) {
+	if s.events != nil && len(audience) > 0 {
+		s.events.Publish(event, payload, audience)
+	}
 }
 
-func (s *TodoService) ListByUser(ctx context.Context, userID int64) ([]model.Todo, error) {
-	return s.db.ListTodosByUser(ctx, userID)
+// flush publishes the events of a committed transaction.
+func (s *TodoService) flush(out outbox) {
+	for _, ev := range out {
+		s.publish(ev.name, ev.payload, ev.audience)
+	}
 }
 
+// List returns a page of the todos userID can see and the cursor of the next
+// page, which is empty on the last one. The values of filter.Fields are the
+// raw strings of the query, which are parsed as their fields' types demand,
+// and filter.Query is run in userID's timezone.
+func (s *TodoService) List(ctx context.Context, userID int64, filter repository.TodoFilter) ([]model.Todo, string, error) {
+	fields := make([]repository.FieldFilter, len(filter.Fields))
+	for i, ff := range filter.Fields {
+		raw, _ := ff.Value.(string)
+		value, err := s.fieldFilterValue(ctx, userID, ff.FieldID, raw)
+		if err != nil {
+			return nil, "", err
+		}
+		fields[i] = repository.FieldFilter{FieldID: ff.FieldID, Op: ff.Op, Value: value}
+	}
+	filter.Fields = fields
+	if filter.Query != nil {
+		q, err := s.resolveQuery(ctx, userID, filter.Query)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.Query = q
+	}
+	return s.db.ListTodos(ctx, userID, filter)
+}
+
+// ListAssigned returns the todos assigned to userID across all their lists.
+func (s *TodoService) ListAssigned(ctx context.Context, userID int64) ([]model.Todo, error) {
+	return s.db.ListAssignedTodos(ctx, userID)
+}
+
+// Search returns up to limit of the todos userID can see whose title or notes
+// match query, best matches first.
+func (s *TodoService) Search(ctx context.Context, userID int64, query string, limit int) ([]model.SearchResult, error) {
+	return s.db.SearchTodos(ctx, userID, query, limit)
+}
+
+// Create adds todo on behalf of todo.UserID, into their default list when no
+// list is set. The creator must be able to edit the list.
 func (s *TodoService) Create(ctx context.Context, todo *model.Todo) error {
-	return s.db.CreateTodo(ctx, todo)
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		return s.create(ctx, tx, todo, &out)
+	})
+	if err != nil {
+		return err
+	}
+	s.flush(out)
+	return nil
+}
+
+func (s *TodoService) create(ctx context.Context, tx *repository.DB, todo *model.Todo, out *outbox) error {
+	if err := normalizeRecurrence(todo); err != nil {
+		return err
+	}
+	if err := normalizeLabels(todo); err != nil {
+		return err
+	}
+	if todo.ListID == 0 {
+		list, err := tx.GetDefaultList(ctx, todo.UserID)
+		if err != nil {
+			return err
+		}
+		todo.ListID = list.ID
+	}
+	if err := requireListRole(ctx, tx, todo.UserID, todo.ListID, model.RoleEditor); err != nil {
+		return err
+	}
+	if err := resolveStatus(ctx, tx, todo, nil); err != nil {
+		return err
+	}
+	if err := normalizeFields(ctx, tx, todo, nil); err != nil {
+		return err
+	}
+	stampCompletion(todo, nil, time.Now().UTC())
+	if err := requireAssignable(ctx, tx, todo); err != nil {
+		return err
+	}
+	if err := tx.CreateTodo(ctx, todo); err != nil {
+		return err
+	}
+	if err := recordRevision(ctx, tx, todo.UserID, model.RevisionCreated, nil, todo, nil); err != nil {
+		return err
+	}
+	assignment, err := recordAssignment(ctx, tx, todo.UserID, todo, nil)
+	if err != nil {
+		return err
+	}
+	audience, err := tx.ListMemberIDs(ctx, todo.ListID)
+	if err != nil {
+		return err
+	}
+	out.add(EventTodoCreated, todo, audience)
+	out.addAssignment(assignment, audience)
+	return nil
+}
+
+// Update saves todo on behalf of userID, who must be able to edit both the
+// list it is in and the list it moves to. When the update completes an
+// occurrence of a recurring todo, the next occurrence is created in the same
+// transaction and returned; otherwise the returned todo is nil.
+func (s *TodoService) Update(ctx context.Context, userID int64, todo *model.Todo) (*model.Todo, error) {
+	return s.update(ctx, userID, todo, model.RevisionUpdated, nil)
+}
+
+// update saves todo like Update and records the change as a revision with
+// the given action.
+func (s *TodoService) update(ctx context.Context, userID int64, todo *model.Todo, action string, revertOf *int64) (*model.Todo, error) {
+	var (
+		next *model.Todo
+		out  outbox
+	)
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		var err error
+		next, err = s.updateTx(ctx, tx, userID, todo, action, revertOf, &out)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flush(out)
+	return next, nil
+}
+
+func (s *TodoService) updateTx(ctx context.Context, tx *repository.DB, userID int64, todo *model.Todo, action string, revertOf *int64, out *outbox) (*model.Todo, error) {
+	if err := normalizeRecurrence(todo); err != nil {
+		return nil, err
+	}
+	if err := normalizeLabels(todo); err != nil {
+		return nil, err
+	}
+	prev, err := tx.GetTodo(ctx, userID, todo.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	// Members of the old list hear about a move as well.
+	audience, err := tx.ListMemberIDs(ctx, prev.ListID)
+	if err != nil {
+		return nil, err
+	}
+	if todo.ListID != prev.ListID {
+		if err := requireListRole(ctx, tx, userID, todo.ListID, model.RoleEditor); err != nil {
+			return nil, err
+		}
+		moved, err := tx.ListMemberIDs(ctx, todo.ListID)
+		if err != nil {
+			return nil, err
+		}
+		audience = mergeAudiences(audience, moved)
+	}
+	if err := resolveStatus(ctx, tx, todo, prev); err != nil {
+		return nil, err
+	}
+	if err := normalizeFields(ctx, tx, todo, prev); err != nil {
+		return nil, err
+	}
+	stampCompletion(todo, prev, now)
+	if err := requireUnblocked(ctx, tx, userID, todo, prev); err != nil {
+		return nil, err
+	}
+	if err := requireAssignable(ctx, tx, todo); err != nil {
+		return nil, err
+	}
+	if err := tx.UpdateTodo(ctx, userID, todo); err != nil {
+		return nil, err
+	}
+	if todo.Completed != prev.Completed {
+		if err := addDependents(ctx, tx, todo.ID, out); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordRevision(ctx, tx, userID, action, prev, todo, revertOf); err != nil {
+		return nil, err
+	}
+	assignment, err := recordAssignment(ctx, tx, userID, todo, prev.AssigneeID)
+	if err != nil {
+		return nil, err
+	}
+	out.add(EventTodoUpdated, todo, audience)
+	out.addAssignment(assignment, audience)
+	if prev.Completed || !todo.Completed || todo.RRule == "" {
+		return nil, nil
+	}
+	next, err := nextOccurrence(todo, now)
+	if err != nil || next == nil {
+		return nil, err
+	}
+	// The next occurrence starts over in the first open status, whatever
+	// its WIP limit.
+	statuses, err := tx.ListStatuses(ctx, next.ListID)
+	if err != nil {
+		return nil, err
+	}
+	if status := pickStatus(statuses, false, model.StatusTodo); status != nil {
+		next.StatusID = status.ID
+	}
+	if err := tx.CreateTodo(ctx, next); err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, tx, userID, model.RevisionCreated, nil, next, nil); err != nil {
+		return nil, err
+	}
+	out.add(EventTodoCreated, next, audience)
+	return next, nil
+}
+
+// Delete moves todoID to the trash, from which it can be restored until the
+// retention window passes. A non-zero ifVersion makes the delete fail with
+// repository.ErrVersionConflict unless the todo is still at that version.
+func (s *TodoService) Delete(ctx context.Context, userID, todoID, ifVersion int64) error {
+	var out outbox
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		return s.delete(ctx, tx, userID, todoID, ifVersion, &out)
+	})
+	if err != nil {
+		return err
+	}
+	s.flush(out)
+	return nil
+}
+
+func (s *TodoService) delete(ctx context.Context, tx *repository.DB, userID, todoID, ifVersion int64, out *outbox) error {
+	audience, err := tx.TodoAudience(ctx, todoID)
+	if err != nil {
+		return err
+	}
+	if err := tx.DeleteTodo(ctx, userID, todoID, ifVersion); err != nil {
+		return err
+	}
+	if err := recordTrashRevision(ctx, tx, userID, todoID, model.RevisionDeleted); err != nil {
+		return err
+	}
+	out.add(EventTodoDeleted, TodoDeleted{ID: todoID}, audience)
+	return addDependents(ctx, tx, todoID, out)
+}
+
+// Restore takes todoID out of the trash on behalf of userID, who must be able
+// to edit its list.
+func (s *TodoService) Restore(ctx context.Context, userID, todoID int64) (*model.Todo, error) {
+	var (
+		todo *model.Todo
+		out  outbox
+	)
+	err := s.db.WithTx(ctx, func(tx *repository.DB) error {
+		if err := tx.RestoreTodo(ctx, userID, todoID); err != nil {
+			return err
+		}
+		var err error
+		if todo, err = tx.GetTodo(ctx, userID, todoID); err != nil {
+			return err
+		}
+		if err := recordTrashRevision(ctx, tx, userID, todoID, model.RevisionRestored); err != nil {
+			return err
+		}
+		audience, err := tx.ListMemberIDs(ctx, todo.ListID)
+		if err != nil {
+			return err
+		}
+		out.add(EventTodoRestored, todo, audience)
+		return addDependents(ctx, tx, todoID, &out)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flush(out)
+	return todo, nil
+}
+
+// Trash returns the deleted todos in the lists userID belongs to.
+func (s *TodoService) Trash(ctx context.Context, userID int64) ([]model.Todo, error) {
+	return s.db.ListTrash(ctx, userID)
 }
 
-func (s *TodoService) Update(ctx context.Context, todo *model.Todo) error {
-	return s.db.UpdateTodo(ctx, todo)
+// PurgeTrash permanently deletes todos that have been in the trash for longer
+// than retention.
+func (s *TodoService) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.db.PurgeTrash(ctx, time.Now().UTC().Add(-retention))
 }
 
-func (s *TodoService) Delete(ctx context.Context, userID, todoID int64) error {
-	return s.db.DeleteTodo(ctx, userID, todoID)
+// RunTrashPurger calls PurgeTrash every interval until ctx is cancelled.
+func (s *TodoService) RunTrashPurger(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeTrash(ctx, retention)
+			if err != nil {
+				log.Printf("trash purger: %v", err)
+			} else if purged > 0 {
+				log.Printf("trash purger: removed %d todos", purged)
+			}
+		}
+	}
 }
 
 func (s *TodoService) Get(ctx context.Context, userID, todoID int64) (*model.Todo, error) {
 	return s.db.GetTodo(ctx, userID, todoID)
 }
+
+// Occurrences previews the next n due dates a recurring todo would produce if
+// each occurrence were completed on time.
+func (s *TodoService) Occurrences(ctx context.Context, userID, todoID int64, n int) ([]time.Time, error) {
+	todo, err := s.db.GetTodo(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if todo.RRule == "" {
+		return []time.Time{}, nil
+	}
+	now := time.Now().UTC()
+	rule, from, err := scheduleFrom(todo, now)
+	if err != nil {
+		return nil, err
+	}
+	return rule.Occurrences(from, n), nil
+}
+
+// assignment is a change of assignee waiting to be announced once the
+// transaction that made it commits.
+type assignment struct {
+	event        TodoAssigned
+	notification *model.Notification
+}
+
+// requireAssignable returns ErrInvalidAssignee when todo is assigned to a user
+// who is not a member of its list.
+func requireAssignable(ctx context.Context, db *repository.DB, todo *model.Todo) error {
+	if todo.AssigneeID == nil {
+		return nil
+	}
+	if _, err := db.ListRole(ctx, *todo.AssigneeID, todo.ListID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidAssignee
+		}
+		return err
+	}
+	return nil
+}
+
+// recordAssignment notifies todo's assignee when actorID changed it from
+// previous to someone other than themselves. It returns nil when the assignee
+// is unchanged.
+func recordAssignment(ctx context.Context, tx *repository.DB, actorID int64, todo *model.Todo, previous *int64) (*assignment, error) {
+	if sameID(todo.AssigneeID, previous) {
+		return nil, nil
+	}
+	a := &assignment{event: TodoAssigned{
+		TodoID:             todo.ID,
+		ListID:             todo.ListID,
+		Assignee:           todo.Assignee,
+		PreviousAssigneeID: previous,
+		AssignedBy:         actorID,
+	}}
+	if todo.AssigneeID == nil || *todo.AssigneeID == actorID {
+		return a, nil
+	}
+	a.notification = &model.Notification{
+		Kind:   model.NotificationAssigned,
+		TodoID: &todo.ID,
+		Actor:  &model.UserSummary{ID: actorID},
+	}
+	if err := tx.CreateNotification(ctx, *todo.AssigneeID, a.notification); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// requireListRole returns ErrNotFound when userID is not a member of listID
+// and ErrForbidden when their role is below min.
+func requireListRole(ctx context.Context, db *repository.DB, userID, listID int64, min string) error {
+	role, err := db.ListRole(ctx, userID, listID)
+	if err != nil {
+		return err
+	}
+	if !roleAtLeast(role, min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+var roleRank = map[string]int{
+	model.RoleViewer: 1,
+	model.RoleEditor: 2,
+	model.RoleOwner:  3,
+}
+
+func roleAtLeast(role, min string) bool {
+	return roleRank[role] >= roleRank[min]
+}
+
+func normalizeRecurrence(todo *model.Todo) error {
+	if _, err := recurrence.LoadLocation(todo.Timezone); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if todo.RRule == "" {
+		todo.RepeatMode = ""
+		return nil
+	}
+	switch todo.RepeatMode {
+	case "":
+		todo.RepeatMode = model.RepeatFixed
+	case model.RepeatFixed, model.RepeatAfterCompletion:
+	default:
+		return fmt.Errorf("%w: unknown repeat mode %q", ErrInvalidRecurrence, todo.RepeatMode)
+	}
+	if _, err := recurrence.Parse(todo.RRule, todo.Timezone, anchorTime(todo)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return nil
+}
+
+// normalizeLabels upper-cases the priority and lower-cases the tags of todo,
+// dropping duplicate tags, and checks both.
+func normalizeLabels(todo *model.Todo) error {
+	todo.Priority = strings.ToUpper(todo.Priority)
+	if len(todo.Priority) > 1 || (todo.Priority != "" && (todo.Priority[0] < 'A' || todo.Priority[0] > 'Z')) {
+		return ErrInvalidPriority
+	}
+	tags := make([]string, 0, len(todo.Tags))
+	seen := make(map[string]bool, len(todo.Tags))
+	for _, tag := range todo.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength || strings.ContainsAny(tag, " \t\r\n,") {
+			return fmt.Errorf("%w %q", ErrInvalidTag, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: at most %d tags", ErrInvalidTag, maxTags)
+	}
+	todo.Tags = tags
+	return nil
+}
+
+// stampCompletion sets todo.CompletedAt to now when todo becomes completed,
+// keeps the time of prev while it stays completed and clears it when it is
+// reopened. prev is nil for a new todo, which may carry its own time, as an
+// imported one does.
+func stampCompletion(todo, prev *model.Todo, now time.Time) {
+	switch {
+	case !todo.Completed:
+		todo.CompletedAt = nil
+	case prev != nil && prev.Completed:
+		todo.CompletedAt = prev.CompletedAt
+	case prev != nil || todo.CompletedAt == nil:
+		todo.CompletedAt = &now
+	}
+}
+
+// nextOccurrence builds the todo that follows todo once it has been completed
+// at completedAt. It returns nil when the rule has no further occurrences.
+// The next todo is anchored at its own due date, so its rule counts only the
+// occurrences that are left.
+func nextOccurrence(todo *model.Todo, completedAt time.Time) (*model.Todo, error) {
+	rule, from, err := scheduleFrom(todo, completedAt)
+	if err != nil {
+		return nil, err
+	}
+	due, ok := rule.Next(from)
+	if !ok {
+		return nil, nil
+	}
+	return &model.Todo{
+		ListID:     todo.ListID,
+		UserID:     todo.UserID,
+		AssigneeID: todo.AssigneeID,
+		Title:      todo.Title,
+		Notes:      todo.Notes,
+		DueAt:      &due,
+		RRule:      rule.From(due),
+		Timezone:   todo.Timezone,
+		RepeatMode: todo.RepeatMode,
+		Priority:   todo.Priority,
+		Tags:       todo.Tags,
+		Fields:     todo.Fields,
+	}, nil
+}
+
+// scheduleFrom anchors todo's rule for computing occurrences after now and
+// returns the instant the next occurrence must follow. Fixed schedules keep
+// the cadence of the original due date but skip occurrences already in the
+// past; after-completion schedules restart from now, keeping the time of day
+// of the original due date when there is one.
+func scheduleFrom(todo *model.Todo, now time.Time) (*recurrence.Rule, time.Time, error) {
+	anchor := anchorTime(todo)
+	if todo.RepeatMode == model.RepeatAfterCompletion {
+		loc, err := recurrence.LoadLocation(todo.Timezone)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		local := now.In(loc)
+		if todo.DueAt != nil {
+			due := todo.DueAt.In(loc)
+			anchor = time.Date(local.Year(), local.Month(), local.Day(), due.Hour(), due.Minute(), due.Second(), 0, loc)
+		} else {
+			anchor = local
+		}
+		rule, err := recurrence.Parse(todo.RRule, todo.Timezone, anchor)
+		return rule, anchor, err
+	}
+	rule, err := recurrence.Parse(todo.RRule, todo.Timezone, anchor)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if now.After(anchor) {
+		return rule, now, nil
+	}
+	return rule, anchor, nil
+}
+
+func anchorTime(todo *model.Todo) time.Time {
+	if todo.DueAt != nil {
+		return *todo.DueAt
+	}
+	if !todo.CreatedAt.IsZero() {
+		return todo.CreatedAt
+	}
+	return time.Now().UTC()
+}
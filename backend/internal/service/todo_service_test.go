@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		rrule       string
+		mode        string
+		timezone    string
+		due         time.Time
+		completedAt time.Time
+		wantDue     time.Time
+		wantRRule   string
+	}{
+		{
+			name:        "fixed keeps the cadence",
+			rrule:       "FREQ=WEEKLY",
+			due:         utc(11, 2, 9),
+			completedAt: utc(10, 30, 12),
+			wantDue:     utc(11, 9, 9),
+			wantRRule:   "FREQ=WEEKLY",
+		},
+		{
+			name:        "fixed skips past occurrences",
+			rrule:       "FREQ=DAILY;COUNT=10",
+			due:         utc(11, 2, 9),
+			completedAt: utc(11, 5, 12),
+			wantDue:     utc(11, 6, 9),
+			wantRRule:   "FREQ=DAILY;COUNT=6",
+		},
+		{
+			name:        "after completion restarts from the completion day",
+			rrule:       "FREQ=DAILY;INTERVAL=3",
+			mode:        model.RepeatAfterCompletion,
+			due:         utc(11, 2, 9),
+			completedAt: utc(11, 5, 12),
+			wantDue:     utc(11, 8, 9),
+			wantRRule:   "FREQ=DAILY;INTERVAL=3",
+		},
+		{
+			name:        "after completion counts down",
+			rrule:       "FREQ=DAILY;COUNT=3",
+			mode:        model.RepeatAfterCompletion,
+			due:         utc(11, 2, 9),
+			completedAt: utc(11, 5, 12),
+			wantDue:     utc(11, 6, 9),
+			wantRRule:   "FREQ=DAILY;COUNT=2",
+		},
+		{
+			// 9:00 in New York, which leaves daylight saving time on 1 November.
+			name:        "after completion keeps local time across DST",
+			rrule:       "FREQ=DAILY",
+			mode:        model.RepeatAfterCompletion,
+			timezone:    "America/New_York",
+			due:         utc(10, 30, 13),
+			completedAt: utc(10, 31, 20),
+			wantDue:     utc(11, 1, 14),
+			wantRRule:   "FREQ=DAILY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := tt.due
+			todo := &model.Todo{Title: "t", RRule: tt.rrule, RepeatMode: tt.mode, Timezone: tt.timezone, DueAt: &due}
+			next, err := nextOccurrence(todo, tt.completedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next == nil {
+				t.Fatal("no next occurrence")
+			}
+			if !next.DueAt.Equal(tt.wantDue) {
+				t.Errorf("due = %v, want %v", next.DueAt, tt.wantDue)
+			}
+			if next.RRule != tt.wantRRule {
+				t.Errorf("rrule = %q, want %q", next.RRule, tt.wantRRule)
+			}
+		})
+	}
+}
+
+// TestNextOccurrenceEndsSeries completes each todo of a series as soon as it
+// is due and checks that the series ends after COUNT todos rather than
+// restarting its count with every one.
+func TestNextOccurrenceEndsSeries(t *testing.T) {
+	for _, mode := range []string{model.RepeatFixed, model.RepeatAfterCompletion} {
+		due := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+		todo := &model.Todo{Title: "t", RRule: "FREQ=DAILY;COUNT=2", RepeatMode: mode, DueAt: &due}
+		todos := 1
+		for ; todos <= 10; todos++ {
+			next, err := nextOccurrence(todo, *todo.DueAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next == nil {
+				break
+			}
+			todo = next
+		}
+		if todos != 2 {
+			t.Errorf("%s: series had %d todos, want 2", mode, todos)
+		}
+	}
+}
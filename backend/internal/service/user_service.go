@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
 )
 
@@ -70,3 +72,33 @@ func (s *UserService) GetByID(ctx context.Context, id int64) (*model.User, error
 	user.PasswordHash = ""
 	return user, nil
 }
+
+// SetTimezone sets the IANA timezone dates typed by the user are read in; an
+// empty name means UTC.
+func (s *UserService) SetTimezone(ctx context.Context, id int64, timezone string) (*model.User, error) {
+	if _, err := recurrence.LoadLocation(timezone); err != nil {
+		return nil, err
+	}
+	if err := s.db.SetUserTimezone(ctx, id, timezone); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// Location returns the user's timezone.
+func (s *UserService) Location(ctx context.Context, id int64) (*time.Location, error) {
+	user, err := s.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return recurrence.LoadLocation(user.Timezone)
+}
+
+func (s *UserService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/model"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/query"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/recurrence"
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/repository"
+)
+
+var (
+	ErrInvalidViewName = errors.New("view name is required")
+	ErrInvalidViewSort = errors.New("invalid view sort")
+	ErrDuplicateView   = errors.New("you already have a view with that name")
+)
+
+// ViewChanges are the parts of a view to set; nil fields are left alone, or
+// empty on a new view.
+type ViewChanges struct {
+	Name  *string
+	Query *string
+	Sort  *string
+}
+
+// ParseQuery parses a filter query, reporting a syntax error as
+// repository.ErrInvalidQuery. An empty query gives a nil expression.
+func ParseQuery(text string) (query.Expr, error) {
+	expr, err := query.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrInvalidQuery, err)
+	}
+	return expr, nil
+}
+
+// resolveQuery prepares q to run for userID: relative dates are read in
+// their timezone, and custom field values as the types of the fields they
+// can see demand.
+func (s *TodoService) resolveQuery(ctx context.Context, userID int64, q *repository.TodoQuery) (*repository.TodoQuery, error) {
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := recurrence.LoadLocation(user.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *q
+	resolved.Now = time.Now().In(loc)
+	resolved.FieldValue = func(fieldID int64, raw string) (any, error) {
+		return s.fieldFilterValue(ctx, userID, fieldID, raw)
+	}
+	return &resolved, nil
+}
+
+// Views returns userID's saved views.
+func (s *TodoService) Views(ctx context.Context, userID int64) ([]model.View, error) {
+	return s.db.ListViews(ctx, userID)
+}
+
+// GetView returns one of userID's saved views.
+func (s *TodoService) GetView(ctx context.Context, userID, viewID int64) (*model.View, error) {
+	return s.db.GetView(ctx, userID, viewID)
+}
+
+// CreateView saves a view for userID. Its query and sort must run.
+func (s *TodoService) CreateView(ctx context.Context, userID int64, changes ViewChanges) (*model.View, error) {
+	if changes.Name == nil {
+		return nil, ErrInvalidViewName
+	}
+	view := &model.View{}
+	if err := s.applyViewChanges(ctx, userID, view, changes); err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateView(ctx, userID, view); err != nil {
+		return nil, viewWriteError(err)
+	}
+	return view, nil
+}
+
+// UpdateView changes one of userID's saved views.
+func (s *TodoService) UpdateView(ctx context.Context, userID, viewID int64, changes ViewChanges) (*model.View, error) {
+	view, err := s.db.GetView(ctx, userID, viewID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyViewChanges(ctx, userID, view, changes); err != nil {
+		return nil, err
+	}
+	if err := s.db.UpdateView(ctx, userID, view); err != nil {
+		return nil, viewWriteError(err)
+	}
+	return view, nil
+}
+
+// DeleteView deletes one of userID's saved views.
+func (s *TodoService) DeleteView(ctx context.Context, userID, viewID int64) error {
+	return s.db.DeleteView(ctx, userID, viewID)
+}
+
+// ViewTodos returns a page of the todos matching viewID, which must be one
+// of userID's views. A query in filter narrows the view further, and a sort
+// in filter replaces the view's.
+func (s *TodoService) ViewTodos(ctx context.Context, userID, viewID int64, filter repository.TodoFilter) ([]model.Todo, string, error) {
+	view, err := s.db.GetView(ctx, userID, viewID)
+	if err != nil {
+		return nil, "", err
+	}
+	expr, err := ParseQuery(view.Query)
+	if err != nil {
+		return nil, "", err
+	}
+	if filter.Query != nil && filter.Query.Expr != nil {
+		if expr == nil {
+			expr = filter.Query.Expr
+		} else {
+			expr = &query.And{Left: expr, Right: filter.Query.Expr}
+		}
+	}
+	filter.Query = &repository.TodoQuery{Expr: expr}
+	if len(filter.Sort) == 0 {
+		if filter.Sort, err = repository.ParseTodoSort(view.Sort); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidViewSort, err)
+		}
+	}
+	return s.List(ctx, userID, filter)
+}
+
+// applyViewChanges sets changes on view and checks that its query and sort
+// run, so a broken view is refused when it is saved rather than later.
+func (s *TodoService) applyViewChanges(ctx context.Context, userID int64, view *model.View, changes ViewChanges) error {
+	if changes.Name != nil {
+		view.Name = strings.TrimSpace(*changes.Name)
+		if view.Name == "" {
+			return ErrInvalidViewName
+		}
+	}
+	if changes.Query != nil {
+		view.Query = strings.TrimSpace(*changes.Query)
+	}
+	if changes.Sort != nil {
+		view.Sort = strings.TrimSpace(*changes.Sort)
+	}
+	expr, err := ParseQuery(view.Query)
+	if err != nil {
+		return err
+	}
+	sort, err := repository.ParseTodoSort(view.Sort)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidViewSort, err)
+	}
+	filter := repository.TodoFilter{Query: &repository.TodoQuery{Expr: expr}, Sort: sort, Limit: 1}
+	_, _, err = s.List(ctx, userID, filter)
+	return err
+}
+
+func viewWriteError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrDuplicateView
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+// Package todotxt reads and writes the todo.txt format
+// (https://github.com/todotxt/todo.txt): one task per line, optionally
+// marked done with "x", with a priority, completion and creation dates, and
+// a description that may carry +projects, @contexts and key:value
+// extensions.
+package todotxt
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of the dates in a task.
+const DateLayout = "2006-01-02"
+
+// Task is one line of a todo.txt file.
+type Task struct {
+	Done bool
+	// Priority is a letter from A to Z, or empty.
+	Priority string
+	// Completed and Created are dates at midnight UTC, or zero when
+	// absent. A task may only have a completion date when it is done and
+	// also has a creation date.
+	Completed   time.Time
+	Created     time.Time
+	Description string
+}
+
+// Extension is a key:value pair in a task's description.
+type Extension struct {
+	Key   string
+	Value string
+}
+
+// Line is a task read from a file, with its 1-based line number.
+type Line struct {
+	Number int
+	Task   Task
+}
+
+// Parse reads a single line. Anything that is not a recognised marker,
+// priority or date is part of the description, so parsing never fails.
+func Parse(line string) Task {
+	var t Task
+	rest := strings.TrimSpace(line)
+	if strings.HasPrefix(rest, "x ") {
+		t.Done = true
+		rest = strings.TrimLeft(rest[2:], " ")
+	}
+	if !t.Done {
+		if p, ok := cutPriority(rest); ok {
+			t.Priority = p
+			rest = strings.TrimLeft(rest[3:], " ")
+		}
+	}
+	first, ok := cutDate(rest)
+	if ok {
+		rest = strings.TrimLeft(rest[len(DateLayout):], " ")
+		second, ok := cutDate(rest)
+		if t.Done && ok {
+			t.Completed, t.Created = first, second
+			rest = strings.TrimLeft(rest[len(DateLayout):], " ")
+		} else {
+			t.Created = first
+		}
+	}
+	t.Description = rest
+	if t.Done && t.Priority == "" {
+		// Done tasks keep their priority as a pri: extension.
+		if p, ok := t.Extension("pri"); ok && len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z' {
+			t.Priority = p
+		}
+	}
+	return t
+}
+
+// String formats the task as a line, without a line break. The priority of
+// a done task is written as a pri: extension unless the description
+// already has one.
+func (t Task) String() string {
+	var parts []string
+	if t.Done {
+		parts = append(parts, "x")
+	} else if t.Priority != "" {
+		parts = append(parts, "("+t.Priority+")")
+	}
+	if !t.Created.IsZero() {
+		if t.Done && !t.Completed.IsZero() {
+			parts = append(parts, t.Completed.Format(DateLayout))
+		}
+		parts = append(parts, t.Created.Format(DateLayout))
+	}
+	if t.Description != "" {
+		parts = append(parts, t.Description)
+	}
+	if t.Done && t.Priority != "" {
+		if _, ok := t.Extension("pri"); !ok {
+			parts = append(parts, "pri:"+t.Priority)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Projects returns the +projects of the description, without the "+".
+func (t Task) Projects() []string {
+	var projects []string
+	for _, word := range strings.Fields(t.Description) {
+		if name, ok := ParseProject(word); ok {
+			projects = append(projects, name)
+		}
+	}
+	return projects
+}
+
+// Contexts returns the @contexts of the description, without the "@".
+func (t Task) Contexts() []string {
+	var contexts []string
+	for _, word := range strings.Fields(t.Description) {
+		if name, ok := ParseContext(word); ok {
+			contexts = append(contexts, name)
+		}
+	}
+	return contexts
+}
+
+// Extensions returns the key:value extensions of the description in order.
+func (t Task) Extensions() []Extension {
+	var exts []Extension
+	for _, word := range strings.Fields(t.Description) {
+		if ext, ok := ParseExtension(word); ok {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// Extension returns the value of the first extension with key.
+func (t Task) Extension(key string) (string, bool) {
+	for _, ext := range t.Extensions() {
+		if ext.Key == key {
+			return ext.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseProject reports whether word is a +project and returns its name.
+func ParseProject(word string) (string, bool) {
+	return cutMarker(word, '+')
+}
+
+// ParseContext reports whether word is an @context and returns its name.
+func ParseContext(word string) (string, bool) {
+	return cutMarker(word, '@')
+}
+
+// ParseExtension reports whether word is a key:value extension. Words whose
+// value starts with "/", such as URLs, are not.
+func ParseExtension(word string) (Extension, bool) {
+	key, value, ok := strings.Cut(word, ":")
+	if !ok || key == "" || value == "" || strings.HasPrefix(value, "/") || strings.Contains(value, ":") {
+		return Extension{}, false
+	}
+	return Extension{Key: key, Value: value}, true
+}
+
+func (e Extension) String() string {
+	return e.Key + ":" + e.Value
+}
+
+// Read parses every non-blank line of r.
+func Read(r io.Reader) ([]Line, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var lines []Line
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		lines = append(lines, Line{Number: n, Task: Parse(scanner.Text())})
+	}
+	return lines, scanner.Err()
+}
+
+// Write writes tasks one per line.
+func Write(w io.Writer, tasks []Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range tasks {
+		if _, err := bw.WriteString(t.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func cutMarker(word string, marker byte) (string, bool) {
+	if len(word) < 2 || word[0] != marker {
+		return "", false
+	}
+	return word[1:], true
+}
+
+func cutPriority(s string) (string, bool) {
+	if len(s) < 4 || s[0] != '(' || s[1] < 'A' || s[1] > 'Z' || s[2] != ')' || s[3] != ' ' {
+		return "", false
+	}
+	return s[1:2], true
+}
+
+func cutDate(s string) (time.Time, bool) {
+	if len(s) < len(DateLayout) || (len(s) > len(DateLayout) && s[len(DateLayout)] != ' ') {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(DateLayout, s[:len(DateLayout)])
+	return d, err == nil
+}
@@ -0,0 +1,154 @@
+package todotxt
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want Task
+	}{
+		{"call mom", Task{Description: "call mom"}},
+		{"(A) call mom", Task{Priority: "A", Description: "call mom"}},
+		{"(A) 2026-10-01 call mom", Task{Priority: "A", Created: day("2026-10-01"), Description: "call mom"}},
+		{"2026-10-01 call mom", Task{Created: day("2026-10-01"), Description: "call mom"}},
+		{"x call mom", Task{Done: true, Description: "call mom"}},
+		{"x 2026-10-02 2026-10-01 call mom", Task{Done: true, Completed: day("2026-10-02"), Created: day("2026-10-01"), Description: "call mom"}},
+		// A done task with one date has only a creation date.
+		{"x 2026-10-01 call mom", Task{Done: true, Created: day("2026-10-01"), Description: "call mom"}},
+		{"x 2026-10-02 2026-10-01 call mom pri:B", Task{Done: true, Priority: "B", Completed: day("2026-10-02"), Created: day("2026-10-01"), Description: "call mom pri:B"}},
+		{"  (B)   padded  ", Task{Priority: "B", Description: "padded"}},
+		// Things that only look like markers stay in the description.
+		{"(a) lower case priority", Task{Description: "(a) lower case priority"}},
+		{"(A)no space", Task{Description: "(A)no space"}},
+		{"x(A) not done", Task{Description: "x(A) not done"}},
+		{"X call mom", Task{Description: "X call mom"}},
+		{"2026-13-01 not a date", Task{Description: "2026-13-01 not a date"}},
+		{"2026-10-01T10:00 not a date", Task{Description: "2026-10-01T10:00 not a date"}},
+		{"(A) x 2026-10-01 priority first", Task{Priority: "A", Description: "x 2026-10-01 priority first"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.line); got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestRoundTrip checks that parsing a line, formatting it and parsing it
+// again gives the same task, and that well-formed lines format as they were
+// written.
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		line string
+		want string // when formatting normalises the line
+	}{
+		{line: "call mom"},
+		{line: "(A) call mom +family @phone"},
+		{line: "(C) 2026-10-01 pay rent +home @online due:2026-11-01 rec:+1m id:42"},
+		{line: "x 2026-10-02 2026-10-01 pay rent +home due:2026-11-01 id:42"},
+		{line: "x 2026-10-02 2026-10-01 pay rent pri:A id:42"},
+		{line: "2026-10-01 read https://example.com/a:b later"},
+		{line: "water plants rrule:FREQ=WEEKLY;BYDAY=MO,TH id:7"},
+		{line: "keep unknown foo:bar and t:2026-10-05 id:3"},
+		{line: "x (A) done wins over priority"},
+		{line: "  (B)   padded  ", want: "(B) padded"},
+	}
+	for _, tt := range tests {
+		task := Parse(tt.line)
+		want := tt.want
+		if want == "" {
+			want = tt.line
+		}
+		if got := task.String(); got != want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.line, got, want)
+		}
+		if again := Parse(task.String()); again != task {
+			t.Errorf("round trip of %q = %+v, want %+v", tt.line, again, task)
+		}
+	}
+}
+
+func TestStringDonePriority(t *testing.T) {
+	task := Task{Done: true, Priority: "A", Description: "ship it"}
+	if got := task.String(); got != "x ship it pri:A" {
+		t.Errorf("String = %q, want the priority as pri:A", got)
+	}
+	if again := Parse(task.String()); again.Priority != "A" {
+		t.Errorf("priority after a round trip = %q, want A", again.Priority)
+	}
+	// A completion date needs a creation date to be written.
+	task = Task{Done: true, Completed: day("2026-10-02"), Description: "ship it"}
+	if got := task.String(); got != "x ship it" {
+		t.Errorf("String = %q, want no dates", got)
+	}
+}
+
+func TestDescriptionParts(t *testing.T) {
+	task := Parse("(A) plan trip +travel +family @home @phone due:2026-12-01 id:9 see http://x.org/a:b 12:30 foo: :bar +")
+	if got, want := task.Projects(), []string{"travel", "family"}; !slices.Equal(got, want) {
+		t.Errorf("Projects = %q, want %q", got, want)
+	}
+	if got, want := task.Contexts(), []string{"home", "phone"}; !slices.Equal(got, want) {
+		t.Errorf("Contexts = %q, want %q", got, want)
+	}
+	want := []Extension{{"due", "2026-12-01"}, {"id", "9"}, {"12", "30"}}
+	if got := task.Extensions(); !slices.Equal(got, want) {
+		t.Errorf("Extensions = %v, want %v", got, want)
+	}
+	if v, ok := task.Extension("id"); !ok || v != "9" {
+		t.Errorf("Extension(id) = %q, %v", v, ok)
+	}
+	if _, ok := task.Extension("rec"); ok {
+		t.Error("Extension(rec) found a missing extension")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	input := "(A) first id:1\n\n   \nx 2026-10-02 2026-10-01 second id:2\r\nthird +p @c k:v\n"
+	lines, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Read = %+v, want 3 lines", lines)
+	}
+	for i, want := range []int{1, 4, 5} {
+		if lines[i].Number != want {
+			t.Errorf("line %d number = %d, want %d", i, lines[i].Number, want)
+		}
+	}
+	tasks := make([]Task, len(lines))
+	for i, line := range lines {
+		tasks[i] = line.Task
+	}
+	var b bytes.Buffer
+	if err := Write(&b, tasks); err != nil {
+		t.Fatal(err)
+	}
+	want := "(A) first id:1\nx 2026-10-02 2026-10-01 second id:2\nthird +p @c k:v\n"
+	if b.String() != want {
+		t.Errorf("Write = %q, want %q", b.String(), want)
+	}
+	again, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if again[i].Task != tasks[i] {
+			t.Errorf("task %d after a round trip = %+v, want %+v", i, again[i].Task, tasks[i])
+		}
+	}
+}
@@ -6,13 +6,16 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-oauth2/oauth2/v4/manage"
 	"github.com/go-oauth2/oauth2/v4/models"
 	"github.com/go-oauth2/oauth2/v4/server"
 	"github.com/go-oauth2/oauth2/v4/store"
 
+	"github.com/n0ll0/hello-world-ripple-app-backend/internal/blob"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/config"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/handler"
 	"github.com/n0ll0/hello-world-ripple-app-backend/internal/middleware"
@@ -35,8 +38,24 @@ func main() {
 		log.Fatalf("failed to apply migrations: %v", err)
 	}
 
+	blobStore, err := newBlobStore(cfg.Blob)
+	if err != nil {
+		log.Fatalf("failed to initialise blob store: %v", err)
+	}
+
 	userService := service.NewUserService(db)
 	todoService := service.NewTodoService(db)
+	listService := service.NewListService(db)
+	commentService := service.NewCommentService(db)
+	notificationService := service.NewNotificationService(db)
+	attachmentService := service.NewAttachmentService(db, blobStore, cfg.Blob.MaxUploadBytes, cfg.Blob.UserQuotaBytes)
+	go attachmentService.RunSweeper(context.Background(), cfg.Blob.SweepInterval)
+	timeEntryService := service.NewTimeEntryService(db)
+	importService := service.NewImportService(db, todoService, listService)
+	if err := importService.FailUnfinished(context.Background()); err != nil {
+		log.Fatalf("failed to clean up import jobs: %v", err)
+	}
+	go todoService.RunTrashPurger(context.Background(), cfg.TrashPurgeInterval, cfg.TrashRetention)
 
 	manager := manage.NewDefaultManager()
 	manager.MustTokenStorage(store.NewMemoryTokenStore())
@@ -66,29 +85,56 @@ func main() {
 	oauthHandler.SetErrorHandlers()
 
 	userHandler := handler.NewUserHandler(userService)
-	todoHandler := handler.NewTodoHandler(todoService)
+	todoHandler := handler.NewTodoHandler(todoService, listService, userService)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService, cfg.Blob.MaxUploadBytes)
+	commentHandler := handler.NewCommentHandler(commentService)
+	listHandler := handler.NewListHandler(listService, userService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	calDAVHandler := handler.NewCalDAVHandler(todoService, listService, userService)
+	todoTxtHandler := handler.NewTodoTxtHandler(todoService, listService)
+	importHandler := handler.NewImportHandler(importService)
+	timeEntryHandler := handler.NewTimeEntryHandler(timeEntryService)
 
 	// Initialize WebSocket event hubs - one per event type
-	todoCreatedHub := handler.NewEventHub("todo:created")
-	todoUpdatedHub := handler.NewEventHub("todo:updated")
-	todoDeletedHub := handler.NewEventHub("todo:deleted")
-
-	go todoCreatedHub.Run()
-	go todoUpdatedHub.Run()
-	go todoDeletedHub.Run()
+	hubs := handler.Hubs{}
+	for _, name := range []string{
+		service.EventTodoCreated,
+		service.EventTodoUpdated,
+		service.EventTodoDeleted,
+		service.EventTodoAssigned,
+		service.EventTodoRestored,
+		service.EventTodoBatch,
+		service.EventCommentCreated,
+		service.EventCommentUpdated,
+		service.EventCommentDeleted,
+		service.EventTimerStarted,
+		service.EventTimerStopped,
+		service.EventNotificationCreated,
+	} {
+		hubs[name] = handler.NewEventHub(name)
+	}
+	hubs.Run()
 
-	// Connect the hubs to the todo handler
-	todoHandler.SetWebSocketHubs(todoCreatedHub, todoUpdatedHub, todoDeletedHub)
+	// Services publish their events through the hubs
+	todoService.SetPublisher(hubs)
+	commentService.SetPublisher(hubs)
+	timeEntryService.SetPublisher(hubs)
 
+	// chi only routes the methods it knows about.
+	for _, method := range []string{handler.MethodPropfind, handler.MethodProppatch, handler.MethodReport} {
+		chi.RegisterMethod(method)
+	}
 	r := chi.NewRouter()
 
 	// CORS middleware
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-			if r.Method == "OPTIONS" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS, PROPFIND, PROPPATCH, REPORT")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match, Idempotency-Key, Depth")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Link, X-Next-Cursor, Idempotent-Replayed, DAV")
+			// CalDAV answers OPTIONS itself, advertising what it supports.
+			if r.Method == "OPTIONS" && !strings.HasPrefix(r.URL.Path, "/caldav") {
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
@@ -106,27 +152,159 @@ func main() {
 				next.ServeHTTP(w, r)
 			})
 		})
+		// Registering needs no token, so it is outside the idempotency
+		// middleware, which scopes keys to the authenticated user.
 		api.Post("/users", userHandler.Register)
 
 		api.Group(func(protected chi.Router) {
 			protected.Use(middleware.OAuth2Guard(srv))
+			protected.Use(middleware.Idempotency(db, cfg.IdempotencyTTL))
 			protected.Get("/users", userHandler.List)
 			protected.Get("/users/me", userHandler.GetCurrentUser)
+			protected.Patch("/users/me", userHandler.UpdateCurrentUser)
+			protected.Get("/users/me/app-passwords", userHandler.AppPasswords)
+			protected.Post("/users/me/app-passwords", userHandler.CreateAppPassword)
+			protected.Delete("/users/me/app-passwords/{appPasswordId}", userHandler.DeleteAppPassword)
+
+			protected.Get("/lists", listHandler.List)
+			protected.Post("/lists", listHandler.Create)
+			protected.Get("/lists/{listId}", listHandler.Get)
+			protected.Patch("/lists/{listId}", listHandler.Update)
+			protected.Delete("/lists/{listId}", listHandler.Delete)
+			protected.Get("/lists/{listId}/members", listHandler.Members)
+			protected.Post("/lists/{listId}/members", listHandler.AddMember)
+			protected.Put("/lists/{listId}/members/{userId}", listHandler.UpdateMember)
+			protected.Delete("/lists/{listId}/members/{userId}", listHandler.RemoveMember)
+			protected.Get("/lists/{listId}/statuses", listHandler.Statuses)
+			protected.Post("/lists/{listId}/statuses", listHandler.CreateStatus)
+			protected.Patch("/lists/{listId}/statuses/{statusId}", listHandler.UpdateStatus)
+			protected.Delete("/lists/{listId}/statuses/{statusId}", listHandler.DeleteStatus)
+			protected.Get("/lists/{listId}/fields", listHandler.Fields)
+			protected.Post("/lists/{listId}/fields", listHandler.CreateField)
+			protected.Patch("/lists/{listId}/fields/{fieldId}", listHandler.UpdateField)
+			protected.Delete("/lists/{listId}/fields/{fieldId}", listHandler.DeleteField)
+			protected.Get("/lists/{listId}/board", todoHandler.Board)
 
 			protected.Get("/todos", todoHandler.List)
+			protected.Get("/todos/assigned", todoHandler.Assigned)
+			protected.Get("/todos/search", todoHandler.Search)
+			protected.Post("/todos/batch", todoHandler.Batch)
+			protected.Post("/todos/parse", todoHandler.Parse)
+			protected.Get("/todos/export.ics", todoHandler.Export)
+			protected.Post("/todos/import", todoHandler.Import)
+			protected.Get("/todos.txt", todoTxtHandler.Get)
+			protected.Put("/todos.txt", todoTxtHandler.Put)
 			protected.Post("/todos", todoHandler.Create)
+			protected.Get("/todos/{id}", todoHandler.Get)
 			protected.Put("/todos/{id}", todoHandler.Update)
+			protected.Patch("/todos/{id}", todoHandler.Patch)
 			protected.Delete("/todos/{id}", todoHandler.Delete)
+			protected.Post("/todos/{id}/restore", todoHandler.Restore)
+			protected.Get("/todos/{id}/history", todoHandler.History)
+			protected.Post("/todos/{id}/revert", todoHandler.Revert)
+			protected.Get("/todos/{id}/occurrences", todoHandler.Occurrences)
+			protected.Get("/todos/{id}/dependencies", todoHandler.Dependencies)
+			protected.Post("/todos/{id}/dependencies", todoHandler.AddDependency)
+			protected.Delete("/todos/{id}/dependencies/{blockerId}", todoHandler.RemoveDependency)
+			protected.Get("/trash", todoHandler.Trash)
+			protected.Get("/stats", todoHandler.Stats)
+
+			protected.Get("/views", todoHandler.Views)
+			protected.Post("/views", todoHandler.CreateView)
+			protected.Get("/views/{viewId}", todoHandler.GetView)
+			protected.Patch("/views/{viewId}", todoHandler.UpdateView)
+			protected.Delete("/views/{viewId}", todoHandler.DeleteView)
+			protected.Get("/views/{viewId}/todos", todoHandler.ViewTodos)
+
+			protected.Get("/todos/{id}/attachments", attachmentHandler.List)
+			protected.Post("/todos/{id}/attachments", attachmentHandler.Upload)
+			protected.Get("/todos/{id}/attachments/{attachmentId}", attachmentHandler.Download)
+			protected.Delete("/todos/{id}/attachments/{attachmentId}", attachmentHandler.Delete)
+
+			protected.Get("/todos/{id}/comments", commentHandler.List)
+			protected.Post("/todos/{id}/comments", commentHandler.Create)
+			protected.Patch("/todos/{id}/comments/{commentId}", commentHandler.Update)
+			protected.Delete("/todos/{id}/comments/{commentId}", commentHandler.Delete)
+
+			protected.Get("/imports", importHandler.List)
+			protected.Post("/imports", importHandler.Create)
+			protected.Get("/imports/{importId}", importHandler.Get)
+
+			protected.Get("/timer", timeEntryHandler.Running)
+			protected.Post("/todos/{id}/timer/start", timeEntryHandler.Start)
+			protected.Post("/todos/{id}/timer/stop", timeEntryHandler.Stop)
+			protected.Get("/todos/{id}/time-entries", timeEntryHandler.ListForTodo)
+			protected.Post("/todos/{id}/time-entries", timeEntryHandler.Create)
+			protected.Patch("/todos/{id}/time-entries/{entryId}", timeEntryHandler.Update)
+			protected.Delete("/todos/{id}/time-entries/{entryId}", timeEntryHandler.Delete)
+			protected.Get("/time-entries", timeEntryHandler.List)
+			protected.Get("/time-entries.csv", timeEntryHandler.ExportCSV)
+			protected.Get("/time-entries/totals", timeEntryHandler.Totals)
+
+			protected.Get("/notifications", notificationHandler.List)
+			protected.Post("/notifications/read", notificationHandler.MarkAllRead)
+			protected.Post("/notifications/{notificationId}/read", notificationHandler.MarkRead)
 		})
 	})
 
-	// WebSocket endpoints - one per event type
-	r.Get("/ws/todos/created", todoCreatedHub.HandleWebSocket)
-	r.Get("/ws/todos/updated", todoUpdatedHub.HandleWebSocket)
-	r.Get("/ws/todos/deleted", todoDeletedHub.HandleWebSocket)
+	// CalDAV task apps sign in with HTTP Basic authentication and an app
+	// password; bearer tokens work too. Collections are addressed with or
+	// without their trailing slash.
+	r.Get("/.well-known/caldav", calDAVHandler.WellKnown)
+	r.Route("/caldav", func(dav chi.Router) {
+		dav.Use(chimiddleware.StripSlashes)
+		routes := map[string]http.HandlerFunc{
+			"/":                                     calDAVHandler.Root,
+			"/principals/{userId}":                  calDAVHandler.Principal,
+			"/calendars/{userId}":                   calDAVHandler.Home,
+			"/calendars/{userId}/{listId}":          calDAVHandler.Calendar,
+			"/calendars/{userId}/{listId}/{object}": calDAVHandler.Object,
+		}
+		guard := middleware.BasicAuthGuard(srv, userService, "todos")
+		for pattern, h := range routes {
+			dav.With(guard).HandleFunc(pattern, h)
+			// Registered last so that OPTIONS skips authentication.
+			dav.Options(pattern, calDAVHandler.Options)
+		}
+	})
+
+	// WebSocket endpoints - one per event type. Browsers cannot set headers on
+	// WebSocket requests, so the token is passed as ?access_token=.
+	r.Route("/ws", func(ws chi.Router) {
+		ws.Use(middleware.OAuth2Guard(srv))
+		ws.Get("/todos/created", hubs[service.EventTodoCreated].HandleWebSocket)
+		ws.Get("/todos/updated", hubs[service.EventTodoUpdated].HandleWebSocket)
+		ws.Get("/todos/deleted", hubs[service.EventTodoDeleted].HandleWebSocket)
+		ws.Get("/todos/assigned", hubs[service.EventTodoAssigned].HandleWebSocket)
+		ws.Get("/todos/restored", hubs[service.EventTodoRestored].HandleWebSocket)
+		ws.Get("/todos/batch", hubs[service.EventTodoBatch].HandleWebSocket)
+		ws.Get("/comments/created", hubs[service.EventCommentCreated].HandleWebSocket)
+		ws.Get("/comments/updated", hubs[service.EventCommentUpdated].HandleWebSocket)
+		ws.Get("/comments/deleted", hubs[service.EventCommentDeleted].HandleWebSocket)
+		ws.Get("/timer/started", hubs[service.EventTimerStarted].HandleWebSocket)
+		ws.Get("/timer/stopped", hubs[service.EventTimerStopped].HandleWebSocket)
+		ws.Get("/notifications", hubs[service.EventNotificationCreated].HandleWebSocket)
+	})
 
 	log.Printf("Starting server with OAuth2 and SQLite on :%s...", cfg.Port)
 	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func newBlobStore(cfg config.BlobConfig) (blob.BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return blob.NewLocalStore(cfg.Dir)
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_BACKEND %q", cfg.Backend)
+	}
+}
@@ -21,7 +21,14 @@ export class WebSocketClient {
 			return;
 		}
 
-		this.ws = new WebSocket(this.url);
+		// Browsers cannot send an Authorization header on WebSocket requests,
+		// so the access token travels as a query parameter.
+		const url = new URL(this.url);
+		const token = typeof window !== "undefined" ? window.localStorage.getItem("access_token") : null;
+		if (token) {
+			url.searchParams.set("access_token", token);
+		}
+		this.ws = new WebSocket(url.toString());
 
 		this.ws.onopen = () => {
 			console.log(`[${this.eventType}] WebSocket connected`);
@@ -104,3 +111,10 @@ export class WebSocketClient {
 export const todoCreatedWS = new WebSocketClient("/ws/todos/created");
 export const todoUpdatedWS = new WebSocketClient("/ws/todos/updated");
 export const todoDeletedWS = new WebSocketClient("/ws/todos/deleted");
+export const todoAssignedWS = new WebSocketClient("/ws/todos/assigned");
+export const todoRestoredWS = new WebSocketClient("/ws/todos/restored");
+export const todoBatchWS = new WebSocketClient("/ws/todos/batch");
+export const commentCreatedWS = new WebSocketClient("/ws/comments/created");
+export const commentUpdatedWS = new WebSocketClient("/ws/comments/updated");
+export const commentDeletedWS = new WebSocketClient("/ws/comments/deleted");
+export const notificationWS = new WebSocketClient("/ws/notifications");